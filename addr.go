@@ -1,7 +1,11 @@
 package noise
 
 import (
+	"context"
+	"fmt"
 	"net"
+	"sync"
+	"time"
 )
 
 // ResolveAddress resolves an address using net.ResolveTCPAddress("tcp", (*net.Conn).RemoteAddr()) and nullifies the
@@ -36,3 +40,101 @@ func normalizeIP(ip net.IP) string {
 
 	return str
 }
+
+// Resolver resolves hostnames into IP addresses. *net.Resolver implements Resolver.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// resolvedHostTTL is the duration the IP addresses a hostname resolves to are cached for.
+const resolvedHostTTL = 30 * time.Second
+
+type resolvedHost struct {
+	addrs []string
+	until time.Time
+}
+
+// resolvedHostMap caches the IP addresses hostnames resolve to.
+type resolvedHostMap struct {
+	sync.Mutex
+	entries map[string]resolvedHost
+}
+
+func newResolvedHostMap() *resolvedHostMap {
+	return &resolvedHostMap{entries: make(map[string]resolvedHost)}
+}
+
+func (r *resolvedHostMap) find(host string) []string {
+	r.Lock()
+	defer r.Unlock()
+
+	entry, exists := r.entries[host]
+	if !exists || time.Now().After(entry.until) {
+		delete(r.entries, host)
+		return nil
+	}
+
+	return entry.addrs
+}
+
+func (r *resolvedHostMap) record(host string, addrs []string) {
+	r.Lock()
+	defer r.Unlock()
+
+	now := time.Now()
+
+	for key, entry := range r.entries {
+		if now.After(entry.until) {
+			delete(r.entries, key)
+		}
+	}
+
+	r.entries[host] = resolvedHost{addrs: addrs, until: now.Add(resolvedHostTTL)}
+}
+
+func (r *resolvedHostMap) forget(host string) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.entries, host)
+}
+
+// dialTCP dials addr over TCP. Should the host of addr be a hostname, it is resolved using the resolver configured
+// on this node, and every IP address it resolves to is dialed in order until one succeeds. Should all of them fail
+// to be dialed, the resolution is forgotten so that the hostname is resolved again the next time it is dialed.
+func (n *Node) dialTCP(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	ips := n.resolved.find(host)
+
+	if ips == nil {
+		ips, err = n.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q: %w", host, err)
+		}
+
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("failed to resolve %q: no addresses found", host)
+		}
+
+		n.resolved.record(host, ips)
+	}
+
+	for _, ip := range ips {
+		var conn net.Conn
+
+		conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	n.resolved.forget(host)
+
+	return nil, err
+}
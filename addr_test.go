@@ -0,0 +1,139 @@
+package noise_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeResolver struct {
+	sync.Mutex
+	hosts   map[string][]string
+	lookups int
+}
+
+func (r *fakeResolver) set(host string, addrs ...string) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.hosts[host] = addrs
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.lookups++
+
+	addrs, exists := r.hosts[host]
+	if !exists {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+func (r *fakeResolver) numLookups() int {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.lookups
+}
+
+func TestHostnameResolvedAtDialTime(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	resolver := &fakeResolver{hosts: make(map[string][]string)}
+	resolver.set("b.test", "192.0.2.1") // TEST-NET-1 (RFC 5737), which may not be dialed.
+
+	a, err := noise.NewNode(
+		noise.WithNodeResolver(resolver),
+		noise.WithNodeMaxDialAttempts(1),
+		noise.WithNodeDialFailureCooldown(0),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	addr := net.JoinHostPort("b.test", strconv.FormatUint(uint64(b.ID().Port), 10))
+
+	// Dialing the stale address b.test resolves to fails.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err = a.Ping(ctx, addr)
+	assert.Error(t, err)
+	assert.Equal(t, 1, resolver.numLookups())
+
+	// Having failed to dial b, b.test is resolved again the next time it is dialed.
+
+	resolver.set("b.test", "127.0.0.1")
+
+	client, err := a.Ping(context.Background(), addr)
+	assert.NoError(t, err)
+	assert.Equal(t, b.ID().ID, client.ID().ID)
+	assert.Equal(t, addr, client.Addr())
+	assert.Equal(t, 2, resolver.numLookups())
+
+	_, err = a.Ping(context.Background(), net.JoinHostPort("unknown.test", "3000"))
+	assert.Error(t, err)
+}
+
+func TestHostnameAdvertisedInID(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeAddress("peer.test:3000"))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	id := a.ID()
+
+	assert.Equal(t, "peer.test:3000", id.Address)
+	assert.Nil(t, id.Host)
+	assert.EqualValues(t, 3000, id.Port)
+	assert.Len(t, id.Addrs, 1)
+	assert.Equal(t, noise.AddressDNS, id.Addrs[0].Type)
+	assert.Equal(t, "peer.test", id.Addrs[0].Name)
+
+	assert.NoError(t, a.UpdateAddress(fmt.Sprintf("other.test:%d", 3001)))
+	assert.Equal(t, "other.test:3001", a.ID().Address)
+}
+
+func TestConfiguredAddressKeptAsConfigured(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeAddress("[::ffff:192.0.2.1]:3000"))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	// The ID of a carries the address as it was resolved, though the address is kept as configured.
+
+	assert.Equal(t, "[::ffff:192.0.2.1]:3000", a.Addr())
+	assert.Equal(t, "192.0.2.1:3000", a.ID().Address)
+
+	assert.NoError(t, a.UpdateAddress("peer.test:3001"))
+	assert.Equal(t, "peer.test:3001", a.Addr())
+}
@@ -2,6 +2,7 @@ package noise
 
 import (
 	"crypto/cipher"
+	"fmt"
 	"io"
 	"math/rand"
 )
@@ -26,12 +27,24 @@ func extendBack(buf []byte, n int) []byte {
 }
 
 func encryptAEAD(suite cipher.AEAD, buf []byte) ([]byte, error) {
+	return encryptAEADWithNonce(suite, buf, nil)
+}
+
+// encryptAEADWithNonce encrypts buf under nonce, which is prepended to the ciphertext. A random nonce is picked should
+// nonce be nil.
+func encryptAEADWithNonce(suite cipher.AEAD, buf []byte, nonce []byte) ([]byte, error) {
 	a, b := suite.NonceSize(), len(buf)
 
+	if nonce != nil && len(nonce) != a {
+		return nil, fmt.Errorf("got nonce of %d byte(s), but expected %d byte(s)", len(nonce), a)
+	}
+
 	buf = extendFront(buf, a)
 	buf = extendBack(buf, b)
 
-	if _, err := rand.Read(buf[:a]); err != nil {
+	if nonce != nil {
+		copy(buf[:a], nonce)
+	} else if _, err := rand.Read(buf[:a]); err != nil {
 		return nil, err
 	}
 
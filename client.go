@@ -2,13 +2,14 @@ package noise
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/binary"
-	"encoding/hex"
 	"errors"
 	"fmt"
+	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"io"
 	"net"
@@ -16,19 +17,60 @@ import (
 	"time"
 )
 
-type clientSide bool
+// ClientSide denotes whether the connection of a client was accepted from or dialed to its peer.
+type ClientSide uint8
 
 const (
-	clientSideInbound  clientSide = false
-	clientSideOutbound clientSide = true
+	// ClientSideInbound denotes a client whose connection was accepted from its peer.
+	ClientSideInbound ClientSide = iota + 1
+
+	// ClientSideOutbound denotes a client whose connection was dialed to its peer.
+	ClientSideOutbound
 )
 
-func (c clientSide) String() string {
-	if c {
+// String returns either "inbound" or "outbound".
+func (s ClientSide) String() string {
+	switch s {
+	case ClientSideInbound:
+		return "inbound"
+	case ClientSideOutbound:
 		return "outbound"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(s))
 	}
+}
+
+// ClientStats is a snapshot of the activity over the connection of a client.
+type ClientStats struct {
+	// Side denotes whether the connection was accepted from or dialed to the peer.
+	Side ClientSide
+
+	// BytesSent and BytesReceived are the number of bytes sent and received over the connection, including framing.
+	BytesSent     uint64
+	BytesReceived uint64
+
+	// FramesSent and FramesReceived are the number of frames sent and received over the connection, including
+	// handshake and control frames.
+	FramesSent     uint64
+	FramesReceived uint64
+
+	// PendingRequests is the number of requests sent to the peer that are awaiting a response.
+	PendingRequests int
 
-	return "inbound"
+	// QueuedMessages is the number of messages queued to be written to the connection.
+	QueuedMessages int
+
+	// ConnectedAt is the time the connection was established, and Age is the time elapsed since. Both are zero
+	// should the connection not yet be established.
+	ConnectedAt time.Time
+	Age         time.Duration
+
+	// LastActivity is the last time a frame was sent or received over the connection.
+	LastActivity time.Time
+
+	// RequestRTT is the smoothed round-trip time of requests sent to the peer that were responded to. It is zero
+	// should no request have been responded to yet.
+	RequestRTT time.Duration
 }
 
 // Client represents an pooled inbound/outbound connection under some node. Should a client successfully undergo
@@ -43,17 +85,23 @@ func (c clientSide) String() string {
 // provably has been useful in writing unit tests where a client instance is used under high concurrency scenarios.
 //
 // A client in total has two goroutines associated to it: a goroutine responsible for handling writing messages, and a
-// goroutine responsible for handling the recipient of messages.
+// goroutine responsible for handling the recipient of messages. Should heartbeats be enabled via
+// WithNodeHeartbeatInterval, a third goroutine is associated to it which is responsible for sending heartbeats.
 type Client struct {
 	node *Node
 
-	id ID
+	id       ID
+	expected PublicKey
 
 	addr string
-	side clientSide
+	side ClientSide
 
 	suite cipher.AEAD
 
+	envelope uint8
+	control  bool
+	remap    opcodeRemap
+
 	logger struct {
 		sync.RWMutex
 		*zap.Logger
@@ -71,6 +119,30 @@ type Client struct {
 	writerClosed bool
 
 	requests *requestMap
+	circuits *circuitMap
+
+	// ctx is cancelled once the client is closed, and is the parent of the context of every message being handled.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// handling holds the requests from the peer that are being handled, keyed by their nonce.
+	handling struct {
+		sync.Mutex
+		entries map[uint64]handledRequest
+	}
+
+	lastRecv         atomic.Int64
+	heartbeatsMissed atomic.Uint32
+	heartbeats       sync.WaitGroup
+	rtt              atomic.Duration
+
+	bytesSent   atomic.Uint64
+	bytesRecv   atomic.Uint64
+	framesSent  atomic.Uint64
+	framesRecv  atomic.Uint64
+	connectedAt atomic.Int64
+	lastSent    atomic.Int64
+	requestRTT  atomic.Int64
 
 	ready      chan struct{}
 	readerDone chan struct{}
@@ -85,11 +157,13 @@ type Client struct {
 	closeOnce sync.Once
 }
 
-func newClient(node *Node) *Client {
+func newClient(node *Node, side ClientSide) *Client {
 	c := &Client{
 		node: node,
+		side: side,
 
 		requests: newRequestMap(),
+		circuits: newCircuitMap(),
 
 		readerBuf: make([]byte, 4+node.maxRecvMessageSize),
 
@@ -102,6 +176,9 @@ func newClient(node *Node) *Client {
 
 	c.writerCond.L = &sync.Mutex{}
 
+	c.ctx, c.cancel = context.WithCancel(node.ctx)
+	c.handling.entries = make(map[uint64]handledRequest)
+
 	c.SetLogger(node.logger)
 
 	return c
@@ -115,6 +192,23 @@ func (c *Client) ID() ID {
 	return c.id
 }
 
+// Addr returns the address this client is pooled under on its associated node. For outbound clients, it is the
+// address the peer was dialed through. For inbound clients, it is the remote address of the peer.
+//
+// Addr may be called concurrently.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+// EnvelopeVersion returns the message envelope version negotiated with the peer during the handshake. It is zero
+// should the peer predate versioned message envelopes, in which case headers of messages sent to the peer are
+// dropped. See Header.
+//
+// EnvelopeVersion may be called concurrently.
+func (c *Client) EnvelopeVersion() uint8 {
+	return c.envelope
+}
+
 // Logger returns the underlying logger associated to this client. It may optionally be set via (*Client).SetLogger.
 //
 // Logger may be called concurrently.
@@ -135,6 +229,50 @@ func (c *Client) SetLogger(logger *zap.Logger) {
 	c.logger.Logger = logger
 }
 
+// RTT returns the smoothed round-trip time to the peer of this client, sampled from heartbeats sent over the
+// connection. It returns zero should no heartbeat have been acknowledged by the peer yet, should heartbeats be
+// disabled on the node via WithNodeHeartbeatInterval, or should the peer predate heartbeats.
+//
+// RTT may be called concurrently.
+func (c *Client) RTT() time.Duration {
+	return c.rtt.Load()
+}
+
+// Stats returns a snapshot of the activity over the connection of this client.
+//
+// Stats may be called concurrently.
+func (c *Client) Stats() ClientStats {
+	stats := ClientStats{
+		Side:            c.side,
+		BytesSent:       c.bytesSent.Load(),
+		BytesReceived:   c.bytesRecv.Load(),
+		FramesSent:      c.framesSent.Load(),
+		FramesReceived:  c.framesRecv.Load(),
+		PendingRequests: c.requests.len(),
+		RequestRTT:      time.Duration(c.requestRTT.Load()),
+	}
+
+	c.writerCond.L.Lock()
+	stats.QueuedMessages = len(c.writerBuf)
+	c.writerCond.L.Unlock()
+
+	if connectedAt := c.connectedAt.Load(); connectedAt != 0 {
+		stats.ConnectedAt = time.Unix(0, connectedAt)
+		stats.Age = time.Since(stats.ConnectedAt)
+	}
+
+	lastActivity := c.lastRecv.Load()
+	if lastSent := c.lastSent.Load(); lastSent > lastActivity {
+		lastActivity = lastSent
+	}
+
+	if lastActivity != 0 {
+		stats.LastActivity = time.Unix(0, lastActivity)
+	}
+
+	return stats
+}
+
 // Close asynchronously kills the underlying connection and signals all goroutines to stop underlying this client.
 //
 // Close may be called concurrently.
@@ -186,37 +324,73 @@ func (c *Client) reportError(err error) {
 
 func (c *Client) close() {
 	c.closeOnce.Do(func() {
+		c.cancel()
+
 		c.writerCond.L.Lock()
 		c.writerClosed = true
 		c.writerCond.Signal()
+		conn := c.conn
 		c.writerCond.L.Unlock()
 
-		if c.conn != nil {
-			c.conn.Close()
+		if conn != nil {
+			conn.Close()
 		}
 	})
 }
 
+// setConn sets the underlying connection of this client. Should the client have already been closed, the connection
+// is closed immediately.
+func (c *Client) setConn(conn net.Conn) {
+	c.connectedAt.Store(time.Now().UnixNano())
+
+	c.writerCond.L.Lock()
+	c.conn = conn
+	closed := c.writerClosed
+	c.writerCond.L.Unlock()
+
+	if closed {
+		conn.Close()
+	}
+}
+
 func (c *Client) waitUntilReady() {
 	<-c.ready
 }
 
+func (c *Client) isReady() bool {
+	select {
+	case <-c.ready:
+		return true
+	default:
+		return false
+	}
+}
+
 func (c *Client) waitUntilClosed() {
 	<-c.clientDone
 }
 
 func (c *Client) outbound(ctx context.Context, addr string) {
 	c.addr = addr
-	c.side = clientSideInbound
 
 	defer func() {
 		c.node.outbound.remove(addr)
+		c.cancel()
 		close(c.clientDone)
 	}()
 
-	var dialer net.Dialer
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if relayAddr, target, ok := parseRelayAddress(addr); ok {
+		c.expected = target
+		conn, err = c.node.dialRelay(ctx, relayAddr, target)
+	} else {
+		conn, err = c.node.dialTCP(ctx, addr)
+	}
 
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
 		c.reportError(err)
 		close(c.ready)
@@ -227,14 +401,25 @@ func (c *Client) outbound(ctx context.Context, addr string) {
 
 	c.reader = bufio.NewReader(conn)
 	c.writer = bufio.NewWriter(conn)
-	c.conn = conn
+	c.setConn(conn)
 
 	c.handshake()
 
+	if c.Error() == nil {
+		c.reportObservedAddress()
+		c.startHeartbeats()
+	}
+
 	go c.writeLoop()
 	c.recvLoop()
 	c.close()
 
+	c.requests.close()
+	c.circuits.close()
+	c.node.relays.release(c)
+
+	c.heartbeats.Wait()
+
 	c.Logger().Debug("Peer connection closed.")
 
 	for _, protocol := range c.node.protocols {
@@ -248,16 +433,16 @@ func (c *Client) outbound(ctx context.Context, addr string) {
 
 func (c *Client) inbound(conn net.Conn, addr string) {
 	c.addr = addr
-	c.side = clientSideOutbound
 
 	defer func() {
 		c.node.inbound.remove(addr)
+		c.cancel()
 		close(c.clientDone)
 	}()
 
 	c.reader = bufio.NewReader(conn)
 	c.writer = bufio.NewWriter(conn)
-	c.conn = conn
+	c.setConn(conn)
 
 	c.handshake()
 
@@ -266,10 +451,19 @@ func (c *Client) inbound(conn net.Conn, addr string) {
 		return
 	}
 
+	c.reportObservedAddress()
+	c.startHeartbeats()
+
 	go c.writeLoop()
 	c.recvLoop()
 	c.close()
 
+	c.requests.close()
+	c.circuits.close()
+	c.node.relays.release(c)
+
+	c.heartbeats.Wait()
+
 	for _, protocol := range c.node.protocols {
 		if protocol.OnPeerDisconnected == nil {
 			continue
@@ -280,39 +474,54 @@ func (c *Client) inbound(conn net.Conn, addr string) {
 }
 
 func (c *Client) read() ([]byte, error) {
+	buf, _, err := c.readWithNonce()
+	return buf, err
+}
+
+// readWithNonce reads a single frame, and returns it alongside the nonce it was encrypted under, which is nil should
+// no session have been established yet. Both are only valid until the next frame is read.
+func (c *Client) readWithNonce() ([]byte, []byte, error) {
 	if c.node.idleTimeout > 0 {
 		if err := c.conn.SetReadDeadline(time.Now().Add(c.node.idleTimeout)); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	if _, err := io.ReadFull(c.reader, c.readerBuf[:4]); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	size := binary.BigEndian.Uint32(c.readerBuf[:4])
 
 	if c.node.maxRecvMessageSize > 0 && size > c.node.maxRecvMessageSize {
-		return nil, fmt.Errorf("got %d bytes, but limit is set to %d: %w", size, c.node.maxRecvMessageSize, ErrMessageTooLarge)
+		return nil, nil, fmt.Errorf("got %d bytes, but limit is set to %d: %w", size, c.node.maxRecvMessageSize, ErrMessageTooLarge)
 	}
 
 	if _, err := io.ReadFull(c.reader, c.readerBuf[4:size+4]); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	c.recordRecv(int(size) + 4)
+
 	if c.suite == nil {
-		return c.readerBuf[4 : size+4], nil
+		return c.readerBuf[4 : size+4], nil, nil
 	}
 
 	buf, err := decryptAEAD(c.suite, c.readerBuf[4:size+4])
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return buf, nil
+	return buf, c.readerBuf[4 : 4+c.suite.NonceSize()], nil
 }
 
 func (c *Client) write(data []byte) error {
+	return c.writeWithNonce(data, nil)
+}
+
+// writeWithNonce writes data as a single frame, encrypted under nonce should a session have been established. A
+// random nonce is picked should nonce be nil.
+func (c *Client) writeWithNonce(data []byte, nonce []byte) error {
 	if c.node.idleTimeout > 0 {
 		if err := c.conn.SetWriteDeadline(time.Now().Add(c.node.idleTimeout)); err != nil {
 			return err
@@ -322,7 +531,7 @@ func (c *Client) write(data []byte) error {
 	if c.suite != nil {
 		var err error
 
-		if data, err = encryptAEAD(c.suite, data); err != nil {
+		if data, err = encryptAEADWithNonce(c.suite, data, nonce); err != nil {
 			return err
 		}
 	}
@@ -334,19 +543,48 @@ func (c *Client) write(data []byte) error {
 		return err
 	}
 
+	c.recordSent(len(data))
+
 	return c.writer.Flush()
 }
 
 func (c *Client) send(nonce uint64, data []byte) error {
+	return c.enqueue(message{nonce: nonce, data: data})
+}
+
+func (c *Client) enqueue(msg message) error {
+	// Check that the headers of msg fit within its header block before it is queued, so that the error may be
+	// returned to the sender of msg.
+
+	if msg.nonce != controlNonce && !msg.header.IsZero() {
+		if _, err := marshalHeader(nil, msg.header); err != nil {
+			return err
+		}
+	}
+
 	c.writerCond.L.Lock()
-	c.writerBuf = append(c.writerBuf, message{nonce: nonce, data: data})
+	c.writerBuf = append(c.writerBuf, msg)
 	c.writerCond.Signal()
 	c.writerCond.L.Unlock()
 
 	return nil
 }
 
-func (c *Client) request(ctx context.Context, data []byte) (message, error) {
+func (c *Client) request(ctx context.Context, header Header, data []byte) (msg message, err error) {
+	start := time.Now()
+
+	defer func() {
+		if err != nil {
+			c.node.metrics.requestFails.Inc()
+			return
+		}
+
+		c.node.metrics.requests.Inc()
+		c.node.metrics.requestDuration.ObserveSince(start)
+
+		c.sampleRequestRTT(time.Since(start))
+	}()
+
 	// Figure out an available request nonce.
 
 	ch, nonce, err := c.requests.nextNonce()
@@ -354,32 +592,234 @@ func (c *Client) request(ctx context.Context, data []byte) (message, error) {
 		return message{}, err
 	}
 
-	// Send request.
+	// Send request, alongside the deadline of ctx should no other deadline be set.
 
-	if err := c.send(nonce, data); err != nil {
+	if deadline, ok := ctx.Deadline(); ok && header.Deadline.IsZero() {
+		header.Deadline = deadline
+	}
+
+	if err := c.enqueue(message{nonce: nonce, header: header, data: data}); err != nil {
 		c.requests.markRequestFailed(nonce)
 		return message{}, err
 	}
 
-	// Await response.
-
-	var msg message
+	// Await response. Should ctx be canceled/expired, abandon the request and have the peer cancel handling it.
 
 	select {
 	case msg = <-ch:
 		if msg.nonce == 0 {
 			return message{}, io.EOF
 		}
+
+		if msg.header.err != nil {
+			return message{}, msg.header.err
+		}
+
+		// Should the peer stream back responses, only the first is awaited, and the peer stops streaming the rest.
+
+		if msg.header.stream == streamItem {
+			c.sendCancel(msg.nonce)
+		}
 	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			c.node.metrics.requestTimeouts.Inc()
+		} else {
+			c.node.metrics.requestCancels.Inc()
+		}
+
+		if c.requests.abandonRequest(nonce) {
+			c.sendCancel(nonce)
+		}
+
 		return message{}, ctx.Err()
 	}
 
 	return msg, nil
 }
 
+// requestStream sends a request, and returns the stream of responses the peer sends back to it.
+func (c *Client) requestStream(ctx context.Context, header Header, data []byte) (*ResponseStream, error) {
+	pending, nonce, err := c.requests.nextStream(ctx.Done())
+	if err != nil {
+		c.node.metrics.requestFails.Inc()
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && header.Deadline.IsZero() {
+		header.Deadline = deadline
+	}
+
+	if err := c.enqueue(message{nonce: nonce, header: header, data: data}); err != nil {
+		c.requests.abandonStream(nonce)
+		c.node.metrics.requestFails.Inc()
+
+		return nil, err
+	}
+
+	return &ResponseStream{client: c, ctx: ctx, nonce: nonce, pending: pending, start: time.Now()}, nil
+}
+
+// deliverStream delivers msg to the stream of responses it was streamed to without blocking, dropping msg should the
+// stream have been abandoned or closed. It returns an error should the peer have streamed back more responses than
+// it was granted credit for.
+func (c *Client) deliverStream(stream *pendingStream, msg message) error {
+	if msg.header.stream != streamItem {
+		c.requests.endStream(msg.nonce)
+	}
+
+	select {
+	case <-stream.abandoned:
+		return nil
+	case <-stream.done:
+		return nil
+	default:
+	}
+
+	select {
+	case stream.responses <- msg:
+		return nil
+	default:
+		return fmt.Errorf("peer streamed back more than %d responses ahead of those read", streamBufferSize)
+	}
+}
+
+// sendCredit sends a control frame to the peer granting it credit to stream back n more responses to the request
+// under nonce.
+func (c *Client) sendCredit(nonce uint64, n uint32) {
+	if !c.control {
+		return
+	}
+
+	var payload [8 + 4]byte
+	binary.BigEndian.PutUint64(payload[:8], nonce)
+	binary.BigEndian.PutUint32(payload[8:], n)
+
+	_ = c.send(controlNonce, marshalControl(controlStreamCredit, payload[:]))
+}
+
+// sendCancel sends a control frame to the peer cancelling the context its handlers handle the request under nonce
+// with. Peers that predate request cancellation ignore the control frame, and peers that predate control frames are
+// not sent it.
+func (c *Client) sendCancel(nonce uint64) {
+	if !c.control {
+		return
+	}
+
+	var payload [8]byte
+	binary.BigEndian.PutUint64(payload[:], nonce)
+
+	_ = c.send(controlNonce, marshalControl(controlCancel, payload[:]))
+}
+
+// handledRequest is a request from the peer that is being handled.
+type handledRequest struct {
+	cancel   context.CancelFunc
+	response *responseState
+}
+
+// newHandlerContext creates the context msg is handled under, which is cancelled once the client is closed, once the
+// deadline of msg elapses, or once the peer cancels the request msg is of.
+func (c *Client) newHandlerContext(msg message) HandlerContext {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if !msg.header.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(c.ctx, msg.header.Deadline)
+	} else {
+		ctx, cancel = context.WithCancel(c.ctx)
+	}
+
+	response := newResponseState()
+
+	if msg.nonce > 0 {
+		c.handling.Lock()
+		c.handling.entries[msg.nonce] = handledRequest{cancel: cancel, response: response}
+		c.handling.Unlock()
+	}
+
+	return HandlerContext{client: c, msg: msg, response: response, ctx: ctx, cancel: cancel}
+}
+
+// handled releases the context ctx was handled under once all handlers have handled it, and ends the stream of
+// responses handlers may have streamed back to it.
+func (c *Client) handled(ctx HandlerContext) {
+	if ctx.cancel == nil {
+		return
+	}
+
+	if ctx.IsRequest() {
+		ctx.response.Lock()
+
+		if ctx.response.status == responseStreaming {
+			ctx.response.status = responseSent
+			_ = c.enqueue(message{nonce: ctx.msg.nonce, header: Header{stream: streamEnd}})
+		}
+
+		ctx.response.Unlock()
+	}
+
+	ctx.cancel()
+
+	if ctx.msg.nonce > 0 {
+		c.handling.Lock()
+		delete(c.handling.entries, ctx.msg.nonce)
+		c.handling.Unlock()
+	}
+}
+
+// recordSent accounts for a frame of n bytes having been written to the connection.
+func (c *Client) recordSent(n int) {
+	c.bytesSent.Add(uint64(n))
+	c.framesSent.Inc()
+	c.lastSent.Store(time.Now().UnixNano())
+
+	c.node.metrics.bytesSent.Add(uint64(n))
+	c.node.metrics.framesSent.Inc()
+}
+
+// recordRecv accounts for a frame of n bytes having been read from the connection.
+func (c *Client) recordRecv(n int) {
+	c.bytesRecv.Add(uint64(n))
+	c.framesRecv.Inc()
+
+	c.node.metrics.bytesRecv.Add(uint64(n))
+	c.node.metrics.framesRecv.Inc()
+}
+
+// sampleRequestRTT folds the round-trip time of a completed request into the smoothed request round-trip time as an
+// exponentially-weighted moving average with a weight of 1/8, as is done for TCP (RFC 6298).
+func (c *Client) sampleRequestRTT(sample time.Duration) {
+	for {
+		current := c.requestRTT.Load()
+
+		next := int64(sample)
+		if current != 0 {
+			next = current + (int64(sample)-current)/8
+		}
+
+		if c.requestRTT.CAS(current, next) {
+			return
+		}
+	}
+}
+
 func (c *Client) handshake() {
 	defer close(c.ready)
 
+	start := time.Now()
+
+	defer func() {
+		if c.Error() != nil {
+			c.node.metrics.handshakeFails.Inc()
+			return
+		}
+
+		c.node.metrics.handshakes.Inc()
+		c.node.metrics.handshakeDuration.ObserveSince(start)
+	}()
+
 	// Generate Ed25519 ephemeral keypair to perform a Diffie-Hellman handshake.
 
 	pub, sec, err := GenerateKeys(nil)
@@ -450,57 +890,102 @@ func (c *Client) handshake() {
 
 	c.suite = suite
 
-	// Send to our peer our overlay ID.
+	// Send to our peer our overlay ID encoded in the legacy fixed-size format, and a signature of it alongside the
+	// shared key, as peers that predate the handshake extension do. The nonce it is encrypted under advertises our
+	// support for the handshake extension.
 
-	buf := c.node.id.Marshal()
-	signature = c.node.Sign(append(buf, shared...))
-	buf = append(buf, signature[:]...)
+	buf := c.node.ID().marshalLegacy()
 
-	if err := c.write(buf); err != nil {
-		c.reportError(fmt.Errorf("failed to send session handshake: %w", err))
+	signature, err = c.node.TrySign(append(buf, shared...))
+	if err != nil {
+		c.reportError(fmt.Errorf("failed to sign overlay handshake: %w", err))
+		return
+	}
+
+	if err := c.writeWithNonce(append(buf, signature[:]...), handshakeNonce(shared, pub, suite.NonceSize())); err != nil {
+		c.reportError(fmt.Errorf("failed to send overlay handshake: %w", err))
 		return
 	}
 
 	// Read and parse from our peer their overlay ID.
 
-	data, err = c.read()
+	data, nonce, err := c.readWithNonce()
 	if err != nil {
 		c.reportError(fmt.Errorf("failed to read overlay handshake: %w", err))
 		return
 	}
 
-	id, err := UnmarshalID(data)
+	if len(data) != legacyIDSize+SizeSignature {
+		c.reportError(fmt.Errorf("received invalid number of bytes handshaking: expected %d byte(s), got %d byte(s)",
+			legacyIDSize+SizeSignature,
+			len(data),
+		))
+
+		return
+	}
+
+	buf = make([]byte, legacyIDSize)
+	copy(buf, data)
+
+	id, err := unmarshalLegacyID(buf)
 	if err != nil {
 		c.reportError(fmt.Errorf("failed to parse peer id while handling overlay handshake: %w", err))
 		return
 	}
 
-	// Validate the peers ownership of the overlay ID.
+	// Validate the peers ownership of the overlay ID. Should our peer support the handshake extension, it instead
+	// proves its ownership of its peer record, as the public key of our peer need not be an Ed25519 public key.
 
-	buf = make([]byte, id.Size())
-	copy(buf, data)
+	var ext handshakeExtension
 
-	if len(data) != len(buf)+SizeSignature {
-		c.reportError(fmt.Errorf("received invalid number of bytes handshaking: expected %d byte(s), got %d byte(s)",
-			len(buf)+SizeSignature,
-			len(data),
-		))
+	if bytes.Equal(nonce, handshakeNonce(shared, peerPublicKey, suite.NonceSize())) {
+		if id, ext, err = c.exchangeHandshakeExtension(shared, id); err != nil {
+			c.reportError(err)
+			return
+		}
+	} else if !id.ID.Verify(append(buf, shared...), UnmarshalSignature(data[legacyIDSize:])) {
+		c.reportError(errors.New("overlay handshake signature is malformed"))
+		return
+	}
 
+	if c.expected != ZeroPublicKey && id.ID != c.expected {
+		c.reportError(fmt.Errorf("expected peer to have public key %s, but got %s", c.expected, id.ID))
 		return
 	}
 
-	if !id.ID.Verify(append(buf, shared...), UnmarshalSignature(data[len(buf):len(buf)+SizeSignature])) {
-		c.reportError(errors.New("overlay handshake signature is malformed"))
+	// Peers that predate opcode negotiation do not advertise their opcodes, and are presumed to have registered the
+	// same Go types in the same order under opcodes counted up from zero. They are unable to rewrite the opcodes of
+	// the messages they send, and so their opcodes are never remapped.
+
+	var remap opcodeRemap
+
+	if ext.envelope == 0 {
+		_, err = negotiateOpcodes(c.node.codec.table(), c.node.codec.legacyTable(), false)
+	} else {
+		remap, err = negotiateOpcodes(c.node.codec.table(), ext.opcodes, c.node.remapOpcodes)
+	}
+
+	if err != nil {
+		c.reportError(err)
 		return
 	}
 
 	c.id = id
 
+	// Peers of a newer version are only sent message envelopes of the version this node produces.
+
+	c.envelope = ext.envelope
+	if c.envelope > envelopeVersion {
+		c.envelope = envelopeVersion
+	}
+
+	c.control = ext.control
+	c.remap = remap
+
 	c.SetLogger(c.Logger().With(
 		zap.String("peer_id", id.ID.String()),
 		zap.String("peer_addr", id.Address),
 		zap.String("remote_addr", c.conn.RemoteAddr().String()),
-		zap.String("session_key", hex.EncodeToString(shared[:])),
 	))
 
 	c.Logger().Debug("Peer connection opened.")
@@ -514,6 +999,65 @@ func (c *Client) handshake() {
 	}
 }
 
+// exchangeHandshakeExtension sends to our peer our peer record followed by our handshake extension, signed alongside
+// the shared key of the session, and reads back the same from our peer. It is only called should our peer have
+// advertised support for the handshake extension, and returns an error should the peer record of our peer not bear
+// the public key of the overlay ID legacy our peer sent.
+func (c *Client) exchangeHandshakeExtension(shared []byte, legacy ID) (ID, handshakeExtension, error) {
+	ext := handshakeExtension{envelope: envelopeVersion, control: true, opcodes: c.node.codec.table()}
+
+	buf := ext.marshal(c.node.ID().Marshal())
+
+	signature, err := c.node.TrySign(append(buf, shared...))
+	if err != nil {
+		return ID{}, handshakeExtension{}, fmt.Errorf("failed to sign handshake extension: %w", err)
+	}
+
+	if err := c.write(append(buf, signature[:]...)); err != nil {
+		return ID{}, handshakeExtension{}, fmt.Errorf("failed to send handshake extension: %w", err)
+	}
+
+	data, err := c.read()
+	if err != nil {
+		return ID{}, handshakeExtension{}, fmt.Errorf("failed to read handshake extension: %w", err)
+	}
+
+	if len(data) < SizeSignature {
+		return ID{}, handshakeExtension{}, fmt.Errorf(
+			"received invalid number of bytes reading handshake extension: expected at least %d byte(s), got %d byte(s)",
+			SizeSignature,
+			len(data),
+		)
+	}
+
+	buf = make([]byte, len(data)-SizeSignature)
+	copy(buf, data)
+
+	id, n, err := unmarshalRecord(buf)
+	if err != nil {
+		return ID{}, handshakeExtension{}, fmt.Errorf("failed to parse peer record: %w", err)
+	}
+
+	ext, err = unmarshalHandshakeExtension(buf[n:])
+	if err != nil {
+		return ID{}, handshakeExtension{}, fmt.Errorf("failed to parse handshake extension: %w", err)
+	}
+
+	// Validate the peers ownership of the peer record.
+
+	if key := id.IdentityKey(); key.ID() != id.ID || !key.Verify(append(buf, shared...), UnmarshalSignature(data[len(buf):])) {
+		return ID{}, handshakeExtension{}, errors.New("handshake extension signature is malformed")
+	}
+
+	if id.ID != legacy.ID {
+		return ID{}, handshakeExtension{}, fmt.Errorf("peer record bears public key %s, but overlay handshake bore %s",
+			id.ID, legacy.ID,
+		)
+	}
+
+	return id, ext, nil
+}
+
 func (c *Client) recvLoop() {
 	defer close(c.readerDone)
 
@@ -528,6 +1072,9 @@ func (c *Client) recvLoop() {
 			break
 		}
 
+		c.lastRecv.Store(time.Now().UnixNano())
+		c.heartbeatsMissed.Store(0)
+
 		msg, err := unmarshalMessage(buf)
 		if err != nil {
 			c.Logger().Warn("Got an error while reading incoming messages.", zap.Error(err))
@@ -536,16 +1083,46 @@ func (c *Client) recvLoop() {
 			break
 		}
 
+		if msg.nonce == controlNonce {
+			if err := c.handleControl(msg.data); err != nil {
+				c.Logger().Warn("Got an error while handling a control frame.", zap.Error(err))
+				c.reportError(err)
+
+				break
+			}
+
+			continue
+		}
+
 		msg.data = append([]byte{}, msg.data...)
 
-		if ch := c.requests.findRequest(msg.nonce); ch != nil {
-			ch <- msg
-			close(ch)
+		if stream, ok := c.requests.findStream(msg.nonce); ok {
+			if err := c.deliverStream(stream, msg); err != nil {
+				c.Logger().Warn("Got an error while reading a stream of responses.", zap.Error(err))
+				c.reportError(err)
+
+				break
+			}
+
+			continue
+		}
+
+		if ch, ok := c.requests.findRequest(msg.nonce); ok {
+			if ch != nil {
+				ch <- msg
+				close(ch)
+			}
+
+			continue
+		}
 
+		// Streamed responses to requests that were abandoned, or that did not await a stream, are dropped.
+
+		if msg.header.stream != 0 {
 			continue
 		}
 
-		c.node.work <- HandlerContext{client: c, msg: msg}
+		c.node.work <- c.newHandlerContext(msg)
 
 		for _, protocol := range c.node.protocols {
 			if protocol.OnMessageRecv == nil {
@@ -573,17 +1150,6 @@ Write:
 		default:
 		}
 
-		if c.node.idleTimeout > 0 {
-			if err := c.conn.SetWriteDeadline(time.Now().Add(c.node.idleTimeout)); err != nil {
-				if !isEOF(err) {
-					c.Logger().Warn("Got an error setting write deadline.", zap.Error(err))
-				}
-				c.reportError(err)
-
-				break Write
-			}
-		}
-
 		c.writerCond.L.Lock()
 		for len(c.writerBuf) == 0 && !c.writerClosed {
 			c.writerCond.Wait()
@@ -596,13 +1162,32 @@ Write:
 			break Write
 		}
 
+		if c.node.idleTimeout > 0 {
+			if err := c.conn.SetWriteDeadline(time.Now().Add(c.node.idleTimeout)); err != nil {
+				if !isEOF(err) {
+					c.Logger().Warn("Got an error setting write deadline.", zap.Error(err))
+				}
+				c.reportError(err)
+
+				break Write
+			}
+		}
+
 		for _, msg := range writerBuf {
-			buf = buf[:0]
-			buf = msg.marshal(buf)
+			// Legacy peers would misparse a message carrying headers as having a different nonce.
 
-			if c.suite != nil {
-				var err error
+			if c.envelope == 0 {
+				msg.header = Header{}
+			}
+
+			var err error
+
+			if buf, err = msg.marshal(buf[:0]); err != nil {
+				c.Logger().Warn("Got an error marshaling a message.", zap.Error(err))
+				continue
+			}
 
+			if c.suite != nil {
 				if buf, err = encryptAEAD(c.suite, buf); err != nil {
 					c.Logger().Warn("Got an error encrypting a message.", zap.Error(err))
 					c.reportError(err)
@@ -629,6 +1214,8 @@ Write:
 
 				break Write
 			}
+
+			c.recordSent(len(header) + len(buf))
 		}
 
 		if err := c.writer.Flush(); err != nil {
@@ -650,6 +1237,161 @@ Write:
 	}
 }
 
+// startHeartbeats starts sending heartbeats to the peer should heartbeats be enabled, and should the peer understand
+// control frames.
+func (c *Client) startHeartbeats() {
+	if c.node.heartbeatInterval <= 0 || !c.control {
+		return
+	}
+
+	c.lastRecv.Store(time.Now().UnixNano())
+
+	c.heartbeats.Add(1)
+
+	go func() {
+		defer c.heartbeats.Done()
+		c.heartbeatLoop()
+	}()
+}
+
+func (c *Client) heartbeatLoop() {
+	ticker := time.NewTicker(c.node.heartbeatInterval)
+	defer ticker.Stop()
+
+	payload := make([]byte, 8)
+
+	for {
+		select {
+		case <-c.readerDone:
+			return
+		case <-c.writerDone:
+			return
+		case <-ticker.C:
+		}
+
+		// Only send heartbeats should the connection have been quiet for at least a single heartbeat interval.
+
+		if time.Since(time.Unix(0, c.lastRecv.Load())) < c.node.heartbeatInterval {
+			continue
+		}
+
+		if missed := c.heartbeatsMissed.Load(); missed >= uint32(c.node.heartbeatMaxMissed) {
+			err := fmt.Errorf("peer did not respond to %d heartbeat(s): %w", missed, ErrPeerUnresponsive)
+
+			c.Logger().Warn("Peer is unresponsive.", zap.Error(err))
+			c.reportError(err)
+			c.close()
+
+			return
+		}
+
+		c.heartbeatsMissed.Inc()
+
+		binary.BigEndian.PutUint64(payload, uint64(time.Now().UnixNano()))
+
+		if err := c.send(controlNonce, marshalControl(controlHeartbeat, payload)); err != nil {
+			c.reportError(err)
+			c.close()
+
+			return
+		}
+	}
+}
+
+// reportObservedAddress reports to our peer the address we observe our peer to have, should the peer understand
+// control frames.
+func (c *Client) reportObservedAddress() {
+	addr, ok := c.conn.RemoteAddr().(*net.TCPAddr)
+	if !ok || !c.control {
+		return
+	}
+
+	payload := make([]byte, net.IPv6len+2)
+	copy(payload[:net.IPv6len], addr.IP.To16())
+	binary.BigEndian.PutUint16(payload[net.IPv6len:], uint16(addr.Port))
+
+	_ = c.send(controlNonce, marshalControl(controlObservedAddress, payload))
+}
+
+func (c *Client) handleControl(data []byte) error {
+	if len(data) < 1 {
+		return io.ErrUnexpectedEOF
+	}
+
+	kind, payload := controlKind(data[0]), data[1:]
+
+	switch kind {
+	case controlHeartbeat:
+		return c.send(controlNonce, marshalControl(controlHeartbeatAck, payload))
+	case controlObservedAddress:
+		if len(payload) != net.IPv6len+2 {
+			return fmt.Errorf("got observed address of %d byte(s), but expected %d byte(s): %w",
+				len(payload), net.IPv6len+2, io.ErrUnexpectedEOF,
+			)
+		}
+
+		host := make(net.IP, net.IPv6len)
+		copy(host, payload[:net.IPv6len])
+
+		c.node.observeAddress(c, host, binary.BigEndian.Uint16(payload[net.IPv6len:]))
+	case controlCancel:
+		if len(payload) != 8 {
+			return fmt.Errorf("got request cancellation of %d byte(s), but expected 8 byte(s): %w",
+				len(payload), io.ErrUnexpectedEOF,
+			)
+		}
+
+		c.node.metrics.cancelsRecv.Inc()
+
+		c.handling.Lock()
+		request, exists := c.handling.entries[binary.BigEndian.Uint64(payload)]
+		c.handling.Unlock()
+
+		if exists {
+			request.cancel()
+		}
+	case controlStreamCredit:
+		if len(payload) != 8+4 {
+			return fmt.Errorf("got stream credit of %d byte(s), but expected 12 byte(s): %w",
+				len(payload), io.ErrUnexpectedEOF,
+			)
+		}
+
+		c.handling.Lock()
+		request, exists := c.handling.entries[binary.BigEndian.Uint64(payload[:8])]
+		c.handling.Unlock()
+
+		if exists {
+			request.response.grant(binary.BigEndian.Uint32(payload[8:]))
+		}
+	case controlHeartbeatAck:
+		if len(payload) != 8 {
+			return fmt.Errorf("got heartbeat ack of %d byte(s), but expected 8 byte(s): %w",
+				len(payload), io.ErrUnexpectedEOF,
+			)
+		}
+
+		sample := time.Since(time.Unix(0, int64(binary.BigEndian.Uint64(payload))))
+		if sample < 0 {
+			sample = 0
+		}
+
+		// Smooth out round-trip time samples the same way TCP does (RFC 6298).
+
+		if rtt := c.rtt.Load(); rtt > 0 {
+			sample = rtt - rtt/8 + sample/8
+		}
+
+		c.rtt.Store(sample)
+	case controlRelayOpen, controlRelayOpenAck, controlRelayIncoming, controlRelayData, controlRelayClose:
+		return c.handleRelayControl(kind, payload)
+	}
+
+	// Unknown control frames are ignored so that newer peers may introduce new kinds of control frames.
+
+	return nil
+}
+
 func isEOF(err error) bool {
 	if errors.Is(err, io.EOF) {
 		return true
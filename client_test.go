@@ -0,0 +1,115 @@
+package noise_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestClientStats(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	before := time.Now()
+
+	for i := 0; i < 3; i++ {
+		_, err = a.Request(context.Background(), b.Addr(), []byte("hello"))
+		assert.NoError(t, err)
+	}
+
+	clients := a.Outbound()
+	assert.Len(t, clients, 1)
+
+	stats := clients[0].Stats()
+
+	assert.Equal(t, noise.ClientSideOutbound, stats.Side)
+	assert.Equal(t, "outbound", stats.Side.String())
+
+	// 3 requests and responses, on top of the handshake.
+
+	assert.True(t, stats.FramesSent >= 3+2, "sent %d frames", stats.FramesSent)
+	assert.True(t, stats.FramesReceived >= 3+2, "received %d frames", stats.FramesReceived)
+	assert.True(t, stats.BytesSent > stats.FramesSent*4)
+	assert.True(t, stats.BytesReceived > stats.FramesReceived*4)
+
+	assert.Zero(t, stats.PendingRequests)
+	assert.Zero(t, stats.QueuedMessages)
+	assert.NotZero(t, stats.RequestRTT)
+
+	assert.False(t, stats.ConnectedAt.IsZero())
+	assert.True(t, stats.Age > 0)
+	assert.False(t, stats.LastActivity.Before(before))
+
+	waitFor(t, func() bool { return len(b.Inbound()) == 1 })
+
+	inbound := b.Inbound()[0].Stats()
+
+	assert.Equal(t, noise.ClientSideInbound, inbound.Side)
+	assert.Zero(t, inbound.RequestRTT)
+}
+
+func TestPendingRequestsFailOnClose(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		<-ctx.Context().Done()
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	result := make(chan error, 1)
+
+	go func() {
+		_, err := a.Request(context.Background(), b.Addr(), []byte("hello"))
+		result <- err
+	}()
+
+	waitFor(t, func() bool { return len(a.Outbound()) == 1 && a.Outbound()[0].Stats().PendingRequests == 1 })
+
+	// Requests pending on a connection fail with io.EOF once it is closed, rather than awaiting their context.
+
+	a.Outbound()[0].Close()
+
+	select {
+	case err := <-result:
+		assert.Equal(t, io.EOF, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("pending request did not fail once its connection was closed")
+	}
+}
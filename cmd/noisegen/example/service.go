@@ -0,0 +1,61 @@
+// Package example declares a key/value store service, whose messages, server registration, and client stub are
+// generated by noisegen.
+package example
+
+//go:generate go run github.com/perlin-network/noise/cmd/noisegen -o service_noise.go service.go
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+)
+
+// Consistency denotes how up to date a read must be.
+type Consistency uint8
+
+const (
+	// Eventual reads may return stale values.
+	Eventual Consistency = iota
+
+	// Strong reads always return the latest value.
+	Strong
+)
+
+// Entry is a single key/value pair.
+type Entry struct {
+	Key     string
+	Value   []byte
+	Version uint64
+	TTL     int32
+}
+
+// GetRequest requests the entry under Key.
+type GetRequest struct {
+	Key         string
+	Consistency Consistency
+}
+
+// GetResponse holds the entry under the requested key, should it be Found.
+type GetResponse struct {
+	Found bool
+	Entry Entry
+}
+
+// PutRequest requests Entries to be stored on behalf of Owner.
+type PutRequest struct {
+	Owner    noise.ID
+	Entries  []Entry
+	Checksum [4]byte
+	Weight   float64
+}
+
+// PutResponse lists the versions of the entries that were stored.
+type PutResponse struct {
+	Versions []uint64
+	Stored   noise.PublicKey
+}
+
+// Store is a key/value store served over noise.
+type Store interface {
+	Get(ctx context.Context, req GetRequest) (GetResponse, error)
+	Put(ctx context.Context, req PutRequest) (PutResponse, error)
+}
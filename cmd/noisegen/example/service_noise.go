@@ -0,0 +1,405 @@
+// Code generated by noisegen. DO NOT EDIT.
+
+package example
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/perlin-network/noise"
+	"io"
+	"math"
+)
+
+var (
+	_ = binary.BigEndian
+	_ = io.ErrUnexpectedEOF
+	_ = math.Float64bits
+)
+
+// Marshal implements noise.Serializable and encodes GetRequest into its binary representation.
+func (m GetRequest) Marshal() []byte {
+	return m.marshalNoise(nil)
+}
+
+func (m GetRequest) marshalNoise(buf []byte) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	_ = scratch
+	n1 := binary.PutUvarint(scratch[:], uint64(len(m.Key)))
+	buf = append(buf, scratch[:n1]...)
+	buf = append(buf, m.Key...)
+	buf = append(buf, byte(m.Consistency))
+	return buf
+}
+
+// UnmarshalGetRequest decodes buf into GetRequest. It returns an error should buf be malformed, or should buf have
+// trailing bytes.
+func UnmarshalGetRequest(buf []byte) (GetRequest, error) {
+	m, rest, err := unmarshalNoiseGetRequest(buf)
+	if err != nil {
+		return GetRequest{}, err
+	}
+
+	if len(rest) != 0 {
+		return GetRequest{}, fmt.Errorf("got %d trailing byte(s) decoding GetRequest", len(rest))
+	}
+
+	return m, nil
+}
+
+func unmarshalNoiseGetRequest(buf []byte) (GetRequest, []byte, error) {
+	var m GetRequest
+	size2, n3 := binary.Uvarint(buf)
+	if n3 <= 0 || uint64(len(buf)-n3) < size2 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Key = string(buf[n3 : n3+int(size2)])
+	buf = buf[n3+int(size2):]
+	if len(buf) < 1 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Consistency = Consistency(buf[0])
+	buf = buf[1:]
+	return m, buf, nil
+}
+
+// Marshal implements noise.Serializable and encodes GetResponse into its binary representation.
+func (m GetResponse) Marshal() []byte {
+	return m.marshalNoise(nil)
+}
+
+func (m GetResponse) marshalNoise(buf []byte) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	_ = scratch
+	if m.Found {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	buf = m.Entry.marshalNoise(buf)
+	return buf
+}
+
+// UnmarshalGetResponse decodes buf into GetResponse. It returns an error should buf be malformed, or should buf have
+// trailing bytes.
+func UnmarshalGetResponse(buf []byte) (GetResponse, error) {
+	m, rest, err := unmarshalNoiseGetResponse(buf)
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	if len(rest) != 0 {
+		return GetResponse{}, fmt.Errorf("got %d trailing byte(s) decoding GetResponse", len(rest))
+	}
+
+	return m, nil
+}
+
+func unmarshalNoiseGetResponse(buf []byte) (GetResponse, []byte, error) {
+	var m GetResponse
+	if len(buf) < 1 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Found = buf[0] != 0
+	buf = buf[1:]
+	value4, rest5, err6 := unmarshalNoiseEntry(buf)
+	if err6 != nil {
+		return m, nil, err6
+	}
+	m.Entry = value4
+	buf = rest5
+	return m, buf, nil
+}
+
+// Marshal implements noise.Serializable and encodes Entry into its binary representation.
+func (m Entry) Marshal() []byte {
+	return m.marshalNoise(nil)
+}
+
+func (m Entry) marshalNoise(buf []byte) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	_ = scratch
+	n7 := binary.PutUvarint(scratch[:], uint64(len(m.Key)))
+	buf = append(buf, scratch[:n7]...)
+	buf = append(buf, m.Key...)
+	n8 := binary.PutUvarint(scratch[:], uint64(len(m.Value)))
+	buf = append(buf, scratch[:n8]...)
+	buf = append(buf, m.Value...)
+	binary.BigEndian.PutUint64(scratch[:8], uint64(m.Version))
+	buf = append(buf, scratch[:8]...)
+	binary.BigEndian.PutUint32(scratch[:4], uint32(m.TTL))
+	buf = append(buf, scratch[:4]...)
+	return buf
+}
+
+// UnmarshalEntry decodes buf into Entry. It returns an error should buf be malformed, or should buf have
+// trailing bytes.
+func UnmarshalEntry(buf []byte) (Entry, error) {
+	m, rest, err := unmarshalNoiseEntry(buf)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if len(rest) != 0 {
+		return Entry{}, fmt.Errorf("got %d trailing byte(s) decoding Entry", len(rest))
+	}
+
+	return m, nil
+}
+
+func unmarshalNoiseEntry(buf []byte) (Entry, []byte, error) {
+	var m Entry
+	size9, n10 := binary.Uvarint(buf)
+	if n10 <= 0 || uint64(len(buf)-n10) < size9 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Key = string(buf[n10 : n10+int(size9)])
+	buf = buf[n10+int(size9):]
+	size11, n12 := binary.Uvarint(buf)
+	if n12 <= 0 || uint64(len(buf)-n12) < size11 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Value = append([]byte(nil), buf[n12:n12+int(size11)]...)
+	buf = buf[n12+int(size11):]
+	if len(buf) < 8 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Version = binary.BigEndian.Uint64(buf[:8])
+	buf = buf[8:]
+	if len(buf) < 4 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.TTL = int32(binary.BigEndian.Uint32(buf[:4]))
+	buf = buf[4:]
+	return m, buf, nil
+}
+
+// Marshal implements noise.Serializable and encodes PutRequest into its binary representation.
+func (m PutRequest) Marshal() []byte {
+	return m.marshalNoise(nil)
+}
+
+func (m PutRequest) marshalNoise(buf []byte) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	_ = scratch
+	record13 := m.Owner.Marshal()
+	n14 := binary.PutUvarint(scratch[:], uint64(len(record13)))
+	buf = append(buf, scratch[:n14]...)
+	buf = append(buf, record13...)
+	n15 := binary.PutUvarint(scratch[:], uint64(len(m.Entries)))
+	buf = append(buf, scratch[:n15]...)
+	for i16 := range m.Entries {
+		buf = m.Entries[i16].marshalNoise(buf)
+	}
+	buf = append(buf, m.Checksum[:]...)
+	binary.BigEndian.PutUint64(scratch[:8], math.Float64bits(float64(m.Weight)))
+	buf = append(buf, scratch[:8]...)
+	return buf
+}
+
+// UnmarshalPutRequest decodes buf into PutRequest. It returns an error should buf be malformed, or should buf have
+// trailing bytes.
+func UnmarshalPutRequest(buf []byte) (PutRequest, error) {
+	m, rest, err := unmarshalNoisePutRequest(buf)
+	if err != nil {
+		return PutRequest{}, err
+	}
+
+	if len(rest) != 0 {
+		return PutRequest{}, fmt.Errorf("got %d trailing byte(s) decoding PutRequest", len(rest))
+	}
+
+	return m, nil
+}
+
+func unmarshalNoisePutRequest(buf []byte) (PutRequest, []byte, error) {
+	var m PutRequest
+	size17, n18 := binary.Uvarint(buf)
+	if n18 <= 0 || uint64(len(buf)-n18) < size17 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	id19, err20 := noise.UnmarshalID(buf[n18 : n18+int(size17)])
+	if err20 != nil {
+		return m, nil, err20
+	}
+	m.Owner = id19
+	buf = buf[n18+int(size17):]
+	count21, n22 := binary.Uvarint(buf)
+	if n22 <= 0 || uint64(len(buf)-n22) < count21 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	buf = buf[n22:]
+	m.Entries = make([]Entry, count21)
+	for i23 := range m.Entries {
+		value24, rest25, err26 := unmarshalNoiseEntry(buf)
+		if err26 != nil {
+			return m, nil, err26
+		}
+		m.Entries[i23] = value24
+		buf = rest25
+	}
+	if len(buf) < 4 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	copy(m.Checksum[:], buf[:4])
+	buf = buf[4:]
+	if len(buf) < 8 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	m.Weight = math.Float64frombits(binary.BigEndian.Uint64(buf[:8]))
+	buf = buf[8:]
+	return m, buf, nil
+}
+
+// Marshal implements noise.Serializable and encodes PutResponse into its binary representation.
+func (m PutResponse) Marshal() []byte {
+	return m.marshalNoise(nil)
+}
+
+func (m PutResponse) marshalNoise(buf []byte) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+	_ = scratch
+	n27 := binary.PutUvarint(scratch[:], uint64(len(m.Versions)))
+	buf = append(buf, scratch[:n27]...)
+	for i28 := range m.Versions {
+		binary.BigEndian.PutUint64(scratch[:8], uint64(m.Versions[i28]))
+		buf = append(buf, scratch[:8]...)
+	}
+	buf = append(buf, m.Stored[:]...)
+	return buf
+}
+
+// UnmarshalPutResponse decodes buf into PutResponse. It returns an error should buf be malformed, or should buf have
+// trailing bytes.
+func UnmarshalPutResponse(buf []byte) (PutResponse, error) {
+	m, rest, err := unmarshalNoisePutResponse(buf)
+	if err != nil {
+		return PutResponse{}, err
+	}
+
+	if len(rest) != 0 {
+		return PutResponse{}, fmt.Errorf("got %d trailing byte(s) decoding PutResponse", len(rest))
+	}
+
+	return m, nil
+}
+
+func unmarshalNoisePutResponse(buf []byte) (PutResponse, []byte, error) {
+	var m PutResponse
+	count29, n30 := binary.Uvarint(buf)
+	if n30 <= 0 || uint64(len(buf)-n30) < count29 {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	buf = buf[n30:]
+	m.Versions = make([]uint64, count29)
+	for i31 := range m.Versions {
+		if len(buf) < 8 {
+			return m, nil, io.ErrUnexpectedEOF
+		}
+		m.Versions[i31] = binary.BigEndian.Uint64(buf[:8])
+		buf = buf[8:]
+	}
+	if len(buf) < noise.SizePublicKey {
+		return m, nil, io.ErrUnexpectedEOF
+	}
+	copy(m.Stored[:], buf[:noise.SizePublicKey])
+	buf = buf[noise.SizePublicKey:]
+	return m, buf, nil
+}
+
+// RegisterStoreMessages registers all messages of the Store service to node. It must be called on every node that
+// makes requests to the Store service via StoreClient.
+func RegisterStoreMessages(node *noise.Node) {
+	node.RegisterMessage(GetRequest{}, UnmarshalGetRequest)
+	node.RegisterMessage(GetResponse{}, UnmarshalGetResponse)
+	node.RegisterMessage(PutRequest{}, UnmarshalPutRequest)
+	node.RegisterMessage(PutResponse{}, UnmarshalPutResponse)
+}
+
+// RegisterStoreServer registers all messages of the Store service to node, and routes requests to the Store service
+// to srv. Errors returned by srv are responded with as a *noise.RemoteError.
+func RegisterStoreServer(node *noise.Node, srv Store) {
+	RegisterStoreMessages(node)
+
+	node.HandleMessage(GetRequest{}, func(ctx noise.HandlerContext, req GetRequest) error {
+		if !ctx.IsRequest() {
+			return errors.New("got a GetRequest that was not sent as a request")
+		}
+
+		res, err := srv.Get(ctx.Context(), req)
+		if err != nil {
+			var remote *noise.RemoteError
+			if !errors.As(err, &remote) {
+				remote = &noise.RemoteError{Message: err.Error()}
+			}
+
+			return remote
+		}
+
+		return ctx.SendMessage(res)
+	})
+
+	node.HandleMessage(PutRequest{}, func(ctx noise.HandlerContext, req PutRequest) error {
+		if !ctx.IsRequest() {
+			return errors.New("got a PutRequest that was not sent as a request")
+		}
+
+		res, err := srv.Put(ctx.Context(), req)
+		if err != nil {
+			var remote *noise.RemoteError
+			if !errors.As(err, &remote) {
+				remote = &noise.RemoteError{Message: err.Error()}
+			}
+
+			return remote
+		}
+
+		return ctx.SendMessage(res)
+	})
+}
+
+// StoreClient is a typed client stub of the Store service, which makes requests to the peer at the address it was
+// created with.
+type StoreClient struct {
+	node *noise.Node
+	addr string
+}
+
+var _ Store = (*StoreClient)(nil)
+
+// NewStoreClient returns a client stub of the Store service, which makes requests to the peer at addr through
+// node. Messages of the Store service must be registered to node via RegisterStoreMessages.
+func NewStoreClient(node *noise.Node, addr string) *StoreClient {
+	return &StoreClient{node: node, addr: addr}
+}
+
+// Get sends req as a request to the Store service, and returns its response.
+func (c *StoreClient) Get(ctx context.Context, req GetRequest) (GetResponse, error) {
+	obj, err := c.node.RequestMessage(ctx, c.addr, req)
+	if err != nil {
+		return GetResponse{}, err
+	}
+
+	res, ok := obj.(GetResponse)
+	if !ok {
+		return GetResponse{}, fmt.Errorf("got a response of type %T, but expected GetResponse", obj)
+	}
+
+	return res, nil
+}
+
+// Put sends req as a request to the Store service, and returns its response.
+func (c *StoreClient) Put(ctx context.Context, req PutRequest) (PutResponse, error) {
+	obj, err := c.node.RequestMessage(ctx, c.addr, req)
+	if err != nil {
+		return PutResponse{}, err
+	}
+
+	res, ok := obj.(PutResponse)
+	if !ok {
+		return PutResponse{}, fmt.Errorf("got a response of type %T, but expected PutResponse", obj)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,179 @@
+package example_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/cmd/noisegen/example"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"net"
+	"sync"
+	"testing"
+	"testing/quick"
+)
+
+func TestMessagesRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	f := func(key string, value []byte, version uint64, ttl int32, checksum [4]byte, weight float64,
+		versions []uint64, stored noise.PublicKey, port uint16) bool {
+		entry := example.Entry{Key: key, Value: value, Version: version, TTL: ttl}
+
+		msgs := []noise.Serializable{
+			example.GetRequest{Key: key, Consistency: example.Strong},
+			example.GetResponse{Found: true, Entry: entry},
+			example.PutRequest{
+				Owner:    noise.NewID(stored, net.IPv4(1, 2, 3, 4), port),
+				Entries:  []example.Entry{entry, {}, entry},
+				Checksum: checksum,
+				Weight:   weight,
+			},
+			example.PutResponse{Versions: versions, Stored: stored},
+		}
+
+		for _, msg := range msgs {
+			buf := msg.Marshal()
+
+			decoded, err := unmarshal(msg, buf)
+			if !assert.NoError(t, err) || !assert.Equal(t, buf, decoded.Marshal()) {
+				return false
+			}
+
+			// Every truncated or extended encoding must fail to decode.
+
+			for i := 0; i < len(buf); i++ {
+				if _, err := unmarshal(msg, buf[:i]); !assert.Error(t, err) {
+					return false
+				}
+			}
+
+			if _, err := unmarshal(msg, append(buf, 0)); !assert.Error(t, err) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestMessagesDecodeArbitraryBytes(t *testing.T) {
+	t.Parallel()
+
+	msgs := []noise.Serializable{example.GetRequest{}, example.GetResponse{}, example.PutRequest{}, example.PutResponse{}}
+
+	// Decoding arbitrary bytes must never panic, and whatever decodes must be able to be encoded again.
+
+	f := func(buf []byte) bool {
+		for _, msg := range msgs {
+			if decoded, err := unmarshal(msg, buf); err == nil {
+				decoded.Marshal()
+			}
+		}
+
+		return true
+	}
+
+	assert.NoError(t, quick.Check(f, &quick.Config{MaxCount: 10000}))
+}
+
+func unmarshal(msg noise.Serializable, buf []byte) (noise.Serializable, error) {
+	switch msg.(type) {
+	case example.GetRequest:
+		return example.UnmarshalGetRequest(buf)
+	case example.GetResponse:
+		return example.UnmarshalGetResponse(buf)
+	case example.PutRequest:
+		return example.UnmarshalPutRequest(buf)
+	case example.PutResponse:
+		return example.UnmarshalPutResponse(buf)
+	}
+
+	panic("unknown message")
+}
+
+type store struct {
+	sync.Mutex
+	entries map[string]example.Entry
+}
+
+func (s *store) Get(_ context.Context, req example.GetRequest) (example.GetResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if req.Key == "" {
+		return example.GetResponse{}, errors.New("key must not be empty")
+	}
+
+	entry, found := s.entries[req.Key]
+
+	return example.GetResponse{Found: found, Entry: entry}, nil
+}
+
+func (s *store) Put(_ context.Context, req example.PutRequest) (example.PutResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var res example.PutResponse
+
+	for _, entry := range req.Entries {
+		entry.Version = s.entries[entry.Key].Version + 1
+		s.entries[entry.Key] = entry
+
+		res.Versions = append(res.Versions, entry.Version)
+	}
+
+	res.Stored = req.Owner.ID
+
+	return res, nil
+}
+
+func TestStoreService(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer server.Close()
+
+	example.RegisterStoreServer(server, &store{entries: make(map[string]example.Entry)})
+
+	node, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	example.RegisterStoreMessages(node)
+
+	assert.NoError(t, server.Listen())
+	assert.NoError(t, node.Listen())
+
+	client := example.NewStoreClient(node, server.Addr())
+
+	res, err := client.Get(context.Background(), example.GetRequest{Key: "hello"})
+	assert.NoError(t, err)
+	assert.False(t, res.Found)
+
+	put, err := client.Put(context.Background(), example.PutRequest{
+		Owner:   node.ID(),
+		Entries: []example.Entry{{Key: "hello", Value: []byte("world")}, {Key: "hello", Value: []byte("again")}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2}, put.Versions)
+	assert.Equal(t, node.ID().ID, put.Stored)
+
+	res, err = client.Get(context.Background(), example.GetRequest{Key: "hello", Consistency: example.Strong})
+	assert.NoError(t, err)
+	assert.True(t, res.Found)
+	assert.True(t, bytes.Equal([]byte("again"), res.Entry.Value))
+	assert.EqualValues(t, 2, res.Entry.Version)
+
+	_, err = client.Get(context.Background(), example.GetRequest{})
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.Equal(t, "key must not be empty", remote.Message)
+}
@@ -0,0 +1,849 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const noisePath = "github.com/perlin-network/noise"
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindUint
+	kindInt
+	kindFloat
+	kindString
+	kindBytes
+	kindArray
+	kindSlice
+	kindStruct
+	kindPublicKey
+	kindID
+)
+
+// typ describes the Go type of a field of a message.
+type typ struct {
+	kind kind
+
+	// name is the Go type as it is written in generated code.
+	name string
+
+	// size is the number of bytes of a fixed-size integer or float, or the length of an array.
+	size int
+
+	// elem is the element type of an array or slice.
+	elem *typ
+}
+
+type field struct {
+	name string
+	typ  *typ
+}
+
+type message struct {
+	name   string
+	fields []field
+}
+
+type method struct {
+	name     string
+	request  string
+	response string
+}
+
+type service struct {
+	name    string
+	methods []method
+}
+
+// parsed holds all declarations of a source file relevant to generating code.
+type parsed struct {
+	pkg string
+
+	// noise and context are the names the source file imports noise and context under.
+	noise   string
+	context string
+
+	structs map[string]*ast.StructType
+	basics  map[string]string
+
+	services []service
+}
+
+// Generate generates the code of all messages and services declared in src, which is the Go source file at path.
+func Generate(path string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+
+	file, err := parser.ParseFile(fset, path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := parse(file)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{parsed: p, messages: make(map[string]*message)}
+
+	if err := g.collect(); err != nil {
+		return nil, err
+	}
+
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	return g.generate()
+}
+
+func parse(file *ast.File) (*parsed, error) {
+	p := &parsed{
+		pkg:     file.Name.Name,
+		structs: make(map[string]*ast.StructType),
+		basics:  make(map[string]string),
+	}
+
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+
+		switch path {
+		case noisePath:
+			p.noise = name
+		case "context":
+			p.context = name
+		}
+	}
+
+	var interfaces []*ast.TypeSpec
+
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.TypeSpec)
+
+			switch t := spec.Type.(type) {
+			case *ast.StructType:
+				p.structs[spec.Name.Name] = t
+			case *ast.InterfaceType:
+				interfaces = append(interfaces, spec)
+			case *ast.Ident:
+				p.basics[spec.Name.Name] = t.Name
+			}
+		}
+	}
+
+	for _, spec := range interfaces {
+		svc, err := p.parseService(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		p.services = append(p.services, svc)
+	}
+
+	if len(p.services) == 0 {
+		return nil, fmt.Errorf("%s declares no services", p.pkg)
+	}
+
+	return p, nil
+}
+
+func (p *parsed) parseService(spec *ast.TypeSpec) (service, error) {
+	svc := service{name: spec.Name.Name}
+
+	for _, m := range spec.Type.(*ast.InterfaceType).Methods.List {
+		fn, ok := m.Type.(*ast.FuncType)
+		if !ok || len(m.Names) != 1 {
+			return svc, fmt.Errorf("service %s may only declare methods", svc.name)
+		}
+
+		name := m.Names[0].Name
+
+		params, results := flatten(fn.Params), flatten(fn.Results)
+
+		invalid := fmt.Errorf("method %s.%s must be of the signature %s(ctx context.Context, req Request) "+
+			"(Response, error)", svc.name, name, name,
+		)
+
+		if len(params) != 2 || len(results) != 2 || !p.isSelector(params[0], p.context, "Context") {
+			return svc, invalid
+		}
+
+		if ident, ok := results[1].(*ast.Ident); !ok || ident.Name != "error" {
+			return svc, invalid
+		}
+
+		request, ok := params[1].(*ast.Ident)
+		if !ok || p.structs[request.Name] == nil {
+			return svc, fmt.Errorf("request of method %s.%s must be a struct declared in the same file",
+				svc.name, name,
+			)
+		}
+
+		response, ok := results[0].(*ast.Ident)
+		if !ok || p.structs[response.Name] == nil {
+			return svc, fmt.Errorf("response of method %s.%s must be a struct declared in the same file",
+				svc.name, name,
+			)
+		}
+
+		svc.methods = append(svc.methods, method{name: name, request: request.Name, response: response.Name})
+	}
+
+	return svc, nil
+}
+
+// flatten returns the type of every parameter in fields, repeating the type of parameters declared together.
+func flatten(fields *ast.FieldList) []ast.Expr {
+	if fields == nil {
+		return nil
+	}
+
+	var types []ast.Expr
+
+	for _, f := range fields.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+
+		for i := 0; i < n; i++ {
+			types = append(types, f.Type)
+		}
+	}
+
+	return types
+}
+
+func (p *parsed) isSelector(expr ast.Expr, pkg, name string) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+
+	x, ok := sel.X.(*ast.Ident)
+
+	return ok && pkg != "" && x.Name == pkg && sel.Sel.Name == name
+}
+
+type generator struct {
+	*parsed
+
+	messages map[string]*message
+	order    []string
+
+	buf bytes.Buffer
+
+	// vars counts the variables declared within generated code, such that their names are unique.
+	vars int
+}
+
+// collect resolves every message used by a service, and every message nested within them.
+func (g *generator) collect() error {
+	requests := make(map[string]string)
+	owners := make(map[string]string)
+
+	for _, svc := range g.services {
+		for _, m := range svc.methods {
+			if other, exists := requests[m.request]; exists {
+				return fmt.Errorf("%s is the request of both %s and %s.%s, but may only be the request of one method",
+					m.request, other, svc.name, m.name,
+				)
+			}
+
+			requests[m.request] = svc.name + "." + m.name
+
+			for _, name := range []string{m.request, m.response} {
+				if owner, exists := owners[name]; exists && owner != svc.name {
+					return fmt.Errorf("%s is used by both services %s and %s, but may only be used by one",
+						name, owner, svc.name,
+					)
+				}
+
+				owners[name] = svc.name
+
+				if err := g.collectMessage(name); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *generator) collectMessage(name string) error {
+	if _, exists := g.messages[name]; exists {
+		return nil
+	}
+
+	st, ok := g.structs[name]
+	if !ok {
+		return fmt.Errorf("%s is not a struct declared in the same file", name)
+	}
+
+	msg := &message{name: name}
+
+	g.messages[name] = msg
+	g.order = append(g.order, name)
+
+	for _, f := range st.Fields.List {
+		if f.Tag != nil {
+			tag, err := strconv.Unquote(f.Tag.Value)
+			if err == nil && reflect.StructTag(tag).Get("noise") == "-" {
+				continue
+			}
+		}
+
+		t, err := g.resolve(f.Type)
+		if err != nil {
+			return fmt.Errorf("field of %s: %w", name, err)
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			ident, ok := f.Type.(*ast.Ident)
+			if !ok {
+				return fmt.Errorf("embedded field of %s must be a struct declared in the same file", name)
+			}
+
+			names = []*ast.Ident{ident}
+		}
+
+		for _, n := range names {
+			if n.Name == "_" {
+				continue
+			}
+
+			msg.fields = append(msg.fields, field{name: n.Name, typ: t})
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the elements of every slice are encoded in at least one byte, which bounds the number of
+// elements a slice may be decoded with to the number of bytes left to decode.
+func (g *generator) validate() error {
+	var check func(t *typ) bool
+
+	check = func(t *typ) bool {
+		switch t.kind {
+		case kindSlice:
+			return g.minSize(t.elem) > 0 && check(t.elem)
+		case kindArray:
+			return check(t.elem)
+		}
+
+		return true
+	}
+
+	for _, name := range g.order {
+		for _, f := range g.messages[name].fields {
+			if !check(f.typ) {
+				return fmt.Errorf("field %s of %s is a slice whose elements are encoded in zero bytes", f.name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// minSize returns the least number of bytes a value of type t is encoded in.
+func (g *generator) minSize(t *typ) int {
+	switch t.kind {
+	case kindBool:
+		return 1
+	case kindUint, kindInt, kindFloat:
+		return t.size
+	case kindPublicKey:
+		return 32
+	case kindArray:
+		return t.size * g.minSize(t.elem)
+	case kindStruct:
+		size := 0
+
+		for _, f := range g.messages[t.name].fields {
+			size += g.minSize(f.typ)
+		}
+
+		return size
+	}
+
+	// Strings, byte slices, IDs, and slices are prefixed with their length.
+
+	return 1
+}
+
+var basics = map[string]typ{
+	"bool":    {kind: kindBool},
+	"string":  {kind: kindString},
+	"byte":    {kind: kindUint, size: 1},
+	"uint8":   {kind: kindUint, size: 1},
+	"uint16":  {kind: kindUint, size: 2},
+	"uint32":  {kind: kindUint, size: 4},
+	"uint64":  {kind: kindUint, size: 8},
+	"uint":    {kind: kindUint, size: 8},
+	"int8":    {kind: kindInt, size: 1},
+	"int16":   {kind: kindInt, size: 2},
+	"int32":   {kind: kindInt, size: 4},
+	"rune":    {kind: kindInt, size: 4},
+	"int64":   {kind: kindInt, size: 8},
+	"int":     {kind: kindInt, size: 8},
+	"float32": {kind: kindFloat, size: 4},
+	"float64": {kind: kindFloat, size: 8},
+}
+
+func (g *generator) resolve(expr ast.Expr) (*typ, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if b, ok := basics[e.Name]; ok {
+			b.name = e.Name
+			return &b, nil
+		}
+
+		if underlying, ok := g.basics[e.Name]; ok {
+			b, ok := basics[underlying]
+			if !ok {
+				return nil, fmt.Errorf("type %s has unsupported underlying type %s", e.Name, underlying)
+			}
+
+			b.name = e.Name
+
+			return &b, nil
+		}
+
+		if _, ok := g.structs[e.Name]; ok {
+			if err := g.collectMessage(e.Name); err != nil {
+				return nil, err
+			}
+
+			return &typ{kind: kindStruct, name: e.Name}, nil
+		}
+
+		return nil, fmt.Errorf("unsupported type %s", e.Name)
+	case *ast.SelectorExpr:
+		switch {
+		case g.isSelector(e, g.noise, "PublicKey"):
+			return &typ{kind: kindPublicKey, name: "noise.PublicKey"}, nil
+		case g.isSelector(e, g.noise, "ID"):
+			return &typ{kind: kindID, name: "noise.ID"}, nil
+		}
+
+		return nil, fmt.Errorf("unsupported type %s.%s", e.X, e.Sel.Name)
+	case *ast.ArrayType:
+		elem, err := g.resolve(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Len == nil {
+			if elem.kind == kindUint && elem.size == 1 {
+				return &typ{kind: kindBytes, name: "[]" + elem.name}, nil
+			}
+
+			return &typ{kind: kindSlice, name: "[]" + elem.name, elem: elem}, nil
+		}
+
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, fmt.Errorf("array lengths must be integer literals")
+		}
+
+		size, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		return &typ{kind: kindArray, name: fmt.Sprintf("[%d]%s", size, elem.name), size: size, elem: elem}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %T", expr)
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) v(name string) string {
+	g.vars++
+	return fmt.Sprintf("%s%d", name, g.vars)
+}
+
+func (g *generator) generate() ([]byte, error) {
+	g.p("// Code generated by noisegen. DO NOT EDIT.")
+	g.p("")
+	g.p("package %s", g.pkg)
+	g.p("")
+	g.p("import (")
+
+	for _, imp := range []string{"context", "encoding/binary", "errors", "fmt", "io", "math", noisePath} {
+		g.p("%q", imp)
+	}
+
+	g.p(")")
+	g.p("")
+	g.p("var (")
+	g.p("_ = binary.BigEndian")
+	g.p("_ = io.ErrUnexpectedEOF")
+	g.p("_ = math.Float64bits")
+	g.p(")")
+
+	for _, name := range g.order {
+		g.generateMessage(g.messages[name])
+	}
+
+	for _, svc := range g.services {
+		g.generateService(svc)
+	}
+
+	code, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, g.buf.String())
+	}
+
+	return code, nil
+}
+
+func (g *generator) generateMessage(msg *message) {
+	g.p("")
+	g.p("// Marshal implements noise.Serializable and encodes %s into its binary representation.", msg.name)
+	g.p("func (m %s) Marshal() []byte {", msg.name)
+	g.p("return m.marshalNoise(nil)")
+	g.p("}")
+	g.p("")
+	g.p("func (m %s) marshalNoise(buf []byte) []byte {", msg.name)
+	g.p("var scratch [binary.MaxVarintLen64]byte")
+	g.p("_ = scratch")
+
+	for _, f := range msg.fields {
+		g.marshal("m."+f.name, f.typ)
+	}
+
+	g.p("return buf")
+	g.p("}")
+	g.p("")
+	g.p("// Unmarshal%s decodes buf into %s. It returns an error should buf be malformed, or should buf have", msg.name,
+		msg.name,
+	)
+	g.p("// trailing bytes.")
+	g.p("func Unmarshal%s(buf []byte) (%s, error) {", msg.name, msg.name)
+	g.p("m, rest, err := unmarshalNoise%s(buf)", msg.name)
+	g.p("if err != nil {")
+	g.p("return %s{}, err", msg.name)
+	g.p("}")
+	g.p("")
+	g.p("if len(rest) != 0 {")
+	g.p("return %s{}, fmt.Errorf(\"got %%d trailing byte(s) decoding %s\", len(rest))", msg.name, msg.name)
+	g.p("}")
+	g.p("")
+	g.p("return m, nil")
+	g.p("}")
+	g.p("")
+	g.p("func unmarshalNoise%s(buf []byte) (%s, []byte, error) {", msg.name, msg.name)
+	g.p("var m %s", msg.name)
+
+	for _, f := range msg.fields {
+		g.unmarshal("m."+f.name, f.typ)
+	}
+
+	g.p("return m, buf, nil")
+	g.p("}")
+}
+
+// marshal generates code that appends the encoding of expr of type t to buf.
+func (g *generator) marshal(expr string, t *typ) {
+	switch t.kind {
+	case kindBool:
+		g.p("if %s {", expr)
+		g.p("buf = append(buf, 1)")
+		g.p("} else {")
+		g.p("buf = append(buf, 0)")
+		g.p("}")
+	case kindUint, kindInt:
+		if t.size == 1 {
+			g.p("buf = append(buf, byte(%s))", expr)
+			return
+		}
+
+		g.p("binary.BigEndian.PutUint%d(scratch[:%d], uint%d(%s))", t.size*8, t.size, t.size*8, expr)
+		g.p("buf = append(buf, scratch[:%d]...)", t.size)
+	case kindFloat:
+		g.p("binary.BigEndian.PutUint%d(scratch[:%d], math.Float%dbits(float%d(%s)))",
+			t.size*8, t.size, t.size*8, t.size*8, expr,
+		)
+		g.p("buf = append(buf, scratch[:%d]...)", t.size)
+	case kindString, kindBytes:
+		g.marshalLength(expr)
+		g.p("buf = append(buf, %s...)", expr)
+	case kindPublicKey:
+		g.p("buf = append(buf, %s[:]...)", expr)
+	case kindID:
+		record := g.v("record")
+
+		g.p("%s := %s.Marshal()", record, expr)
+		g.marshalLength(record)
+		g.p("buf = append(buf, %s...)", record)
+	case kindStruct:
+		g.p("buf = %s.marshalNoise(buf)", expr)
+	case kindArray:
+		if t.elem.kind == kindUint && t.elem.size == 1 {
+			g.p("buf = append(buf, %s[:]...)", expr)
+			return
+		}
+
+		i := g.v("i")
+
+		g.p("for %s := range %s {", i, expr)
+		g.marshal(fmt.Sprintf("%s[%s]", expr, i), t.elem)
+		g.p("}")
+	case kindSlice:
+		g.marshalLength(expr)
+
+		i := g.v("i")
+
+		g.p("for %s := range %s {", i, expr)
+		g.marshal(fmt.Sprintf("%s[%s]", expr, i), t.elem)
+		g.p("}")
+	}
+}
+
+func (g *generator) marshalLength(expr string) {
+	n := g.v("n")
+
+	g.p("%s := binary.PutUvarint(scratch[:], uint64(len(%s)))", n, expr)
+	g.p("buf = append(buf, scratch[:%s]...)", n)
+}
+
+// unmarshal generates code that decodes the head of buf into target of type t.
+func (g *generator) unmarshal(target string, t *typ) {
+	eof := "return m, nil, io.ErrUnexpectedEOF"
+
+	switch t.kind {
+	case kindBool:
+		g.p("if len(buf) < 1 {")
+		g.p(eof)
+		g.p("}")
+		g.p("%s = %s", target, convert(t, "bool", "buf[0] != 0"))
+		g.p("buf = buf[1:]")
+	case kindUint, kindInt, kindFloat:
+		g.p("if len(buf) < %d {", t.size)
+		g.p(eof)
+		g.p("}")
+
+		value, natural := "buf[0]", "byte"
+		if t.size > 1 {
+			value, natural = fmt.Sprintf("binary.BigEndian.Uint%d(buf[:%d])", t.size*8, t.size), fmt.Sprintf("uint%d", t.size*8)
+		}
+
+		if t.kind == kindFloat {
+			value, natural = fmt.Sprintf("math.Float%dfrombits(%s)", t.size*8, value), fmt.Sprintf("float%d", t.size*8)
+		}
+
+		g.p("%s = %s", target, convert(t, natural, value))
+		g.p("buf = buf[%d:]", t.size)
+	case kindString, kindBytes, kindID:
+		size, n := g.v("size"), g.v("n")
+
+		g.p("%s, %s := binary.Uvarint(buf)", size, n)
+		g.p("if %s <= 0 || uint64(len(buf)-%s) < %s {", n, n, size)
+		g.p(eof)
+		g.p("}")
+
+		value := fmt.Sprintf("buf[%s : %s+int(%s)]", n, n, size)
+
+		switch t.kind {
+		case kindString:
+			g.p("%s = %s(%s)", target, t.name, value)
+		case kindBytes:
+			g.p("%s = %s", target, convert(t, "[]byte", fmt.Sprintf("append([]byte(nil), %s...)", value)))
+		case kindID:
+			id, err := g.v("id"), g.v("err")
+
+			g.p("%s, %s := noise.UnmarshalID(%s)", id, err, value)
+			g.p("if %s != nil {", err)
+			g.p("return m, nil, %s", err)
+			g.p("}")
+			g.p("%s = %s", target, id)
+		}
+
+		g.p("buf = buf[%s+int(%s):]", n, size)
+	case kindPublicKey:
+		g.p("if len(buf) < noise.SizePublicKey {")
+		g.p(eof)
+		g.p("}")
+		g.p("copy(%s[:], buf[:noise.SizePublicKey])", target)
+		g.p("buf = buf[noise.SizePublicKey:]")
+	case kindStruct:
+		value, rest, err := g.v("value"), g.v("rest"), g.v("err")
+
+		g.p("%s, %s, %s := unmarshalNoise%s(buf)", value, rest, err, t.name)
+		g.p("if %s != nil {", err)
+		g.p("return m, nil, %s", err)
+		g.p("}")
+		g.p("%s = %s", target, value)
+		g.p("buf = %s", rest)
+	case kindArray:
+		if t.elem.kind == kindUint && t.elem.size == 1 {
+			g.p("if len(buf) < %d {", t.size)
+			g.p(eof)
+			g.p("}")
+			g.p("copy(%s[:], buf[:%d])", target, t.size)
+			g.p("buf = buf[%d:]", t.size)
+
+			return
+		}
+
+		i := g.v("i")
+
+		g.p("for %s := range %s {", i, target)
+		g.unmarshal(fmt.Sprintf("%s[%s]", target, i), t.elem)
+		g.p("}")
+	case kindSlice:
+		count, n := g.v("count"), g.v("n")
+
+		// Every element is encoded in at least one byte. See validate.
+
+		g.p("%s, %s := binary.Uvarint(buf)", count, n)
+		g.p("if %s <= 0 || uint64(len(buf)-%s) < %s {", n, n, count)
+		g.p(eof)
+		g.p("}")
+		g.p("buf = buf[%s:]", n)
+		g.p("%s = make(%s, %s)", target, t.name, count)
+
+		i := g.v("i")
+
+		g.p("for %s := range %s {", i, target)
+		g.unmarshal(fmt.Sprintf("%s[%s]", target, i), t.elem)
+		g.p("}")
+	}
+}
+
+// convert returns value, whose type is natural, converted to t should t not be of type natural.
+func convert(t *typ, natural, value string) string {
+	if t.name == natural {
+		return value
+	}
+
+	return fmt.Sprintf("%s(%s)", t.name, value)
+}
+
+func (g *generator) generateService(svc service) {
+	var names []string
+
+	seen := make(map[string]bool)
+
+	for _, m := range svc.methods {
+		for _, name := range []string{m.request, m.response} {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	client := svc.name + "Client"
+
+	g.p("")
+	g.p("// Register%sMessages registers all messages of the %s service to node. It must be called on every node that",
+		svc.name, svc.name,
+	)
+	g.p("// makes requests to the %s service via %s.", svc.name, client)
+	g.p("func Register%sMessages(node *noise.Node) {", svc.name)
+
+	for _, name := range names {
+		g.p("node.RegisterMessage(%s{}, Unmarshal%s)", name, name)
+	}
+
+	g.p("}")
+	g.p("")
+	g.p("// Register%sServer registers all messages of the %s service to node, and routes requests to the %s service",
+		svc.name, svc.name, svc.name,
+	)
+	g.p("// to srv. Errors returned by srv are responded with as a *noise.RemoteError.")
+	g.p("func Register%sServer(node *noise.Node, srv %s) {", svc.name, svc.name)
+	g.p("Register%sMessages(node)", svc.name)
+
+	for _, m := range svc.methods {
+		g.p("")
+		g.p("node.HandleMessage(%s{}, func(ctx noise.HandlerContext, req %s) error {", m.request, m.request)
+		g.p("if !ctx.IsRequest() {")
+		g.p("return errors.New(\"got a %s that was not sent as a request\")", m.request)
+		g.p("}")
+		g.p("")
+		g.p("res, err := srv.%s(ctx.Context(), req)", m.name)
+		g.p("if err != nil {")
+		g.p("var remote *noise.RemoteError")
+		g.p("if !errors.As(err, &remote) {")
+		g.p("remote = &noise.RemoteError{Message: err.Error()}")
+		g.p("}")
+		g.p("")
+		g.p("return remote")
+		g.p("}")
+		g.p("")
+		g.p("return ctx.SendMessage(res)")
+		g.p("})")
+	}
+
+	g.p("}")
+	g.p("")
+	g.p("// %s is a typed client stub of the %s service, which makes requests to the peer at the address it was", client,
+		svc.name,
+	)
+	g.p("// created with.")
+	g.p("type %s struct {", client)
+	g.p("node *noise.Node")
+	g.p("addr string")
+	g.p("}")
+	g.p("")
+	g.p("var _ %s = (*%s)(nil)", svc.name, client)
+	g.p("")
+	g.p("// New%s returns a client stub of the %s service, which makes requests to the peer at addr through", client,
+		svc.name,
+	)
+	g.p("// node. Messages of the %s service must be registered to node via Register%sMessages.", svc.name, svc.name)
+	g.p("func New%s(node *noise.Node, addr string) *%s {", client, client)
+	g.p("return &%s{node: node, addr: addr}", client)
+	g.p("}")
+
+	for _, m := range svc.methods {
+		g.p("")
+		g.p("// %s sends req as a request to the %s service, and returns its response.", m.name, svc.name)
+		g.p("func (c *%s) %s(ctx context.Context, req %s) (%s, error) {", client, m.name, m.request, m.response)
+		g.p("obj, err := c.node.RequestMessage(ctx, c.addr, req)")
+		g.p("if err != nil {")
+		g.p("return %s{}, err", m.response)
+		g.p("}")
+		g.p("")
+		g.p("res, ok := obj.(%s)", m.response)
+		g.p("if !ok {")
+		g.p("return %s{}, fmt.Errorf(\"got a response of type %%T, but expected %s\", obj)", m.response, m.response)
+		g.p("}")
+		g.p("")
+		g.p("return res, nil")
+		g.p("}")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	t.Parallel()
+
+	src, err := ioutil.ReadFile("example/service.go")
+	assert.NoError(t, err)
+
+	expected, err := ioutil.ReadFile("example/service_noise.go")
+	assert.NoError(t, err)
+
+	code, err := Generate("example/service.go", src)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(code), "example/service_noise.go is stale; run go generate")
+}
+
+func TestGenerateRejectsInvalidServices(t *testing.T) {
+	t.Parallel()
+
+	header := "package test\n\nimport \"context\"\n\ntype Req struct{}\n\ntype Res struct{}\n\n"
+
+	cases := map[string]string{
+		"no services":      "",
+		"missing context":  "type S interface { M(req Req) (Res, error) }",
+		"missing error":    "type S interface { M(ctx context.Context, req Req) Res }",
+		"non-struct":       "type S interface { M(ctx context.Context, req string) (Res, error) }",
+		"undeclared":       "type S interface { M(ctx context.Context, req Other) (Res, error) }",
+		"embedded":         "type S interface { context.Context }",
+		"shared request":   "type S interface { A(ctx context.Context, req Req) (Res, error)\nB(ctx context.Context, req Req) (Res, error) }",
+		"shared message":   "type S interface { A(ctx context.Context, req Req) (Res, error) }\ntype T interface { B(ctx context.Context, req Res) (Req, error) }",
+		"unsupported":      "type Bad struct { C chan int }\ntype S interface { M(ctx context.Context, req Bad) (Res, error) }",
+		"zero-sized slice": "type Bad struct { R []Res }\ntype S interface { M(ctx context.Context, req Bad) (Res, error) }",
+	}
+
+	for name, src := range cases {
+		_, err := Generate("test.go", []byte(header+src))
+		assert.Error(t, err, name)
+	}
+}
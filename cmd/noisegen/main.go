@@ -0,0 +1,59 @@
+// Command noisegen generates typed RPC services over noise from a Go source file.
+//
+// The source file declares every service as a Go interface whose methods are of the signature
+//
+//	Method(ctx context.Context, req Request) (Response, error)
+//
+// alongside all request and response message types as Go structs. For every message, noisegen generates a binary
+// Marshal method and an Unmarshal function. For every service S, it generates RegisterSMessages which registers all
+// messages of the service to a node, RegisterSServer which routes requests to an implementation of S, and SClient
+// which is a typed client stub wrapping (*noise.Node).RequestMessage.
+//
+// Fields of messages may be of type bool, string, []byte, any sized integer or float, int, uint, byte arrays, slices,
+// noise.PublicKey, noise.ID, or another message struct declared in the same source file. Usage:
+//
+//	//go:generate noisegen -o service_noise.go service.go
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var outFlag = pflag.StringP("out", "o", "", "output file (default: <input>_noise.go)")
+
+func main() {
+	pflag.Parse()
+
+	if pflag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: noisegen [-o output.go] input.go")
+		os.Exit(2)
+	}
+
+	in := pflag.Arg(0)
+
+	out := *outFlag
+	if out == "" {
+		out = strings.TrimSuffix(in, ".go") + "_noise.go"
+	}
+
+	src, err := ioutil.ReadFile(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "noisegen: %s\n", err)
+		os.Exit(1)
+	}
+
+	code, err := Generate(in, src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "noisegen: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile(out, code, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "noisegen: %s\n", err)
+		os.Exit(1)
+	}
+}
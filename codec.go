@@ -3,6 +3,7 @@ package noise
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"math"
 	"reflect"
@@ -15,65 +16,312 @@ type Serializable interface {
 	Marshal() []byte
 }
 
+// Codec encodes and decodes the Go types registered under it via (*Node).RegisterMessageCodec into their byte
+// representation, such that messages may be serialized by frameworks such as protocol buffers or MessagePack without
+// implementing Serializable by hand. Values of such Go types are wrapped in a CodecMessage. A Codec must be safe for
+// concurrent use.
+type Codec interface {
+	// Marshal encodes msg, which is of a Go type registered under the codec, into its byte representation.
+	Marshal(msg interface{}) ([]byte, error)
+
+	// Unmarshal decodes data into msg, which is a pointer to a zero value of a Go type registered under the codec.
+	Unmarshal(data []byte, msg interface{}) error
+}
+
+// CodecMessage adapts a value of a Go type registered via (*Node).RegisterMessageCodec to Serializable, such that it
+// may be encoded, sent, and handled the same way as a value of a Go type registered via (*Node).RegisterMessage:
+//
+//	node.RegisterMessageCodec(&pb.Query{}, protocodec.Codec)
+//	node.SendMessage(ctx, addr, noise.CodecMessage{Value: &pb.Query{...}})
+//
+// Messages of Go types registered via (*Node).RegisterMessageCodec are decoded into a CodecMessage.
+type CodecMessage struct {
+	// Value is a value of a Go type registered via (*Node).RegisterMessageCodec.
+	Value interface{}
+
+	// codec is the Codec Value was decoded by, which is nil should the CodecMessage have been created by hand.
+	codec Codec
+}
+
+// Marshal encodes Value via the Codec it was decoded by, and returns nil should Value not have been decoded by a
+// node, or should it fail to be encoded. Nodes encode Value via the Codec its Go type was registered under instead.
+func (m CodecMessage) Marshal() []byte {
+	if m.codec == nil {
+		return nil
+	}
+
+	data, err := m.codec.Marshal(m.Value)
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
+
 type codec struct {
 	sync.RWMutex
 
-	counter uint16
-	ser     map[reflect.Type]uint16
-	de      map[uint16]reflect.Value
+	// legacy is true should Go types registered without an explicit opcode be registered under opcodes counted up
+	// from zero in the order they are registered, as peers that predate opcode negotiation register them.
+	legacy bool
+
+	ser   map[reflect.Type]uint16
+	enc   map[uint16]func(msg interface{}) ([]byte, error)
+	de    map[uint16]func(data []byte) (Serializable, error)
+	names map[uint16]string
+	order []uint16
 }
 
 func newCodec() *codec {
 	return &codec{
-		ser: make(map[reflect.Type]uint16, math.MaxUint16),
-		de:  make(map[uint16]reflect.Value, math.MaxUint16),
+		ser:   make(map[reflect.Type]uint16, math.MaxUint16),
+		enc:   make(map[uint16]func(msg interface{}) ([]byte, error), math.MaxUint16),
+		de:    make(map[uint16]func(data []byte) (Serializable, error), math.MaxUint16),
+		names: make(map[uint16]string, math.MaxUint16),
 	}
 }
 
+// typeName returns the stable name of the Go type t, which is comprised of its package path and its name.
+func typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr && t.Name() == "" {
+		return "*" + typeName(t.Elem())
+	}
+
+	if t.Name() == "" {
+		return t.String()
+	}
+
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+
+	return t.PkgPath() + "." + t.Name()
+}
+
+// deriveOpcode derives an opcode from the stable name of a Go type by folding the 32-bit FNV-1a hash of the name.
+func deriveOpcode(name string) uint16 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+
+	sum := h.Sum32()
+
+	return uint16(sum>>16) ^ uint16(sum)
+}
+
+// register registers ser under the next opcode of its Go type. See nextOpcode.
 func (c *codec) register(ser Serializable, de interface{}) uint16 {
-	c.Lock()
-	defer c.Unlock()
+	return c.registerWithOpcode(ser, de, c.nextOpcode(reflect.TypeOf(ser)))
+}
+
+// nextOpcode returns the opcode the Go type t is to be registered under should it not be assigned an explicit
+// opcode. The opcode is derived from the stable name of t, or is the number of Go types registered thus far should
+// legacy opcodes be enabled.
+func (c *codec) nextOpcode(t reflect.Type) uint16 {
+	if !c.legacy {
+		return deriveOpcode(typeName(t))
+	}
+
+	c.RLock()
+	defer c.RUnlock()
 
+	return uint16(len(c.order))
+}
+
+func (c *codec) registerWithOpcode(ser Serializable, de interface{}, opcode uint16) uint16 {
 	t := reflect.TypeOf(ser)
 	d := reflect.ValueOf(de)
 
+	expected := reflect.FuncOf([]reflect.Type{reflect.TypeOf(([]byte)(nil))}, []reflect.Type{t, reflect.TypeOf((*error)(nil)).Elem()}, false)
+
+	if !d.IsValid() || d.Type() != expected {
+		panic(fmt.Errorf("provided decoder for message type %+v is %s, but expected %s", t, d, expected))
+	}
+
+	encode := func(msg interface{}) ([]byte, error) {
+		return msg.(Serializable).Marshal(), nil
+	}
+
+	decode := func(data []byte) (Serializable, error) {
+		results := d.Call([]reflect.Value{reflect.ValueOf(data)})
+
+		if !results[1].IsNil() {
+			return nil, results[1].Interface().(error)
+		}
+
+		return results[0].Interface().(Serializable), nil
+	}
+
+	return c.add(t, opcode, encode, decode)
+}
+
+// registerCodec registers the Go type of msg under the next opcode of the type, whose values are encoded and decoded
+// by cd. See nextOpcode.
+func (c *codec) registerCodec(msg interface{}, cd Codec) uint16 {
+	return c.registerCodecWithOpcode(msg, cd, c.nextOpcode(codecMessageType(msg, cd)))
+}
+
+// registerCodecWithOpcode registers the Go type of msg under opcode, whose values are encoded and decoded by cd, and
+// which are decoded into a CodecMessage.
+func (c *codec) registerCodecWithOpcode(msg interface{}, cd Codec, opcode uint16) uint16 {
+	t := codecMessageType(msg, cd)
+
+	encode := func(msg interface{}) ([]byte, error) {
+		return cd.Marshal(msg)
+	}
+
+	decode := func(data []byte) (Serializable, error) {
+		if t.Kind() == reflect.Ptr {
+			v := reflect.New(t.Elem())
+
+			if err := cd.Unmarshal(data, v.Interface()); err != nil {
+				return nil, err
+			}
+
+			return CodecMessage{Value: v.Interface(), codec: cd}, nil
+		}
+
+		v := reflect.New(t)
+
+		if err := cd.Unmarshal(data, v.Interface()); err != nil {
+			return nil, err
+		}
+
+		return CodecMessage{Value: v.Elem().Interface(), codec: cd}, nil
+	}
+
+	return c.add(t, opcode, encode, decode)
+}
+
+// codecMessageType returns the Go type of msg to be registered under cd. It panics should msg be nil.
+func codecMessageType(msg interface{}, cd Codec) reflect.Type {
+	t := reflect.TypeOf(msg)
+	if t == nil {
+		panic(fmt.Errorf("attempted to register a nil message under codec %T", cd))
+	}
+
+	return t
+}
+
+func (c *codec) add(
+	t reflect.Type, opcode uint16, encode func(interface{}) ([]byte, error), decode func([]byte) (Serializable, error),
+) uint16 {
+	c.Lock()
+	defer c.Unlock()
+
 	if opcode, registered := c.ser[t]; registered {
 		panic(fmt.Errorf("attempted to register type %+v which is already registered under opcode %d", t, opcode))
 	}
 
-	expected := reflect.FuncOf([]reflect.Type{reflect.TypeOf(([]byte)(nil))}, []reflect.Type{t, reflect.TypeOf((*error)(nil)).Elem()}, false)
+	if name, registered := c.names[opcode]; registered {
+		panic(fmt.Errorf("attempted to register type %+v under opcode %d which is already registered to type %s; "+
+			"register either type under an explicit opcode instead", t, opcode, name,
+		))
+	}
 
-	if d.Type() != expected {
-		panic(fmt.Errorf("provided decoder for message type %+v is %s, but expected %s", t, d, expected))
+	c.ser[t] = opcode
+	c.enc[opcode] = encode
+	c.de[opcode] = decode
+	c.names[opcode] = typeName(t)
+	c.order = append(c.order, opcode)
+
+	return opcode
+}
+
+// table returns the names of all registered Go types keyed by the opcode they are registered under.
+func (c *codec) table() map[uint16]string {
+	c.RLock()
+	defer c.RUnlock()
+
+	table := make(map[uint16]string, len(c.names))
+	for opcode, name := range c.names {
+		table[opcode] = name
 	}
 
-	c.ser[t] = c.counter
-	c.de[c.counter] = d
+	return table
+}
+
+// legacyTable returns the names of all registered Go types keyed by the opcode peers that predate opcode negotiation
+// would have registered them under, which counts up from zero in the order the Go types were registered.
+func (c *codec) legacyTable() map[uint16]string {
+	c.RLock()
+	defer c.RUnlock()
 
-	c.counter++
+	table := make(map[uint16]string, len(c.order))
+	for i, opcode := range c.order {
+		table[uint16(i)] = c.names[opcode]
+	}
 
-	return c.counter - 1
+	return table
+}
+
+// messageType returns the Go type msg was registered under, which is the Go type of the value msg wraps should msg be
+// a CodecMessage.
+func messageType(msg Serializable) reflect.Type {
+	if m, ok := msg.(CodecMessage); ok {
+		return reflect.TypeOf(m.Value)
+	}
+
+	return reflect.TypeOf(msg)
 }
 
 func (c *codec) encode(msg Serializable) ([]byte, error) {
 	c.RLock()
 	defer c.RUnlock()
 
-	t := reflect.TypeOf(msg)
+	var value interface{} = msg
+	if m, ok := msg.(CodecMessage); ok {
+		value = m.Value
+	}
 
-	if t.Kind() == reflect.Ptr {
-		t = t.Elem()
+	t := reflect.TypeOf(value)
+	if t == nil {
+		return nil, fmt.Errorf("attempted to encode a nil message")
 	}
 
 	opcode, registered := c.ser[t]
+	if !registered && t.Kind() == reflect.Ptr {
+		opcode, registered = c.ser[t.Elem()]
+	}
+
 	if !registered {
 		return nil, fmt.Errorf("opcode not registered for message type %+v", t)
 	}
 
-	buf := make([]byte, 2)
+	data, err := c.enc[opcode](value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message type %+v: %w", t, err)
+	}
+
+	buf := make([]byte, 2, 2+len(data))
 	binary.BigEndian.PutUint16(buf[:2], opcode)
 
-	return append(buf, msg.Marshal()...), nil
+	return append(buf, data...), nil
+}
+
+// opcodeOf returns the opcode the Go type t is registered under.
+func (c *codec) opcodeOf(t reflect.Type) (uint16, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	opcode, registered := c.ser[t]
+
+	return opcode, registered
+}
+
+// opcode returns the opcode data is prefixed with, and whether or not a type has been registered under it.
+func (c *codec) opcode(data []byte) (uint16, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+
+	opcode := binary.BigEndian.Uint16(data[:2])
+
+	c.RLock()
+	defer c.RUnlock()
+
+	_, registered := c.de[opcode]
+
+	return opcode, registered
 }
 
 func (c *codec) decode(data []byte) (Serializable, error) {
@@ -87,16 +335,10 @@ func (c *codec) decode(data []byte) (Serializable, error) {
 	c.RLock()
 	defer c.RUnlock()
 
-	decoder, registered := c.de[opcode]
+	decode, registered := c.de[opcode]
 	if !registered {
 		return nil, fmt.Errorf("opcode %d is not registered", opcode)
 	}
 
-	results := decoder.Call([]reflect.Value{reflect.ValueOf(data)})
-
-	if !results[1].IsNil() {
-		return nil, results[1].Interface().(error)
-	}
-
-	return results[0].Interface().(Serializable), nil
+	return decode(data)
 }
@@ -2,6 +2,8 @@ package noise
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -75,3 +77,118 @@ func TestPanicIfDuplicateMessagesRegistered(t *testing.T) {
 		codec.register(test{}, unmarshalTest)
 	})
 }
+
+func TestCodecOpcodesAreStable(t *testing.T) {
+	t.Parallel()
+
+	a, b := newCodec(), newCodec()
+
+	opcode := a.register(test{}, unmarshalTest)
+	a.register(test2{}, unmarshalTest2)
+
+	b.register(test2{}, unmarshalTest2)
+	assert.Equal(t, opcode, b.register(test{}, unmarshalTest))
+
+	assert.Equal(t, a.table(), b.table())
+	assert.Equal(t, "github.com/perlin-network/noise.test", a.table()[opcode])
+
+	c := newCodec()
+	c.registerWithOpcode(test2{}, unmarshalTest2, opcode)
+
+	assert.Panics(t, func() { c.register(test{}, unmarshalTest) })
+}
+
+func TestCodecLegacyOpcodes(t *testing.T) {
+	t.Parallel()
+
+	codec := newCodec()
+	codec.legacy = true
+
+	// Go types are registered under opcodes counted up from zero in the order they are registered, which agree with
+	// the opcodes peers that predate opcode negotiation register them under.
+
+	assert.EqualValues(t, 0, codec.register(test{}, unmarshalTest))
+	assert.EqualValues(t, 1, codec.registerCodec(text(""), textCodec{}))
+	assert.EqualValues(t, 2, codec.register(test2{}, unmarshalTest2))
+
+	assert.Equal(t, codec.legacyTable(), codec.table())
+}
+
+// textCodec encodes pointers to strings, and strings, as their raw bytes.
+type textCodec struct{}
+
+func (textCodec) Marshal(msg interface{}) ([]byte, error) {
+	switch msg := msg.(type) {
+	case text:
+		return []byte(msg), nil
+	case *text:
+		return []byte(*msg), nil
+	}
+
+	return nil, fmt.Errorf("unexpected message %T", msg)
+}
+
+func (textCodec) Unmarshal(data []byte, msg interface{}) error {
+	if string(data) == "invalid" {
+		return errors.New("got invalid text")
+	}
+
+	*msg.(*text) = text(data)
+
+	return nil
+}
+
+type text string
+
+func TestCodecRegisterCodec(t *testing.T) {
+	t.Parallel()
+
+	codec := newCodec()
+
+	opcode := codec.registerCodecWithOpcode(text(""), textCodec{}, 1)
+	assert.Panics(t, func() { codec.registerCodecWithOpcode(text(""), textCodec{}, 2) })
+	assert.Panics(t, func() { codec.registerCodecWithOpcode(nil, textCodec{}, 2) })
+
+	// Nil messages are refused before an opcode is derived from their type.
+
+	func() {
+		defer func() {
+			err, _ := recover().(error)
+			assert.EqualError(t, err, "attempted to register a nil message under codec noise.textCodec")
+		}()
+
+		codec.registerCodec(nil, textCodec{})
+	}()
+
+	// Values are wrapped in a CodecMessage, which may wrap either the registered type or a pointer to it.
+
+	for _, value := range []interface{}{text("hello"), func() *text { msg := text("hello"); return &msg }()} {
+		data, err := codec.encode(CodecMessage{Value: value})
+		assert.NoError(t, err)
+		assert.Equal(t, append([]byte{0, 1}, "hello"...), data)
+
+		decoded, err := codec.decode(data)
+		assert.NoError(t, err)
+
+		if assert.IsType(t, CodecMessage{}, decoded) {
+			assert.Equal(t, text("hello"), decoded.(CodecMessage).Value)
+			assert.Equal(t, []byte("hello"), decoded.Marshal())
+		}
+	}
+
+	assert.Nil(t, CodecMessage{Value: text("hello")}.Marshal())
+
+	_, err := codec.decode(append([]byte{0, byte(opcode)}, "invalid"...))
+	assert.Error(t, err)
+
+	// Pointer types are decoded into newly allocated values.
+
+	other := newCodec()
+	other.registerCodecWithOpcode((*text)(nil), textCodec{}, 1)
+
+	decoded, err := other.decode(append([]byte{0, 1}, "hello"...))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(*decoded.(CodecMessage).Value.(*text)))
+
+	assert.Equal(t, "*github.com/perlin-network/noise.text", other.table()[1])
+}
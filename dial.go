@@ -0,0 +1,287 @@
+package noise
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// DialError is returned by (*Node).Ping, (*Node).Send, and (*Node).Request, and reported to
+// (Protocol).OnPingFailed, whenever a node fails to dial a peer.
+type DialError struct {
+	// Addr is the address of the peer that failed to be dialed.
+	Addr string
+
+	// Attempts is the number of times the peer was dialed before giving up.
+	Attempts uint
+
+	// Cached marks whether the peer was not dialed at all because it recently failed to be dialed, and is still
+	// cooling down. Should Cached be true, Attempts and Err describe the failure that was cached.
+	Cached bool
+
+	// Err is the error that caused the very last attempt to dial the peer to fail.
+	Err error
+}
+
+// Error implements error.
+func (e *DialError) Error() string {
+	if e.Cached {
+		return fmt.Sprintf("skipped dialing %s as it recently failed to be dialed %d time(s): %v", e.Addr, e.Attempts, e.Err)
+	}
+
+	return fmt.Sprintf("attempted to dial %s %d time(s) but failed: %v", e.Addr, e.Attempts, e.Err)
+}
+
+// Unwrap returns the error that caused the very last attempt to dial the peer to fail.
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// dialBackoff returns how long to wait before making the attempt'th attempt to dial a peer. The delay doubles
+// every attempt starting from min up until max, with half of the delay being randomly jittered.
+func dialBackoff(attempt uint, min, max time.Duration) time.Duration {
+	if min <= 0 || attempt == 0 {
+		return 0
+	}
+
+	delay := min
+
+	for i := uint(1); i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		delay = max
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func waitDialBackoff(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to dial peer: %w", ctx.Err())
+	case <-timer.C:
+		return nil
+	}
+}
+
+// dialAny dials a peer through several candidate addresses, which are expected to already be ordered by
+// interleaveAddrs, following RFC 8305. It returns either the first client to successfully complete noise's
+// handshake, or the errors of all candidates that failed to be dialed.
+func (n *Node) dialAny(ctx context.Context, addrs []string) (*Client, []*DialError) {
+	for _, addr := range addrs {
+		if client := n.outbound.find(addr); client != nil && client.isReady() && client.Error() == nil {
+			return client, nil
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		client *Client
+		err    *DialError
+	}
+
+	results := make(chan result, len(addrs))
+	next, pending := 0, 0
+
+	launch := func() {
+		addr := addrs[next]
+
+		next++
+		pending++
+
+		go func() {
+			client, err := n.dial(ctx, addr, true)
+			results <- result{client: client, err: err}
+		}()
+	}
+
+	launch()
+
+	stagger := time.NewTimer(n.dialStagger)
+	defer stagger.Stop()
+
+	var (
+		winner *Client
+		errs   []*DialError
+	)
+
+	for pending > 0 {
+		select {
+		case <-stagger.C:
+			if winner == nil && next < len(addrs) {
+				launch()
+				stagger.Reset(n.dialStagger)
+			}
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				if winner == nil {
+					winner = res.client
+					cancel()
+				} else {
+					res.client.close()
+					res.client.waitUntilClosed()
+				}
+
+				continue
+			}
+
+			errs = append(errs, res.err)
+
+			// Dial the next candidate immediately should the previous candidate have failed.
+
+			if winner == nil && next < len(addrs) {
+				launch()
+
+				if !stagger.Stop() {
+					select {
+					case <-stagger.C:
+					default:
+					}
+				}
+
+				stagger.Reset(n.dialStagger)
+			}
+		}
+
+		// Should all candidates dialed so far have failed while the stagger timer is pending, dial the next
+		// candidate right away.
+
+		if winner == nil && pending == 0 && next < len(addrs) {
+			launch()
+		}
+	}
+
+	if winner != nil {
+		return winner, nil
+	}
+
+	return nil, errs
+}
+
+// interleaveAddrs deduplicates addrs, and interleaves them by their address family as described in section 4 of
+// RFC 8305. The family of the first address is preferred. Addresses whose host is not an IPv6 address are treated
+// as IPv4 addresses.
+func interleaveAddrs(addrs []string) []string {
+	isIPv6 := func(addr string) bool {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return false
+		}
+
+		ip := net.ParseIP(host)
+
+		return ip != nil && ip.To4() == nil
+	}
+
+	var preferred, fallback []string
+
+	seen := make(map[string]struct{}, len(addrs))
+
+	for _, addr := range addrs {
+		if _, exists := seen[addr]; exists {
+			continue
+		}
+
+		seen[addr] = struct{}{}
+
+		if len(preferred) == 0 || isIPv6(addr) == isIPv6(preferred[0]) {
+			preferred = append(preferred, addr)
+		} else {
+			fallback = append(fallback, addr)
+		}
+	}
+
+	interleaved := make([]string, 0, len(preferred)+len(fallback))
+
+	for i := 0; i < len(preferred) || i < len(fallback); i++ {
+		if i < len(preferred) {
+			interleaved = append(interleaved, preferred[i])
+		}
+
+		if i < len(fallback) {
+			interleaved = append(interleaved, fallback[i])
+		}
+	}
+
+	return interleaved
+}
+
+type dialFailure struct {
+	err   DialError
+	until time.Time
+}
+
+type dialFailureMap struct {
+	sync.Mutex
+	entries map[string]dialFailure
+}
+
+func newDialFailureMap() *dialFailureMap {
+	return &dialFailureMap{entries: make(map[string]dialFailure)}
+}
+
+func (d *dialFailureMap) find(addr string) *DialError {
+	d.Lock()
+	defer d.Unlock()
+
+	entry, exists := d.entries[addr]
+	if !exists {
+		return nil
+	}
+
+	if time.Now().After(entry.until) {
+		delete(d.entries, addr)
+		return nil
+	}
+
+	err := entry.err
+	err.Cached = true
+
+	return &err
+}
+
+func (d *dialFailureMap) record(err *DialError, cooldown time.Duration) {
+	if cooldown <= 0 {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	now := time.Now()
+
+	for addr, entry := range d.entries {
+		if now.After(entry.until) {
+			delete(d.entries, addr)
+		}
+	}
+
+	d.entries[err.Addr] = dialFailure{err: *err, until: now.Add(cooldown)}
+}
+
+func (d *dialFailureMap) forget(addr string) {
+	d.Lock()
+	defer d.Unlock()
+
+	delete(d.entries, addr)
+}
@@ -0,0 +1,98 @@
+package noise
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestInterleaveAddrs(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		[]string{"1.1.1.1:1", "[::1]:1", "2.2.2.2:1", "[::2]:1", "3.3.3.3:1"},
+		interleaveAddrs([]string{"1.1.1.1:1", "2.2.2.2:1", "[::1]:1", "1.1.1.1:1", "[::2]:1", "3.3.3.3:1"}),
+	)
+
+	assert.Equal(t,
+		[]string{"[::1]:1", "1.1.1.1:1", "[::2]:1", "example.com:1"},
+		interleaveAddrs([]string{"[::1]:1", "[::2]:1", "1.1.1.1:1", "example.com:1"}),
+	)
+}
+
+func TestPingAnyFallsBackOnFailure(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	unreachable := l.Addr().String()
+	assert.NoError(t, l.Close())
+
+	b, err := NewNode(WithNodeBindHost(net.IPv4(127, 0, 0, 1)))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	a, err := NewNode(WithNodeMaxDialAttempts(1), WithNodeDialStagger(time.Minute))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	failed := false
+
+	a.Bind(Protocol{
+		OnPingFailed: func(addr string, err error) {
+			failed = true
+		},
+	})
+
+	assert.NoError(t, a.Listen())
+
+	reachable := net.JoinHostPort("127.0.0.1", strconv.FormatUint(uint64(b.ID().Port), 10))
+
+	client, err := a.PingAny(context.Background(), unreachable, reachable)
+	assert.NoError(t, err)
+
+	assert.Equal(t, b.ID().ID, client.ID().ID)
+	assert.Equal(t, reachable, client.Addr())
+	assert.False(t, failed)
+}
+
+func TestPingAnyKeepsSingleConnection(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	b, err := NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	a, err := NewNode(WithNodeDialStagger(0))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	port := strconv.FormatUint(uint64(b.ID().Port), 10)
+
+	client, err := a.PingAny(context.Background(), net.JoinHostPort("127.0.0.1", port), net.JoinHostPort("localhost", port))
+	assert.NoError(t, err)
+	assert.Equal(t, b.ID().ID, client.ID().ID)
+
+	assert.Len(t, a.Outbound(), 1)
+
+	// Should a live connection already exist through any candidate, it is reused.
+
+	again, err := a.PingAny(context.Background(), net.JoinHostPort("localhost", port), net.JoinHostPort("127.0.0.1", port))
+	assert.NoError(t, err)
+	assert.Equal(t, client, again)
+}
@@ -1,9 +1,99 @@
 package noise
 
-import "errors"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
 
 var (
 	// ErrMessageTooLarge is reported by a client when it receives a message from a peer that exceeds the max
 	// receivable message size limit configured on a node.
 	ErrMessageTooLarge = errors.New("msg from peer is too large")
+
+	// ErrPeerUnresponsive is reported by a client when its peer has failed to acknowledge the max number of
+	// consecutive heartbeats configured on a node.
+	ErrPeerUnresponsive = errors.New("peer is unresponsive")
+
+	// ErrRecordUnsigned is returned when verifying a peer ID that has not been signed.
+	ErrRecordUnsigned = errors.New("peer record is unsigned")
+
+	// ErrRecordSignatureInvalid is returned when verifying a peer ID whose signature was not signed by the private
+	// key of the public key of the ID.
+	ErrRecordSignatureInvalid = errors.New("peer record signature is invalid")
+
+	// ErrRecordExpired is returned when verifying a peer ID that has expired.
+	ErrRecordExpired = errors.New("peer record has expired")
+
+	// ErrKeyTypeUnsupported is returned when decoding an identity key of a type that has not been registered via
+	// RegisterKeyType.
+	ErrKeyTypeUnsupported = errors.New("identity key type is unsupported")
+
+	// ErrOpcodeMismatch is reported by a client when its peer has registered a Go type under a different opcode, or
+	// has registered a different Go type under the same opcode, and opcode remapping is disabled. See
+	// WithNodeOpcodeRemapping.
+	ErrOpcodeMismatch = errors.New("peer registered messages under mismatched opcodes")
 )
+
+// RemoteError is returned by (*Node).Request and its variants should the peer have responded to a request with an
+// error, as opposed to the request having failed to be delivered or responded to. A handler may respond to a request
+// with an error by returning a *RemoteError, or by calling (*HandlerContext).SendError. Codes are application-defined.
+type RemoteError struct {
+	Code    uint32
+	Message string
+	Details []byte
+}
+
+// Error returns the code and message of the error.
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("peer responded with error %d: %s", e.Code, e.Message)
+}
+
+// maxRemoteErrorSize is the max number of bytes the message and details of a RemoteError are truncated to, such that
+// they fit within the header block of a message.
+const maxRemoteErrorSize = 32 << 10
+
+func (e *RemoteError) marshal() []byte {
+	message, details := e.Message, e.Details
+
+	if len(message) > maxRemoteErrorSize {
+		message = message[:maxRemoteErrorSize]
+	}
+
+	if len(message)+len(details) > maxRemoteErrorSize {
+		details = details[:maxRemoteErrorSize-len(message)]
+	}
+
+	buf := make([]byte, 4+2, 4+2+len(message)+len(details))
+	binary.BigEndian.PutUint32(buf[:4], e.Code)
+	binary.BigEndian.PutUint16(buf[4:6], uint16(len(message)))
+
+	buf = append(buf, message...)
+	buf = append(buf, details...)
+
+	return buf
+}
+
+func unmarshalRemoteError(buf []byte) (*RemoteError, error) {
+	if len(buf) < 4+2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	e := &RemoteError{Code: binary.BigEndian.Uint32(buf[:4])}
+
+	size := int(binary.BigEndian.Uint16(buf[4:6]))
+	buf = buf[6:]
+
+	if len(buf) < size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	e.Message = string(buf[:size])
+
+	if len(buf) > size {
+		e.Details = append([]byte{}, buf[size:]...)
+	}
+
+	return e, nil
+}
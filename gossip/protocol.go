@@ -18,6 +18,12 @@ type Protocol struct {
 	events  Events
 
 	seen *fastcache.Cache
+
+	metrics struct {
+		pushed     *noise.Counter
+		received   *noise.Counter
+		duplicates *noise.Counter
+	}
 }
 
 // New returns a new instance of a gossip protocol with 32MB of in-memory cache instantiated.
@@ -44,12 +50,21 @@ func (p *Protocol) Protocol() noise.Protocol {
 	}
 }
 
-// Bind registers a single message gossip.Message, and handles them by registering the (*Protocol).Handle Handler.
+// Bind registers a single message gossip.Message, and routes them to their handler via (*noise.Node).HandleMessage.
 func (p *Protocol) Bind(node *noise.Node) error {
 	p.node = node
 
+	metrics := node.Metrics()
+
+	p.metrics.pushed = metrics.Counter("noise_gossip_messages_pushed_total",
+		"Number of gossiped messages sent to peers.")
+	p.metrics.received = metrics.Counter("noise_gossip_messages_received_total",
+		"Number of gossiped messages received that had not been seen before.")
+	p.metrics.duplicates = metrics.Counter("noise_gossip_messages_duplicate_total",
+		"Number of gossiped messages received that had already been seen.")
+
 	node.RegisterMessage(Message{}, UnmarshalMessage)
-	node.Handle(p.Handle)
+	node.HandleMessage(Message{}, p.handleMessage)
 
 	return nil
 }
@@ -80,6 +95,8 @@ func (p *Protocol) Push(ctx context.Context, data []byte) {
 				return
 			}
 
+			p.metrics.pushed.Inc()
+
 			p.seen.Set(key, nil)
 		}()
 	}
@@ -87,12 +104,9 @@ func (p *Protocol) Push(ctx context.Context, data []byte) {
 	wg.Wait()
 }
 
-// Handle implements noise.Protocol and handles gossip.Message messages.
+// Handle implements noise.Handler and handles gossip.Message messages. Bind routes gossip.Message messages to their
+// handler directly, such that Handle need only be registered to nodes whose messages are not routed.
 func (p *Protocol) Handle(ctx noise.HandlerContext) error {
-	if ctx.IsRequest() {
-		return nil
-	}
-
 	obj, err := ctx.DecodeMessage()
 	if err != nil {
 		return nil
@@ -103,16 +117,27 @@ func (p *Protocol) Handle(ctx noise.HandlerContext) error {
 		return nil
 	}
 
+	return p.handleMessage(ctx, msg)
+}
+
+func (p *Protocol) handleMessage(ctx noise.HandlerContext, msg Message) error {
+	if ctx.IsRequest() {
+		return nil
+	}
+
 	p.seen.Set(p.hash(ctx.ID(), msg), nil) // Mark that the sender already has this data.
 
 	self := p.hash(p.node.ID(), msg)
 
 	if p.seen.Has(self) {
+		p.metrics.duplicates.Inc()
 		return nil
 	}
 
 	p.seen.Set(self, nil) // Mark that we already have this data.
 
+	p.metrics.received.Inc()
+
 	if p.events.OnGossipReceived != nil {
 		if err := p.events.OnGossipReceived(ctx.ID(), msg); err != nil {
 			return err
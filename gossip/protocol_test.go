@@ -99,6 +99,8 @@ func TestGossip(t *testing.T) {
 
 	hub.Push(context.TODO(), []byte("hello!"))
 
+	assert.NotZero(t, leader.Stats().Counters["noise_gossip_messages_pushed_total"])
+
 	cond.L.Lock()
 	for len(seen) != len(nodes) {
 		cond.Wait()
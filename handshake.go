@@ -0,0 +1,233 @@
+package noise
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// handshakeMagic prefixes the handshake extension, which follows the peer record a peer sends in a separate frame
+// right after the overlay handshake. The frame is covered by a signature of its own, and is only sent to peers that
+// advertise support for the handshake extension. See handshakeNonce.
+var handshakeMagic = [...]byte{0xff, 'n', 'x'}
+
+// handshakeNonce returns the nonce a peer that supports the handshake extension encrypts its overlay handshake under,
+// which is derived from the shared key of the session and the ephemeral public key of the peer. Peers that predate
+// the handshake extension encrypt their overlay handshake under a random nonce, and decrypt the overlay handshake of
+// a peer under whichever nonce it is prefixed with, such that support may be advertised without altering the overlay
+// handshake. Both peers derive their nonce from distinct ephemeral public keys, and thus never reuse a nonce.
+func handshakeNonce(shared []byte, pub PublicKey, size int) []byte {
+	h := sha256.New()
+	_, _ = h.Write([]byte(".__noise_handshake_extension"))
+	_, _ = h.Write(shared)
+	_, _ = h.Write(pub[:])
+
+	return h.Sum(nil)[:size]
+}
+
+// handshakeField tags a single field within the handshake extension.
+type handshakeField byte
+
+const (
+	handshakeEnvelope handshakeField = iota + 1
+	handshakeOpcode
+	handshakeControl
+)
+
+// handshakeExtension holds what a peer advertises about itself right after the overlay handshake. A peer that does
+// not send a handshake extension is represented by its zero value.
+type handshakeExtension struct {
+	// envelope is the message envelope version supported by the peer, which is zero for legacy peers that only
+	// understand messages comprised of a nonce and data.
+	envelope uint8
+
+	// control is true should the peer understand control frames (i.e. heartbeats), which peers that predate control
+	// frames would otherwise handle as requests.
+	control bool
+
+	// opcodes are the names of all Go types the peer has registered, keyed by the opcode they are registered under.
+	opcodes map[uint16]string
+}
+
+// marshal appends the handshake extension to dst, encoded as the handshake magic followed by every field encoded as
+// [type uint8][length uint16][value]. Support for control frames is encoded as a field with no value. Every opcode
+// is encoded as a separate field whose value is comprised of the opcode as a big-endian uint16 followed by the name
+// of the Go type registered under it.
+func (e handshakeExtension) marshal(dst []byte) []byte {
+	dst = append(dst, handshakeMagic[:]...)
+	dst = append(dst, byte(handshakeEnvelope), 0, 1, e.envelope)
+
+	if e.control {
+		dst = append(dst, byte(handshakeControl), 0, 0)
+	}
+
+	opcodes := make([]uint16, 0, len(e.opcodes))
+	for opcode := range e.opcodes {
+		opcodes = append(opcodes, opcode)
+	}
+
+	sort.Slice(opcodes, func(i, j int) bool { return opcodes[i] < opcodes[j] })
+
+	for _, opcode := range opcodes {
+		name := e.opcodes[opcode]
+		if len(name) > math.MaxUint16-2 {
+			continue
+		}
+
+		dst = append(dst, byte(handshakeOpcode), 0, 0, 0, 0)
+		binary.BigEndian.PutUint16(dst[len(dst)-4:len(dst)-2], uint16(2+len(name)))
+		binary.BigEndian.PutUint16(dst[len(dst)-2:], opcode)
+		dst = append(dst, name...)
+	}
+
+	return dst
+}
+
+// unmarshalHandshakeExtension decodes the handshake extension in buf. An empty buf decodes into the zero value of a
+// handshake extension. Fields of types unknown to this version of noise are skipped.
+func unmarshalHandshakeExtension(buf []byte) (handshakeExtension, error) {
+	var e handshakeExtension
+
+	if len(buf) == 0 {
+		return e, nil
+	}
+
+	if !bytes.HasPrefix(buf, handshakeMagic[:]) {
+		return e, io.ErrUnexpectedEOF
+	}
+
+	buf = buf[len(handshakeMagic):]
+
+	for len(buf) > 0 {
+		if len(buf) < 3 {
+			return e, io.ErrUnexpectedEOF
+		}
+
+		typ, length := handshakeField(buf[0]), int(binary.BigEndian.Uint16(buf[1:3]))
+		if len(buf) < 3+length {
+			return e, io.ErrUnexpectedEOF
+		}
+
+		value := buf[3 : 3+length]
+		buf = buf[3+length:]
+
+		switch typ {
+		case handshakeEnvelope:
+			if len(value) != 1 {
+				return e, io.ErrUnexpectedEOF
+			}
+
+			e.envelope = value[0]
+		case handshakeControl:
+			e.control = true
+		case handshakeOpcode:
+			if len(value) < 2 {
+				return e, io.ErrUnexpectedEOF
+			}
+
+			if e.opcodes == nil {
+				e.opcodes = make(map[uint16]string)
+			}
+
+			e.opcodes[binary.BigEndian.Uint16(value[:2])] = string(value[2:])
+		}
+	}
+
+	return e, nil
+}
+
+// opcodeRemap maps the opcodes of messages sent to a peer from the opcodes they are registered under on this node to
+// the opcodes they are registered under on the peer.
+type opcodeRemap struct {
+	// opcodes maps local opcodes to the opcodes of the peer for Go types registered under different opcodes.
+	opcodes map[uint16]uint16
+
+	// unsupported holds the local opcodes of Go types the peer has not registered, but which the peer has registered
+	// another Go type under.
+	unsupported map[uint16]string
+}
+
+// negotiateOpcodes compares the opcodes of local against the opcodes of peer. Should a Go type be registered under
+// different opcodes, or should an opcode be registered to different Go types, it returns ErrOpcodeMismatch unless
+// remap is true, in which case it returns how opcodes are to be remapped for messages sent to the peer.
+func negotiateOpcodes(local, peer map[uint16]string, remap bool) (opcodeRemap, error) {
+	var r opcodeRemap
+
+	byName := make(map[string]uint16, len(peer))
+	for opcode, name := range peer {
+		byName[name] = opcode
+	}
+
+	for opcode, name := range local {
+		peerOpcode, known := byName[name]
+
+		if known && peerOpcode == opcode {
+			continue
+		}
+
+		peerName, taken := peer[opcode]
+
+		if !known && !taken {
+			continue
+		}
+
+		if !remap {
+			if known {
+				return r, fmt.Errorf("%w: %s is registered under opcode %d, but the peer registered it under opcode %d",
+					ErrOpcodeMismatch, name, opcode, peerOpcode,
+				)
+			}
+
+			return r, fmt.Errorf("%w: opcode %d is registered to %s, but the peer registered it to %s",
+				ErrOpcodeMismatch, opcode, name, peerName,
+			)
+		}
+
+		if known {
+			if r.opcodes == nil {
+				r.opcodes = make(map[uint16]uint16)
+			}
+
+			r.opcodes[opcode] = peerOpcode
+		} else {
+			if r.unsupported == nil {
+				r.unsupported = make(map[uint16]string)
+			}
+
+			r.unsupported[opcode] = name
+		}
+	}
+
+	return r, nil
+}
+
+// apply rewrites the opcode data is prefixed with to the opcode of the peer. It returns an error should the peer not
+// support the Go type data is of.
+func (r opcodeRemap) apply(data []byte) ([]byte, error) {
+	if len(data) < 2 || (len(r.opcodes) == 0 && len(r.unsupported) == 0) {
+		return data, nil
+	}
+
+	opcode := binary.BigEndian.Uint16(data[:2])
+
+	if name, unsupported := r.unsupported[opcode]; unsupported {
+		return nil, fmt.Errorf("peer does not support messages of type %s, and registered opcode %d to another type",
+			name, opcode,
+		)
+	}
+
+	peerOpcode, remapped := r.opcodes[opcode]
+	if !remapped {
+		return data, nil
+	}
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	binary.BigEndian.PutUint16(buf[:2], peerOpcode)
+
+	return buf, nil
+}
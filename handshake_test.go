@@ -0,0 +1,414 @@
+package noise
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// legacyPeer speaks to a node the way peers that predate the handshake extension do.
+type legacyPeer struct {
+	conn  net.Conn
+	suite cipher.AEAD
+}
+
+// unmarshalBaselineID decodes an ID exactly as peers that predate peer records do.
+func unmarshalBaselineID(buf []byte) (PublicKey, net.IP, uint16, error) {
+	if len(buf) < SizePublicKey+net.IPv6len+2 {
+		return PublicKey{}, nil, 0, io.ErrUnexpectedEOF
+	}
+
+	var id PublicKey
+	copy(id[:], buf[:SizePublicKey])
+
+	host := make([]byte, net.IPv6len)
+	copy(host, buf[SizePublicKey:SizePublicKey+net.IPv6len])
+
+	return id, host, binary.BigEndian.Uint16(buf[SizePublicKey+net.IPv6len:]), nil
+}
+
+// marshalBaselineID encodes an ID exactly as peers that predate peer records do.
+func marshalBaselineID(id PublicKey, host net.IP, port uint16) []byte {
+	buf := make([]byte, SizePublicKey+net.IPv6len+2)
+
+	copy(buf[:SizePublicKey], id[:])
+	copy(buf[SizePublicKey:SizePublicKey+net.IPv6len], host)
+	binary.BigEndian.PutUint16(buf[SizePublicKey+net.IPv6len:], port)
+
+	return buf
+}
+
+// openSession dials node, and performs the session handshake with it. It returns the ephemeral keypair of the peer
+// alongside the shared key of the session.
+func openSession(t *testing.T, node *Node) (*legacyPeer, PublicKey, PrivateKey, []byte) {
+	conn, err := net.Dial("tcp", node.Addr())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	p := &legacyPeer{conn: conn}
+
+	pub, sec, err := GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	signature := sec.Sign([]byte(".__noise_handshake"))
+	assert.NoError(t, p.write(append(pub[:], signature[:]...)))
+
+	data, err := p.read()
+	if !assert.NoError(t, err) || !assert.Len(t, data, SizePublicKey+SizeSignature) {
+		t.FailNow()
+	}
+
+	var peerPublicKey PublicKey
+	copy(peerPublicKey[:], data[:SizePublicKey])
+
+	shared, err := ECDH(sec, peerPublicKey)
+	assert.NoError(t, err)
+
+	core, err := aes.NewCipher(shared)
+	assert.NoError(t, err)
+
+	p.suite, err = cipher.NewGCM(core)
+	assert.NoError(t, err)
+
+	return p, pub, sec, shared
+}
+
+// dialLegacy dials node, and handshakes with it exactly as peers that predate the handshake extension and peer records
+// do. It returns the public key, host, and port of the ID node sent back, which is checked to be of the exact size
+// and to bear a signature of the ID alongside the shared key of the session.
+func dialLegacy(t *testing.T, node *Node) (*legacyPeer, PublicKey, net.IP, uint16) {
+	p, pub, sec, shared := openSession(t, node)
+
+	buf := marshalBaselineID(pub, net.IPv4(127, 0, 0, 1).To16(), 3000)
+
+	signature := sec.Sign(append(buf, shared...))
+	assert.NoError(t, p.write(append(buf, signature[:]...)))
+
+	data, err := p.read()
+	if !assert.NoError(t, err) || !assert.Len(t, data, SizePublicKey+net.IPv6len+2+SizeSignature) {
+		t.FailNow()
+	}
+
+	id, host, port, err := unmarshalBaselineID(data)
+	assert.NoError(t, err)
+
+	buf = marshalBaselineID(id, host, port)
+	assert.True(t, id.Verify(append(buf, shared...), UnmarshalSignature(data[len(buf):])))
+
+	return p, id, host, port
+}
+
+// dialWithExtension dials node, and handshakes with it by advertising support for the handshake extension and
+// sending ext right after the overlay handshake. It returns the peer record and handshake extension node sent back.
+func dialWithExtension(t *testing.T, node *Node, ext []byte) (*legacyPeer, ID, handshakeExtension) {
+	p, pub, sec, shared := openSession(t, node)
+
+	buf := marshalBaselineID(pub, net.IPv4(127, 0, 0, 1).To16(), 3000)
+
+	signature := sec.Sign(append(buf, shared...))
+	assert.NoError(t, p.writeWithNonce(append(buf, signature[:]...), handshakeNonce(shared, pub, p.suite.NonceSize())))
+
+	data, err := p.read()
+	if !assert.NoError(t, err) || !assert.Len(t, data, legacyIDSize+SizeSignature) {
+		t.FailNow()
+	}
+
+	buf = append(NewID(pub, net.IPv4(127, 0, 0, 1), 3000).Marshal(), ext...)
+
+	signature = sec.Sign(append(buf, shared...))
+	assert.NoError(t, p.write(append(buf, signature[:]...)))
+
+	data, err = p.read()
+	if !assert.NoError(t, err) || !assert.True(t, len(data) > SizeSignature) {
+		t.FailNow()
+	}
+
+	data = data[:len(data)-SizeSignature]
+
+	record, n, err := unmarshalRecord(data)
+	assert.NoError(t, err)
+
+	received, err := unmarshalHandshakeExtension(data[n:])
+	assert.NoError(t, err)
+
+	return p, record, received
+}
+
+func (p *legacyPeer) write(data []byte) error {
+	return p.writeWithNonce(data, nil)
+}
+
+func (p *legacyPeer) writeWithNonce(data []byte, nonce []byte) error {
+	if p.suite != nil {
+		var err error
+
+		if data, err = encryptAEADWithNonce(p.suite, data, nonce); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(data)))
+	copy(buf[4:], data)
+
+	_, err := p.conn.Write(buf)
+
+	return err
+}
+
+func (p *legacyPeer) read() ([]byte, error) {
+	var size [4]byte
+
+	if _, err := io.ReadFull(p.conn, size[:]); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.BigEndian.Uint32(size[:]))
+
+	if _, err := io.ReadFull(p.conn, buf); err != nil {
+		return nil, err
+	}
+
+	if p.suite == nil {
+		return buf, nil
+	}
+
+	return decryptAEAD(p.suite, buf)
+}
+
+func TestControlFramesNotSentToLegacyPeers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := NewNode(WithNodeHeartbeatInterval(10*time.Millisecond), WithNodeHeartbeatMaxMissed(1))
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	node.Handle(func(ctx HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, node.Listen())
+
+	peer, _, _, _ := dialLegacy(t, node)
+	defer peer.conn.Close()
+
+	// The peer is neither sent a handshake extension, heartbeats, nor observed address reports, and is not declared
+	// dead for not acknowledging heartbeats.
+
+	assert.NoError(t, peer.conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+	_, err = peer.read()
+
+	var netErr net.Error
+	if assert.True(t, errors.As(err, &netErr)) {
+		assert.True(t, netErr.Timeout())
+	}
+
+	assert.NoError(t, peer.conn.SetReadDeadline(time.Time{}))
+
+	// Requests from the peer are handled as usual.
+
+	buf, err := message{nonce: 1, data: []byte("ping")}.marshal(nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, peer.write(buf))
+
+	data, err := peer.read()
+	assert.NoError(t, err)
+
+	msg, err := unmarshalMessage(data)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, msg.nonce)
+	assert.Equal(t, []byte("ping"), msg.data)
+
+	if assert.Len(t, node.Inbound(), 1) {
+		client := node.Inbound()[0]
+
+		assert.NoError(t, client.Error())
+		assert.False(t, client.control)
+		assert.Zero(t, client.RTT())
+	}
+}
+
+func TestIDSentToLegacyPeers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := NewNode(WithNodeMetadata("role", "relay"))
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	assert.NoError(t, node.Listen())
+
+	// Peers that predate peer records are sent the ID of a node encoded in the legacy fixed-size format, regardless
+	// of the fields the peer record of the node carries.
+
+	peer, id, host, port := dialLegacy(t, node)
+	defer peer.conn.Close()
+
+	assert.Equal(t, node.ID().ID, id)
+	assert.True(t, node.ID().Host.Equal(host))
+	assert.Equal(t, node.ID().Port, port)
+
+	// Peers that advertise support for the handshake extension are sent the peer record of the node as well.
+
+	peer, record, ext := dialWithExtension(t, node, handshakeExtension{envelope: envelopeVersion}.marshal(nil))
+	defer peer.conn.Close()
+
+	assert.True(t, record.Equal(node.ID()))
+	assert.Equal(t, "relay", record.Metadata["role"])
+	assert.EqualValues(t, envelopeVersion, ext.envelope)
+	assert.True(t, ext.control)
+}
+
+func TestEnvelopeVersionOfNewerPeers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := NewNode()
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	node.Handle(func(ctx HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, node.Listen())
+
+	// Peers that advertise a newer envelope version are only sent envelopes of the version this node produces.
+
+	peer, _, _ := dialWithExtension(t, node, handshakeExtension{envelope: envelopeVersion + 1}.marshal(nil))
+	defer peer.conn.Close()
+
+	buf, err := message{nonce: 1, data: []byte("ping")}.marshal(nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, peer.write(buf))
+
+	_, err = peer.read()
+	assert.NoError(t, err)
+
+	if assert.Len(t, node.Inbound(), 1) {
+		assert.EqualValues(t, envelopeVersion, node.Inbound()[0].EnvelopeVersion())
+	}
+}
+
+func TestOpcodesOfLegacyPeers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	// Legacy peers registered Go types under opcodes counted up from zero, which mismatch derived opcodes.
+
+	mismatched, err := NewNode(WithNodeOpcodeRemapping(true))
+	assert.NoError(t, err)
+
+	mismatched.RegisterMessage(test{}, unmarshalTest)
+	mismatched.RegisterMessage(test2{}, unmarshalTest2)
+
+	assert.NoError(t, mismatched.Listen())
+
+	peer, _, _, _ := dialLegacy(t, mismatched)
+
+	assert.NoError(t, peer.conn.SetReadDeadline(time.Now().Add(3*time.Second)))
+
+	_, err = peer.read()
+
+	var netErr net.Error
+	if assert.Error(t, err) && errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout(), "connection to legacy peer with mismatched opcodes was never closed")
+	}
+
+	assert.NoError(t, peer.conn.Close())
+	mismatched.Close()
+
+	// Legacy peers are accepted should Go types be registered in the same order under opcodes counted up from zero.
+
+	matched, err := NewNode()
+	assert.NoError(t, err)
+
+	defer matched.Close()
+
+	matched.RegisterMessageWithOpcode(test{}, unmarshalTest, 0)
+	matched.RegisterMessageWithOpcode(test2{}, unmarshalTest2, 1)
+
+	received := make(chan Serializable, 1)
+
+	matched.Handle(func(ctx HandlerContext) error {
+		msg, err := ctx.DecodeMessage()
+		if err != nil {
+			return err
+		}
+
+		received <- msg
+
+		return nil
+	})
+
+	assert.NoError(t, matched.Listen())
+
+	peer, _, _, _ = dialLegacy(t, matched)
+	defer peer.conn.Close()
+
+	buf, err := message{data: []byte{0, 1, 'h', 'i'}}.marshal(nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, peer.write(buf))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, test2{data: []byte("hi")}, msg)
+	case <-time.After(3 * time.Second):
+		t.Fatal("message from legacy peer was never received")
+	}
+
+	// Legacy peers are accepted should Go types be registered in the same order with legacy opcodes enabled.
+
+	legacy, err := NewNode(WithNodeLegacyOpcodes(true))
+	assert.NoError(t, err)
+
+	defer legacy.Close()
+
+	legacy.RegisterMessage(test{}, unmarshalTest)
+	legacy.RegisterMessage(test2{}, unmarshalTest2)
+
+	legacy.Handle(func(ctx HandlerContext) error {
+		msg, err := ctx.DecodeMessage()
+		if err != nil {
+			return err
+		}
+
+		received <- msg
+
+		return nil
+	})
+
+	assert.NoError(t, legacy.Listen())
+
+	peer, _, _, _ = dialLegacy(t, legacy)
+	defer peer.conn.Close()
+
+	assert.NoError(t, peer.write(buf))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, test2{data: []byte("hi")}, msg)
+	case <-time.After(3 * time.Second):
+		t.Fatal("message from legacy peer was never received")
+	}
+}
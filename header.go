@@ -0,0 +1,349 @@
+package noise
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// envelopeVersion is the version of the message envelope produced by this version of noise, which is negotiated
+// with peers during the handshake. Peers that do not advertise an envelope version are legacy peers, which are only
+// ever sent messages comprised of a nonce and data.
+const envelopeVersion = 1
+
+// maxHeaderSize is the max size in bytes of all entries within the header block of a message.
+const maxHeaderSize = math.MaxUint16
+
+// headerType tags a single typed header within the header block of a message.
+type headerType byte
+
+const (
+	headerTrace headerType = iota + 1
+	headerTimeout
+	headerContentType
+	headerCompression
+	headerProtocol
+	headerFlags
+	headerError
+	headerStream
+
+	// headerValue is an application-defined key/value header.
+	headerValue headerType = 0x80
+)
+
+// Header holds the optional headers of a message. Headers are only sent to peers that negotiated an envelope version
+// during the handshake, and are otherwise dropped. A message whose headers are all zero incurs no additional bytes on
+// the wire.
+type Header struct {
+	// Trace is the trace context of the message. See ContextWithTrace.
+	Trace TraceContext
+
+	// Deadline is the time by which the sender of the message no longer awaits a response to it. It is sent as the
+	// time remaining until it, and is computed on receipt against the clock of the recipient, such that the clocks
+	// of peers need not be synchronized.
+	Deadline time.Time
+
+	// ContentType describes the encoding of the data of the message, i.e. "application/protobuf".
+	ContentType string
+
+	// Compression names the compression the data of the message is compressed with, i.e. "gzip". noise does not
+	// compress nor decompress data itself.
+	Compression string
+
+	// Protocol identifies the application protocol the message belongs to, i.e. "/chat/1.0.0".
+	Protocol string
+
+	// Flags are application-defined message flags.
+	Flags uint32
+
+	// Values are application-defined key/value headers. Keys must be at most 255 bytes, and all headers must encode
+	// to at most 64 KiB, or else the message fails to be sent.
+	Values map[string]string
+
+	// err marks a response as being an error. See RemoteError.
+	err *RemoteError
+
+	// stream marks a response as being one of a stream of responses to a single request. See ResponseStream.
+	stream streamMarker
+}
+
+// Get returns the application-defined header value under key, or an empty string should there be none.
+func (h Header) Get(key string) string {
+	return h.Values[key]
+}
+
+// Set sets the application-defined header value under key to value.
+func (h *Header) Set(key, value string) {
+	if h.Values == nil {
+		h.Values = make(map[string]string)
+	}
+
+	h.Values[key] = value
+}
+
+// IsZero returns true if none of the headers are set.
+func (h Header) IsZero() bool {
+	return !h.Trace.IsValid() && h.Deadline.IsZero() && h.ContentType == "" && h.Compression == "" &&
+		h.Protocol == "" && h.Flags == 0 && len(h.Values) == 0 && h.err == nil && h.stream == 0
+}
+
+// merge returns h with all headers set in other overriding those of h.
+func (h Header) merge(other Header) Header {
+	if other.Trace.IsValid() {
+		h.Trace = other.Trace
+	}
+
+	if !other.Deadline.IsZero() {
+		h.Deadline = other.Deadline
+	}
+
+	if other.ContentType != "" {
+		h.ContentType = other.ContentType
+	}
+
+	if other.Compression != "" {
+		h.Compression = other.Compression
+	}
+
+	if other.Protocol != "" {
+		h.Protocol = other.Protocol
+	}
+
+	if other.Flags != 0 {
+		h.Flags = other.Flags
+	}
+
+	if len(other.Values) > 0 {
+		values := make(map[string]string, len(h.Values)+len(other.Values))
+
+		for key, value := range h.Values {
+			values[key] = value
+		}
+
+		for key, value := range other.Values {
+			values[key] = value
+		}
+
+		h.Values = values
+	}
+
+	return h
+}
+
+type headerContextKey struct{}
+
+// ContextWithHeader returns a copy of ctx carrying header, merged over any header ctx already carries. Requests and
+// messages sent via (*Node).Request, (*Node).Send, and their variants with ctx carry header to the peer, where it is
+// available via (*HandlerContext).Header.
+func ContextWithHeader(ctx context.Context, header Header) context.Context {
+	existing, _ := ctx.Value(headerContextKey{}).(Header)
+	return context.WithValue(ctx, headerContextKey{}, existing.merge(header))
+}
+
+// HeaderFromContext returns the header carried by ctx, which includes the trace context set via ContextWithTrace
+// should ctx carry no other trace context.
+func HeaderFromContext(ctx context.Context) Header {
+	header, _ := ctx.Value(headerContextKey{}).(Header)
+
+	if trace, ok := TraceFromContext(ctx); ok && !header.Trace.IsValid() {
+		header.Trace = trace
+	}
+
+	return header
+}
+
+// marshalHeader appends the header block of h to dst. The header block is comprised of the envelope version, the
+// length of all entries as a big-endian uint16, and every entry encoded as [type uint8][length uint16][value]. The
+// deadline is encoded as the number of nanoseconds remaining until it as a big-endian uint64. It
+// returns an error should the entries not fit within maxHeaderSize bytes.
+func marshalHeader(dst []byte, h Header) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, envelopeVersion, 0, 0)
+
+	var err error
+
+	entry := func(typ headerType, value []byte) {
+		if err != nil {
+			return
+		}
+
+		if size := len(dst) - start - 3 + 3 + len(value); size > maxHeaderSize {
+			err = fmt.Errorf("header entries are at least %d byte(s), but the max is %d byte(s)", size, maxHeaderSize)
+			return
+		}
+
+		dst = append(dst, byte(typ), byte(len(value)>>8), byte(len(value)))
+		dst = append(dst, value...)
+	}
+
+	if h.Trace.IsValid() {
+		buf := make([]byte, 0, SizeTraceContext)
+		buf = append(buf, h.Trace.TraceID[:]...)
+		buf = append(buf, h.Trace.SpanID[:]...)
+		buf = append(buf, h.Trace.Flags)
+
+		entry(headerTrace, buf)
+	}
+
+	if !h.Deadline.IsZero() {
+		timeout := time.Until(h.Deadline)
+		if timeout < 0 {
+			timeout = 0
+		}
+
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(timeout))
+
+		entry(headerTimeout, buf[:])
+	}
+
+	if h.ContentType != "" {
+		entry(headerContentType, []byte(h.ContentType))
+	}
+
+	if h.Compression != "" {
+		entry(headerCompression, []byte(h.Compression))
+	}
+
+	if h.Protocol != "" {
+		entry(headerProtocol, []byte(h.Protocol))
+	}
+
+	if h.Flags != 0 {
+		var buf [4]byte
+		binary.BigEndian.PutUint32(buf[:], h.Flags)
+
+		entry(headerFlags, buf[:])
+	}
+
+	if h.err != nil {
+		entry(headerError, h.err.marshal())
+	}
+
+	if h.stream != 0 {
+		entry(headerStream, []byte{byte(h.stream)})
+	}
+
+	keys := make([]string, 0, len(h.Values))
+	for key := range h.Values {
+		if len(key) > math.MaxUint8 {
+			return dst[:start], fmt.Errorf("header key %q is %d byte(s), but the max is %d byte(s)",
+				key[:16]+"...", len(key), math.MaxUint8,
+			)
+		}
+
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry(headerValue, append(append([]byte{byte(len(key))}, key...), h.Values[key]...))
+	}
+
+	if err != nil {
+		return dst[:start], err
+	}
+
+	binary.BigEndian.PutUint16(dst[start+1:start+3], uint16(len(dst)-start-3))
+
+	return dst, nil
+}
+
+// unmarshalHeader decodes the header block at the head of buf, returning the number of bytes read. Entries of types
+// unknown to this version of noise are skipped.
+func unmarshalHeader(buf []byte) (Header, int, error) {
+	var h Header
+
+	if len(buf) < 3 {
+		return h, 0, io.ErrUnexpectedEOF
+	}
+
+	if buf[0] != envelopeVersion {
+		return h, 0, fmt.Errorf("got message envelope of unsupported version %d", buf[0])
+	}
+
+	size := 3 + int(binary.BigEndian.Uint16(buf[1:3]))
+	if len(buf) < size {
+		return h, 0, io.ErrUnexpectedEOF
+	}
+
+	entries := buf[3:size]
+
+	for len(entries) > 0 {
+		if len(entries) < 3 {
+			return h, 0, io.ErrUnexpectedEOF
+		}
+
+		typ, length := headerType(entries[0]), int(binary.BigEndian.Uint16(entries[1:3]))
+		if len(entries) < 3+length {
+			return h, 0, io.ErrUnexpectedEOF
+		}
+
+		value := entries[3 : 3+length]
+		entries = entries[3+length:]
+
+		switch typ {
+		case headerTrace:
+			if len(value) != SizeTraceContext {
+				return h, 0, fmt.Errorf("got trace context header of %d byte(s), but expected %d byte(s)",
+					len(value), SizeTraceContext,
+				)
+			}
+
+			copy(h.Trace.TraceID[:], value[:16])
+			copy(h.Trace.SpanID[:], value[16:24])
+			h.Trace.Flags = value[24]
+		case headerTimeout:
+			if len(value) != 8 {
+				return h, 0, fmt.Errorf("got timeout header of %d byte(s), but expected 8 byte(s)", len(value))
+			}
+
+			timeout := binary.BigEndian.Uint64(value)
+			if timeout > math.MaxInt64 {
+				timeout = math.MaxInt64
+			}
+
+			h.Deadline = time.Now().Add(time.Duration(timeout))
+		case headerContentType:
+			h.ContentType = string(value)
+		case headerCompression:
+			h.Compression = string(value)
+		case headerProtocol:
+			h.Protocol = string(value)
+		case headerFlags:
+			if len(value) != 4 {
+				return h, 0, fmt.Errorf("got flags header of %d byte(s), but expected 4 byte(s)", len(value))
+			}
+
+			h.Flags = binary.BigEndian.Uint32(value)
+		case headerError:
+			err, decodeErr := unmarshalRemoteError(value)
+			if decodeErr != nil {
+				return h, 0, fmt.Errorf("got malformed error header: %w", decodeErr)
+			}
+
+			h.err = err
+		case headerStream:
+			if len(value) != 1 {
+				return h, 0, fmt.Errorf("got stream header of %d byte(s), but expected 1 byte", len(value))
+			}
+
+			h.stream = streamMarker(value[0])
+		case headerValue:
+			if len(value) < 1 || len(value) < 1+int(value[0]) {
+				return h, 0, io.ErrUnexpectedEOF
+			}
+
+			h.Set(string(value[1:1+value[0]]), string(value[1+value[0]:]))
+		}
+	}
+
+	return h, size, nil
+}
@@ -0,0 +1,203 @@
+package noise
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"testing"
+	"time"
+)
+
+func TestMessageHeaderRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	header := Header{
+		Trace:       TraceContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Flags: TraceFlagSampled},
+		Deadline:    time.Now().Add(time.Minute),
+		ContentType: "application/json",
+		Compression: "gzip",
+		Protocol:    "/chat/1.0.0",
+		Flags:       0x42,
+	}
+	header.Set("user", "alice")
+	header.Set("empty", "")
+
+	buf, err := message{nonce: 7, header: header, data: []byte("hello")}.marshal(nil)
+	assert.NoError(t, err)
+
+	msg, err := unmarshalMessage(buf)
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 7, msg.nonce)
+	assert.Equal(t, []byte("hello"), msg.data)
+	assert.WithinDuration(t, header.Deadline, msg.header.Deadline, time.Second)
+
+	msg.header.Deadline = header.Deadline
+	assert.Equal(t, header, msg.header)
+
+	// Deadlines are sent as the time remaining until them, and so are independent of the clock of the sender.
+
+	binary.BigEndian.PutUint64(buf[8+3+3+SizeTraceContext+3:], uint64(time.Hour))
+
+	msg, err = unmarshalMessage(buf)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), msg.header.Deadline, time.Second)
+
+	buf, err = message{nonce: 7, header: Header{Deadline: time.Now().Add(-time.Hour)}}.marshal(nil)
+	assert.NoError(t, err)
+
+	msg, err = unmarshalMessage(buf)
+	assert.NoError(t, err)
+	assert.False(t, msg.header.Deadline.After(time.Now()))
+
+	// Messages without headers incur no additional bytes, and control frames never carry headers.
+
+	buf, err = message{nonce: 7, data: []byte("hello")}.marshal(nil)
+	assert.NoError(t, err)
+	assert.Len(t, buf, 8+5)
+
+	buf, err = message{nonce: controlNonce, header: header}.marshal(nil)
+	assert.NoError(t, err)
+	assert.Len(t, buf, 8)
+
+	// Entries of unknown types are skipped.
+
+	buf, err = message{header: Header{Protocol: "/chat/1.0.0"}}.marshal(nil)
+	assert.NoError(t, err)
+
+	buf = append(buf, 0x7f, 0, 1, 0xaa)
+	buf[10] += 4
+
+	msg, err = unmarshalMessage(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, Header{Protocol: "/chat/1.0.0"}, msg.header)
+	assert.Empty(t, msg.data)
+
+	// Header blocks of an unknown version are rejected.
+
+	buf, err = message{header: Header{Flags: 1}}.marshal(nil)
+	assert.NoError(t, err)
+
+	buf[8] = envelopeVersion + 1
+
+	_, err = unmarshalMessage(buf)
+	assert.Error(t, err)
+
+	_, err = unmarshalMessage(buf[:9])
+	assert.Error(t, err)
+}
+
+func TestMessageHeaderOverflow(t *testing.T) {
+	t.Parallel()
+
+	// Headers that do not fit within a header block fail to be marshaled, rather than being dropped.
+
+	var header Header
+	header.Set("a", string(make([]byte, maxHeaderSize/2)))
+	header.Set("b", string(make([]byte, maxHeaderSize/2)))
+
+	buf, err := message{nonce: 7, header: header, data: []byte("hello")}.marshal([]byte("prefix"))
+	assert.Error(t, err)
+	assert.Equal(t, []byte("prefix"), buf)
+
+	delete(header.Values, "b")
+
+	buf, err = message{nonce: 7, header: header}.marshal(nil)
+	assert.NoError(t, err)
+
+	msg, err := unmarshalMessage(buf)
+	assert.NoError(t, err)
+	assert.Len(t, msg.header.Get("a"), maxHeaderSize/2)
+
+	header = Header{}
+	header.Set(string(make([]byte, 256)), "")
+
+	_, err = message{nonce: 7, header: header}.marshal(nil)
+	assert.Error(t, err)
+}
+
+func TestHandshakeExtension(t *testing.T) {
+	t.Parallel()
+
+	ext, err := unmarshalHandshakeExtension(nil)
+	assert.NoError(t, err)
+	assert.Zero(t, ext.envelope)
+
+	buf := handshakeExtension{envelope: envelopeVersion, control: true}.marshal(nil)
+	buf = append(buf, 0x7f, 0, 2, 0xaa, 0xbb)
+
+	ext, err = unmarshalHandshakeExtension(buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, envelopeVersion, ext.envelope)
+	assert.True(t, ext.control)
+
+	_, err = unmarshalHandshakeExtension(buf[:len(buf)-1])
+	assert.Error(t, err)
+
+	_, err = unmarshalHandshakeExtension([]byte("garbage"))
+	assert.Error(t, err)
+}
+
+func TestHeadersPropagated(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	headers := make(chan Header, 2)
+
+	b.Handle(func(ctx HandlerContext) error {
+		headers <- ctx.Header()
+
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	var header Header
+	header.ContentType = "text/plain"
+	header.Set("key", "value")
+
+	ctx := ContextWithHeader(context.Background(), header)
+	ctx = ContextWithHeader(ctx, Header{Protocol: "/echo/1.0.0"})
+
+	res, err := a.Request(ctx, b.Addr(), []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), res)
+
+	got := <-headers
+	assert.Equal(t, "text/plain", got.ContentType)
+	assert.Equal(t, "/echo/1.0.0", got.Protocol)
+	assert.Equal(t, "value", got.Get("key"))
+
+	// Requests whose headers do not fit within a header block fail to be sent.
+
+	var large Header
+	large.Set("key", string(make([]byte, maxHeaderSize)))
+
+	_, err = a.Request(ContextWithHeader(context.Background(), large), b.Addr(), []byte("hello"))
+	assert.Error(t, err)
+
+	client := a.Outbound()[0]
+	assert.EqualValues(t, envelopeVersion, client.EnvelopeVersion())
+
+	// Headers are dropped for peers that did not negotiate an envelope version.
+
+	client.envelope = 0
+
+	assert.NoError(t, a.Send(ctx, b.Addr(), []byte("hello")))
+	assert.True(t, (<-headers).IsZero())
+}
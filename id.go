@@ -1,40 +1,306 @@
 package noise
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// ID represents a peer ID. It comprises of a cryptographic public key, and a public, reachable network address
-// specified by a IPv4/IPv6 host and 16-bit port number. The size of an ID in terms of its byte representation
-// is static, with its contents being deterministic.
+// AddressType denotes the kind of network address a PeerAddress represents.
+type AddressType byte
+
+const (
+	// AddressIPv4 is an IPv4 host and port.
+	AddressIPv4 AddressType = iota + 1
+
+	// AddressIPv6 is an IPv6 host and port.
+	AddressIPv6
+
+	// AddressDNS is a DNS hostname and port.
+	AddressDNS
+
+	// AddressUnix is the path to a Unix domain socket.
+	AddressUnix
+)
+
+// String returns a human-readable representation of this address type.
+func (t AddressType) String() string {
+	switch t {
+	case AddressIPv4:
+		return "ipv4"
+	case AddressIPv6:
+		return "ipv6"
+	case AddressDNS:
+		return "dns"
+	case AddressUnix:
+		return "unix"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(t))
+	}
+}
+
+// PeerAddress represents a single typed network address a peer may be reached at.
+type PeerAddress struct {
+	// Type of this address.
+	Type AddressType
+
+	// Host of this address should it be of type AddressIPv4 or AddressIPv6.
+	Host net.IP
+
+	// Name of this address should it be of type AddressDNS (a hostname), or of type AddressUnix (a socket path).
+	Name string
+
+	// Port of this address should it not be of type AddressUnix.
+	Port uint16
+}
+
+// NewIPAddress returns an address of type AddressIPv4 or AddressIPv6 depending on the family of host. A nil host is
+// treated as being the unspecified IPv6 address.
+func NewIPAddress(host net.IP, port uint16) PeerAddress {
+	if ip := host.To4(); ip != nil {
+		return PeerAddress{Type: AddressIPv4, Host: ip, Port: port}
+	}
+
+	if host == nil {
+		host = net.IPv6unspecified
+	}
+
+	return PeerAddress{Type: AddressIPv6, Host: host.To16(), Port: port}
+}
+
+// NewDNSAddress returns an address of type AddressDNS.
+func NewDNSAddress(name string, port uint16) PeerAddress {
+	return PeerAddress{Type: AddressDNS, Name: name, Port: port}
+}
+
+// NewUnixAddress returns an address of type AddressUnix.
+func NewUnixAddress(path string) PeerAddress {
+	return PeerAddress{Type: AddressUnix, Name: path}
+}
+
+// Network returns the name of the network this address may be dialed over, which is either "tcp" or "unix". It
+// returns an empty string should this address be of a type unknown to this version of noise.
+func (a PeerAddress) Network() string {
+	switch a.Type {
+	case AddressIPv4, AddressIPv6, AddressDNS:
+		return "tcp"
+	case AddressUnix:
+		return "unix"
+	default:
+		return ""
+	}
+}
+
+// Equal returns true if this address and other are of the same type, and point to the same host, name, and port.
+func (a PeerAddress) Equal(other PeerAddress) bool {
+	return a.Type == other.Type && a.Host.Equal(other.Host) && a.Name == other.Name && a.Port == other.Port
+}
+
+// String returns this address formatted as 'host:port', or as a socket path should it be of type AddressUnix.
+func (a PeerAddress) String() string {
+	switch a.Type {
+	case AddressIPv4, AddressIPv6:
+		return net.JoinHostPort(normalizeIP(a.Host), strconv.FormatUint(uint64(a.Port), 10))
+	case AddressDNS:
+		return net.JoinHostPort(a.Name, strconv.FormatUint(uint64(a.Port), 10))
+	default:
+		return a.Name
+	}
+}
+
+func (a PeerAddress) marshal() ([]byte, bool) {
+	var buf []byte
+
+	switch a.Type {
+	case AddressIPv4:
+		ip := a.Host.To4()
+		if ip == nil {
+			return nil, false
+		}
+
+		buf = append(buf, ip...)
+	case AddressIPv6:
+		ip := a.Host.To16()
+		if ip == nil {
+			return nil, false
+		}
+
+		buf = append(buf, ip...)
+	case AddressDNS, AddressUnix:
+		if len(a.Name) == 0 || len(a.Name) > 255-2 {
+			return nil, false
+		}
+
+		buf = append(buf, a.Name...)
+	default:
+		if a.Type == 0 || len(a.Name) > 255 {
+			return nil, false
+		}
+
+		return append([]byte{byte(a.Type), byte(len(a.Name))}, a.Name...), true
+	}
+
+	if a.Type == AddressIPv4 || a.Type == AddressIPv6 || a.Type == AddressDNS {
+		buf = append(buf, 0, 0)
+		binary.BigEndian.PutUint16(buf[len(buf)-2:], a.Port)
+	}
+
+	return append([]byte{byte(a.Type), byte(len(buf))}, buf...), true
+}
+
+// unmarshalPeerAddress decodes a single address from buf, returning the number of bytes read. The payload of an
+// address of an unknown type is kept as-is in its Name so that the address may be re-encoded.
+func unmarshalPeerAddress(buf []byte) (addr PeerAddress, n int, err error) {
+	if len(buf) < 2 || len(buf) < 2+int(buf[1]) {
+		return addr, 0, io.ErrUnexpectedEOF
+	}
+
+	addr.Type, n = AddressType(buf[0]), 2+int(buf[1])
+	payload := buf[2:n]
+
+	switch addr.Type {
+	case AddressIPv4, AddressIPv6:
+		size := net.IPv4len
+		if addr.Type == AddressIPv6 {
+			size = net.IPv6len
+		}
+
+		if len(payload) != size+2 {
+			return addr, 0, fmt.Errorf("got %s address of %d byte(s), but expected %d byte(s): %w",
+				addr.Type, len(payload), size+2, io.ErrUnexpectedEOF,
+			)
+		}
+
+		addr.Host = append(net.IP{}, payload[:size]...)
+		addr.Port = binary.BigEndian.Uint16(payload[size:])
+	case AddressDNS:
+		if len(payload) < 3 {
+			return addr, 0, fmt.Errorf("got dns address of %d byte(s): %w", len(payload), io.ErrUnexpectedEOF)
+		}
+
+		addr.Name = string(payload[:len(payload)-2])
+		addr.Port = binary.BigEndian.Uint16(payload[len(payload)-2:])
+	case AddressUnix:
+		if len(payload) < 1 {
+			return addr, 0, fmt.Errorf("got empty unix address: %w", io.ErrUnexpectedEOF)
+		}
+
+		addr.Name = string(payload)
+	default:
+		addr.Name = string(payload)
+	}
+
+	return addr, n, nil
+}
+
+const (
+	// recordVersion is the version of the peer record format produced by (ID).Marshal.
+	recordVersion = 2
+
+	// recordHeaderSize is the number of bytes comprising the magic, version, and length prefix of a peer record.
+	recordHeaderSize = len(recordMagic) + 1 + 4
+
+	// legacyIDSize is the number of bytes an ID encoded in the legacy fixed-size format comprises of.
+	legacyIDSize = SizePublicKey + net.IPv6len + 2
+)
+
+// recordMagic prefixes all peer records so that they may be told apart from IDs encoded in the legacy format.
+var recordMagic = [3]byte{0xff, 'n', 'r'}
+
+// ID represents a peer ID, otherwise referred to as a peer record. It comprises of a cryptographic public key, a
+// list of typed addresses the bearer of the ID may be reached at, a sequence number, and optional key/value metadata.
+//
+// The public key of an ID may be of any key type registered via RegisterKeyType. An ID whose Key is nil bears the
+// Ed25519 public key ID.
+//
+// The first IP or DNS address of an ID is its primary address, which is mirrored by Host, Port, and Address for
+// convenience.
+//
+// An ID may not be compared using ==, as it holds slices and maps. IDs are instead compared via (ID).Equal.
 type ID struct {
-	// The Ed25519 public key of the bearer of this ID.
+	// The identifier of the public key of the bearer of this ID, which is the public key itself should it be an
+	// Ed25519 key. See (IdentityKey).ID.
 	ID PublicKey `json:"public_key"`
 
-	// Public host of the bearer of this ID.
+	// Key is the public key of the bearer of this ID should it not be an Ed25519 key, in which case ID must be the
+	// identifier of Key.
+	Key IdentityKey `json:"key,omitempty"`
+
+	// Public host of the primary address of the bearer of this ID.
 	Host net.IP `json:"address"`
 
-	// Public port of the bearer of this ID.
+	// Public port of the primary address of the bearer of this ID.
 	Port uint16
 
-	// 'host:port'
+	// 'host:port' of the primary address of the bearer of this ID.
 	Address string
+
+	// All addresses the bearer of this ID may be reached at, in order of preference.
+	Addrs []PeerAddress
+
+	// Seq is the sequence number of this ID. An ID with a larger sequence number supersedes an ID of the same public
+	// key with a smaller sequence number.
+	Seq uint64
+
+	// Metadata is optional, application-specific key/value metadata about the bearer of this ID.
+	Metadata map[string]string
+
+	// Expiry is the time after which this ID is no longer valid. A zero value denotes that this ID never expires.
+	Expiry time.Time
+
+	// Signature is the signature of this ID signed by the private key of its public key. A zero value denotes that
+	// this ID is unsigned. See (*ID).Sign and (ID).Verify.
+	Signature Signature
 }
 
 // NewID instantiates a new, immutable cryptographic user ID.
 func NewID(id PublicKey, host net.IP, port uint16) ID {
 	addr := net.JoinHostPort(normalizeIP(host), strconv.FormatUint(uint64(port), 10))
-	return ID{ID: id, Host: host, Port: port, Address: addr}
+	return ID{ID: id, Host: host, Port: port, Address: addr, Addrs: []PeerAddress{NewIPAddress(host, port)}}
 }
 
-// Size returns the number of bytes this ID comprises of.
+// NewIDWithAddresses instantiates a new ID whose bearer may be reached at any one of addrs, with the first IP or DNS
+// address in addrs being its primary address.
+func NewIDWithAddresses(id PublicKey, addrs ...PeerAddress) ID {
+	e := ID{ID: id, Addrs: addrs}
+
+	for _, addr := range addrs {
+		switch addr.Type {
+		case AddressIPv4, AddressIPv6:
+			e.Host, e.Port = addr.Host, addr.Port
+		case AddressDNS:
+			e.Port = addr.Port
+		default:
+			continue
+		}
+
+		e.Address = addr.String()
+
+		break
+	}
+
+	return e
+}
+
+// IdentityKey returns the public key of the bearer of this ID.
+func (e ID) IdentityKey() IdentityKey {
+	if e.Key != nil {
+		return e.Key
+	}
+
+	return e.ID
+}
+
+// Size returns the number of bytes this ID comprises of once marshaled.
 func (e ID) Size() int {
-	return len(e.ID) + net.IPv6len + 2
+	return len(e.Marshal())
 }
 
 // String returns a JSON representation of this ID.
@@ -48,20 +314,381 @@ func (e ID) String() string {
 	return builder.String()
 }
 
-// Marshal serializes this ID into its byte representation.
+// Marshal serializes this ID into a versioned, length-prefixed peer record. The format of a record is:
+//
+//	magic      [3]byte  0xff 'n' 'r'
+//	version    uint8    2
+//	length     uint32   number of bytes that follow
+//	key        [type uint8][length uint8][public key]
+//	seq        uint64
+//	addrs      uint8 count, followed by every address as [type uint8][length uint8][payload]
+//	metadata   uint8 count, followed by every entry as [key length uint8][key][value length uint16][value]
+//	expiry     int64    unix timestamp in seconds, or zero should the record never expire
+//	signature  uint8 flag marking whether the record is signed, followed by a [64]byte signature if it is
+//
+// All integers are big-endian. The key type is one of the key types registered via RegisterKeyType, and the ID of
+// a record is derived from its key. Records of version 1 instead start with a [32]byte Ed25519 public key. The
+// payload of an IP address is its 4 or 16 byte host followed by its port, of a DNS address is its hostname followed
+// by its port, and of a Unix address is its path. Metadata is sorted by key. The signature covers the record as it
+// would be encoded should it not be signed, prefixed by '.__noise_record'.
+//
+// Addresses that are malformed, and metadata whose key or value is too long, are omitted. Only the first 255
+// addresses and metadata entries are kept.
+//
+// Peers that predate peer records are unable to decode a record. During the handshake, a node sends its ID encoded
+// in the legacy fixed-size format instead, and only sends its peer record to peers that advertise support for it.
 func (e ID) Marshal() []byte {
-	buf := make([]byte, e.Size())
+	key := e.IdentityKey()
+	raw := key.Bytes()
+
+	buf := make([]byte, recordHeaderSize, recordHeaderSize+2+len(raw)+8+2)
 
-	copy(buf[:len(e.ID)], e.ID[:])
-	copy(buf[len(e.ID):len(e.ID)+net.IPv6len], e.Host)
-	binary.BigEndian.PutUint16(buf[len(e.ID)+net.IPv6len:len(e.ID)+net.IPv6len+2], e.Port)
+	copy(buf, recordMagic[:])
+	buf[len(recordMagic)] = recordVersion
+
+	buf = append(buf, byte(key.Type()), byte(len(raw)))
+	buf = append(buf, raw...)
+	buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], e.Seq)
+
+	addrs := e.Addrs
+	if addrs == nil && e.Address != "" {
+		addrs = []PeerAddress{NewIPAddress(e.Host, e.Port)}
+	}
+
+	count := len(buf)
+	buf = append(buf, 0)
+
+	for _, addr := range addrs {
+		if buf[count] == 255 {
+			break
+		}
+
+		if encoded, ok := addr.marshal(); ok {
+			buf = append(buf, encoded...)
+			buf[count]++
+		}
+	}
+
+	keys := make([]string, 0, len(e.Metadata))
+	for key, value := range e.Metadata {
+		if len(key) > 0 && len(key) <= 255 && len(value) <= 65535 {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	if len(keys) > 255 {
+		keys = keys[:255]
+	}
+
+	buf = append(buf, byte(len(keys)))
+
+	for _, key := range keys {
+		value := e.Metadata[key]
+
+		buf = append(buf, byte(len(key)))
+		buf = append(buf, key...)
+		buf = append(buf, byte(len(value)>>8), byte(len(value)))
+		buf = append(buf, value...)
+	}
+
+	var expiry int64
+
+	if !e.Expiry.IsZero() {
+		expiry = e.Expiry.Unix()
+	}
+
+	buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], uint64(expiry))
+
+	if e.Signature == ZeroSignature {
+		buf = append(buf, 0)
+	} else {
+		buf = append(buf, 1)
+		buf = append(buf, e.Signature[:]...)
+	}
+
+	binary.BigEndian.PutUint32(buf[len(recordMagic)+1:recordHeaderSize], uint32(len(buf)-recordHeaderSize))
 
 	return buf
 }
 
-// UnmarshalID deserializes buf, representing a slice of bytes, ID instance. It throws io.ErrUnexpectedEOF if the
-// contents of buf is malformed.
+// recordSignaturePrefix is prepended to the encoding of a record before it is signed or verified.
+const recordSignaturePrefix = ".__noise_record"
+
+func (e ID) signingPayload() []byte {
+	e.Signature = ZeroSignature
+	return append([]byte(recordSignaturePrefix), e.Marshal()...)
+}
+
+// Sign signs this ID with signer, whose public key must be the public key of this ID. The expiry of this ID is
+// truncated to the second, as it is encoded to the second. It returns an error should signer fail to sign this ID.
+func (e *ID) Sign(signer Signer) error {
+	if !e.Expiry.IsZero() {
+		e.Expiry = time.Unix(e.Expiry.Unix(), 0)
+	}
+
+	signature, err := signer.Sign(e.signingPayload())
+	if err != nil {
+		return fmt.Errorf("failed to sign peer record: %w", err)
+	}
+
+	e.Signature = signature
+
+	return nil
+}
+
+// Verify checks that this ID is signed by the private key of its public key, and that it has not expired. It returns
+// ErrRecordUnsigned, ErrRecordSignatureInvalid, or ErrRecordExpired otherwise.
+func (e ID) Verify() error {
+	if e.Signature == ZeroSignature {
+		return ErrRecordUnsigned
+	}
+
+	key := e.IdentityKey()
+
+	if key.ID() != e.ID {
+		return fmt.Errorf("record is identified by %s, but its %s key is identified by %s: %w",
+			e.ID, key.Type(), key.ID(), ErrRecordSignatureInvalid,
+		)
+	}
+
+	if !key.Verify(e.signingPayload(), e.Signature) {
+		return ErrRecordSignatureInvalid
+	}
+
+	if !e.Expiry.IsZero() && time.Now().After(e.Expiry) {
+		return fmt.Errorf("record expired at %s: %w", e.Expiry, ErrRecordExpired)
+	}
+
+	return nil
+}
+
+// Equal returns true if this ID and other bear the same public key, addresses, sequence number, metadata, expiry, and
+// signature.
+func (e ID) Equal(other ID) bool {
+	if e.ID != other.ID || e.Seq != other.Seq || e.Signature != other.Signature || !e.Expiry.Equal(other.Expiry) {
+		return false
+	}
+
+	if !e.Host.Equal(other.Host) || e.Port != other.Port || e.Address != other.Address {
+		return false
+	}
+
+	if (e.Key == nil) != (other.Key == nil) {
+		return false
+	}
+
+	if e.Key != nil && (e.Key.Type() != other.Key.Type() || !bytes.Equal(e.Key.Bytes(), other.Key.Bytes())) {
+		return false
+	}
+
+	if len(e.Addrs) != len(other.Addrs) || len(e.Metadata) != len(other.Metadata) {
+		return false
+	}
+
+	for i := range e.Addrs {
+		if !e.Addrs[i].Equal(other.Addrs[i]) {
+			return false
+		}
+	}
+
+	for key, value := range e.Metadata {
+		if otherValue, exists := other.Metadata[key]; !exists || value != otherValue {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Supersedes returns true if this ID and other share the same public key, and this ID has a larger sequence number
+// than other.
+func (e ID) Supersedes(other ID) bool {
+	return e.ID == other.ID && e.Seq > other.Seq
+}
+
+// UnmarshalID deserializes buf, representing a slice of bytes, ID instance. buf may either be a peer record produced
+// by (ID).Marshal, or an ID encoded in the legacy fixed-size format comprised of a 32-byte public key, 16-byte host,
+// and 2-byte port. Any bytes in buf past the end of the ID are ignored. It throws io.ErrUnexpectedEOF if the contents
+// of buf is malformed.
 func UnmarshalID(buf []byte) (ID, error) {
+	id, _, err := unmarshalIDPrefix(buf)
+	return id, err
+}
+
+// unmarshalIDPrefix decodes an ID from the head of buf, returning the number of bytes read.
+func unmarshalIDPrefix(buf []byte) (ID, int, error) {
+	id, n, err := unmarshalRecord(buf)
+	if err == nil {
+		return id, n, nil
+	}
+
+	// A legacy ID may only be mistaken for a record should its public key happen to start with the record magic.
+
+	if len(buf) != legacyIDSize && bytes.HasPrefix(buf, recordMagic[:]) {
+		return ID{}, 0, err
+	}
+
+	id, err = unmarshalLegacyID(buf)
+	if err != nil {
+		return ID{}, 0, err
+	}
+
+	return id, legacyIDSize, nil
+}
+
+// unmarshalRecord decodes a peer record from buf, returning the number of bytes read.
+func unmarshalRecord(buf []byte) (ID, int, error) {
+	if len(buf) < recordHeaderSize || !bytes.Equal(buf[:len(recordMagic)], recordMagic[:]) {
+		return ID{}, 0, io.ErrUnexpectedEOF
+	}
+
+	version := buf[len(recordMagic)]
+	if version != 1 && version != recordVersion {
+		return ID{}, 0, fmt.Errorf("got peer record of unsupported version %d", version)
+	}
+
+	size := binary.BigEndian.Uint32(buf[len(recordMagic)+1 : recordHeaderSize])
+	if uint64(len(buf)-recordHeaderSize) < uint64(size) {
+		return ID{}, 0, io.ErrUnexpectedEOF
+	}
+
+	total := recordHeaderSize + int(size)
+	buf = buf[recordHeaderSize:total]
+
+	var key IdentityKey
+
+	if version == 1 {
+		if len(buf) < SizePublicKey {
+			return ID{}, 0, io.ErrUnexpectedEOF
+		}
+
+		var id PublicKey
+		copy(id[:], buf[:SizePublicKey])
+
+		key, buf = id, buf[SizePublicKey:]
+	} else {
+		if len(buf) < 2 || len(buf) < 2+int(buf[1]) {
+			return ID{}, 0, io.ErrUnexpectedEOF
+		}
+
+		var err error
+
+		if key, err = UnmarshalIdentityKey(KeyType(buf[0]), buf[2:2+buf[1]]); err != nil {
+			return ID{}, 0, fmt.Errorf("failed to decode key of peer record: %w", err)
+		}
+
+		buf = buf[2+int(buf[1]):]
+	}
+
+	if len(buf) < 8+1 {
+		return ID{}, 0, io.ErrUnexpectedEOF
+	}
+
+	seq := binary.BigEndian.Uint64(buf[:8])
+	buf = buf[8:]
+
+	count := int(buf[0])
+	buf = buf[1:]
+
+	addrs := make([]PeerAddress, 0, count)
+
+	for i := 0; i < count; i++ {
+		addr, n, err := unmarshalPeerAddress(buf)
+		if err != nil {
+			return ID{}, 0, err
+		}
+
+		addrs = append(addrs, addr)
+		buf = buf[n:]
+	}
+
+	if len(buf) < 1 {
+		return ID{}, 0, io.ErrUnexpectedEOF
+	}
+
+	count = int(buf[0])
+	buf = buf[1:]
+
+	var metadata map[string]string
+
+	if count > 0 {
+		metadata = make(map[string]string, count)
+	}
+
+	for i := 0; i < count; i++ {
+		if len(buf) < 1 || len(buf) < 1+int(buf[0])+2 {
+			return ID{}, 0, io.ErrUnexpectedEOF
+		}
+
+		key := string(buf[1 : 1+buf[0]])
+		buf = buf[1+int(buf[0]):]
+
+		size := int(binary.BigEndian.Uint16(buf[:2]))
+		buf = buf[2:]
+
+		if len(buf) < size {
+			return ID{}, 0, io.ErrUnexpectedEOF
+		}
+
+		metadata[key] = string(buf[:size])
+		buf = buf[size:]
+	}
+
+	if len(buf) < 8+1 {
+		return ID{}, 0, io.ErrUnexpectedEOF
+	}
+
+	var expiry time.Time
+
+	if unix := int64(binary.BigEndian.Uint64(buf[:8])); unix != 0 {
+		expiry = time.Unix(unix, 0)
+	}
+
+	signed := buf[8] != 0
+	buf = buf[8+1:]
+
+	var signature Signature
+
+	if signed {
+		if len(buf) < SizeSignature {
+			return ID{}, 0, io.ErrUnexpectedEOF
+		}
+
+		copy(signature[:], buf[:SizeSignature])
+	}
+
+	// Any remaining bytes are fields introduced by newer peers, and are ignored.
+
+	e := NewIDWithAddresses(key.ID(), addrs...)
+	e.Seq = seq
+	e.Metadata = metadata
+	e.Expiry = expiry
+	e.Signature = signature
+
+	if key.Type() != KeyTypeEd25519 {
+		e.Key = key
+	}
+
+	return e, total, nil
+}
+
+// marshalLegacy serializes the public key and primary address of this ID into the legacy fixed-size format comprised
+// of a 32-byte public key, 16-byte host, and 2-byte port, which peers that predate peer records expect.
+func (e ID) marshalLegacy() []byte {
+	buf := make([]byte, legacyIDSize)
+
+	copy(buf[:SizePublicKey], e.ID[:])
+	copy(buf[SizePublicKey:SizePublicKey+net.IPv6len], e.Host.To16())
+	binary.BigEndian.PutUint16(buf[SizePublicKey+net.IPv6len:], e.Port)
+
+	return buf
+}
+
+func unmarshalLegacyID(buf []byte) (ID, error) {
 	if len(buf) < SizePublicKey {
 		return ID{}, io.ErrUnexpectedEOF
 	}
@@ -1,6 +1,7 @@
 package noise_test
 
 import (
+	"errors"
 	"fmt"
 	"github.com/perlin-network/noise"
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"testing"
 	"testing/quick"
+	"time"
 )
 
 func TestID_String(t *testing.T) {
@@ -57,3 +59,155 @@ func TestUnmarshalID(t *testing.T) {
 	_, err = noise.UnmarshalID(append(noise.ZeroPublicKey[:], append(net.IPv6loopback, 1, 2)...))
 	assert.NoError(t, err)
 }
+
+func TestIDRecordRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	f := func(publicKey noise.PublicKey, host [net.IPv6len]byte, port uint16, seq uint64, name, key, value string) bool {
+		if len(name) == 0 || len(name) > 200 || len(key) == 0 || len(key) > 255 {
+			return true
+		}
+
+		id := noise.NewIDWithAddresses(publicKey,
+			noise.NewIPAddress(host[:], port),
+			noise.NewIPAddress(net.IPv4(1, 2, 3, 4), port),
+			noise.NewDNSAddress(name, port),
+			noise.NewUnixAddress(name),
+		)
+		id.Seq = seq
+		id.Metadata = map[string]string{key: value}
+
+		buf := id.Marshal()
+
+		if !assert.Len(t, buf, id.Size()) {
+			return false
+		}
+
+		decoded, err := noise.UnmarshalID(append(buf, 1, 2, 3))
+		if !assert.NoError(t, err) {
+			return false
+		}
+
+		return assert.Equal(t, id.ID, decoded.ID) &&
+			assert.Equal(t, id.Address, decoded.Address) &&
+			assert.Equal(t, id.Seq, decoded.Seq) &&
+			assert.Equal(t, id.Metadata, decoded.Metadata) &&
+			assert.Len(t, decoded.Addrs, 4) &&
+			assert.Equal(t, noise.AddressDNS, decoded.Addrs[2].Type) &&
+			assert.Equal(t, name, decoded.Addrs[2].Name) &&
+			assert.Equal(t, "unix", decoded.Addrs[3].Network())
+	}
+
+	assert.NoError(t, quick.Check(f, nil))
+}
+
+func TestIDEqual(t *testing.T) {
+	t.Parallel()
+
+	pub, priv, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	id := noise.NewIDWithAddresses(pub,
+		noise.NewIPAddress(net.IPv4(1, 2, 3, 4), 3000),
+		noise.NewDNSAddress("peer.test", 3000),
+	)
+	id.Seq = 1
+	id.Metadata = map[string]string{"role": "relay"}
+	id.Expiry = time.Now().Add(1 * time.Hour)
+
+	assert.NoError(t, id.Sign(noise.NewSigner(priv)))
+
+	decoded, err := noise.UnmarshalID(id.Marshal())
+	assert.NoError(t, err)
+	assert.True(t, id.Equal(decoded))
+	assert.True(t, decoded.Equal(id))
+
+	for _, modify := range []func(id *noise.ID){
+		func(id *noise.ID) { id.Seq++ },
+		func(id *noise.ID) { id.Addrs = id.Addrs[:1] },
+		func(id *noise.ID) {
+			id.Addrs = []noise.PeerAddress{id.Addrs[0], noise.NewDNSAddress("peer.test", 3001)}
+		},
+		func(id *noise.ID) { id.Metadata = map[string]string{"role": "peer"} },
+		func(id *noise.ID) { id.Expiry = id.Expiry.Add(time.Second) },
+		func(id *noise.ID) { id.Signature = noise.ZeroSignature },
+	} {
+		other := decoded
+		modify(&other)
+
+		assert.False(t, id.Equal(other))
+	}
+}
+
+func TestUnmarshalIDLegacy(t *testing.T) {
+	t.Parallel()
+
+	publicKey, _, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	buf := append(publicKey[:], net.ParseIP("1.2.3.4").To16()...)
+	buf = append(buf, 0x0b, 0xb8)
+
+	id, err := noise.UnmarshalID(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, id.ID)
+	assert.Equal(t, "1.2.3.4:3000", id.Address)
+	assert.Len(t, id.Addrs, 1)
+	assert.Equal(t, noise.AddressIPv4, id.Addrs[0].Type)
+
+	// A legacy ID is re-encoded as a peer record.
+
+	decoded, err := noise.UnmarshalID(id.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, id.Address, decoded.Address)
+}
+
+func TestIDSignAndVerify(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	id := noise.NewID(publicKey, net.ParseIP("1.2.3.4"), 3000)
+	assert.True(t, errors.Is(id.Verify(), noise.ErrRecordUnsigned))
+
+	id.Seq = 1
+	id.Expiry = time.Now().Add(time.Hour)
+	assert.NoError(t, id.Sign(noise.NewSigner(privateKey)))
+
+	assert.NoError(t, id.Verify())
+
+	// Signatures survive being marshaled.
+
+	decoded, err := noise.UnmarshalID(id.Marshal())
+	assert.NoError(t, err)
+	assert.NoError(t, decoded.Verify())
+	assert.True(t, decoded.Expiry.Equal(id.Expiry))
+
+	// Pointing the public key at a different address invalidates the signature.
+
+	forged := decoded
+	forged.Addrs = []noise.PeerAddress{noise.NewIPAddress(net.ParseIP("5.6.7.8"), 3000)}
+	assert.True(t, errors.Is(forged.Verify(), noise.ErrRecordSignatureInvalid))
+
+	// Signing with a different private key invalidates the signature.
+
+	_, other, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	forged = decoded
+	assert.NoError(t, forged.Sign(noise.NewSigner(other)))
+	assert.True(t, errors.Is(forged.Verify(), noise.ErrRecordSignatureInvalid))
+
+	expired := noise.NewID(publicKey, net.ParseIP("1.2.3.4"), 3000)
+	expired.Expiry = time.Now().Add(-time.Second)
+	assert.NoError(t, expired.Sign(noise.NewSigner(privateKey)))
+	assert.True(t, errors.Is(expired.Verify(), noise.ErrRecordExpired))
+
+	newer := id
+	newer.Seq++
+	assert.NoError(t, newer.Sign(noise.NewSigner(privateKey)))
+
+	assert.True(t, newer.Supersedes(id))
+	assert.False(t, id.Supersedes(newer))
+}
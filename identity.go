@@ -0,0 +1,131 @@
+package noise
+
+import (
+	"fmt"
+	"sync"
+)
+
+// KeyType tags the kind of cryptographic key the identity of a node is comprised of.
+type KeyType byte
+
+const (
+	// KeyTypeEd25519 denotes an Ed25519 identity key, which is the default.
+	KeyTypeEd25519 KeyType = iota + 1
+
+	// KeyTypeSecp256k1 denotes a secp256k1 identity key which signs data with ECDSA over SHA-256.
+	KeyTypeSecp256k1
+)
+
+// String returns a human-readable representation of this key type.
+func (t KeyType) String() string {
+	switch t {
+	case KeyTypeEd25519:
+		return "ed25519"
+	case KeyTypeSecp256k1:
+		return "secp256k1"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(t))
+	}
+}
+
+// IdentityKey is the public key of the identity of a node, which may be of any one of the key types registered via
+// RegisterKeyType.
+//
+// Every identity key maps to a 32-byte PublicKey which identifies the bearer of the key throughout noise (i.e. as
+// (ID).ID). The identifier of an Ed25519 key is the key itself. The identifier of any other key type is the SHA-256
+// hash of its encoding.
+type IdentityKey interface {
+	// Type returns the type of this key.
+	Type() KeyType
+
+	// Bytes returns the encoding of this key.
+	Bytes() []byte
+
+	// ID returns the 32-byte identifier of this key.
+	ID() PublicKey
+
+	// Verify returns true if signature is a signature of data signed by the private key of this key.
+	Verify(data []byte, signature Signature) bool
+}
+
+// KeyedSigner is a Signer which signs data with a private key that is not an Ed25519 key. Public must return the
+// identifier of the key returned by Key.
+type KeyedSigner interface {
+	Signer
+
+	// Key returns the public key of the private key data is signed with.
+	Key() IdentityKey
+}
+
+var _ IdentityKey = PublicKey{}
+
+// Type implements IdentityKey, and returns KeyTypeEd25519.
+func (k PublicKey) Type() KeyType {
+	return KeyTypeEd25519
+}
+
+// Bytes implements IdentityKey, and returns this public key as a slice of bytes.
+func (k PublicKey) Bytes() []byte {
+	return append([]byte{}, k[:]...)
+}
+
+// ID implements IdentityKey, and returns this public key as-is.
+func (k PublicKey) ID() PublicKey {
+	return k
+}
+
+var (
+	keyTypes     = map[KeyType]func([]byte) (IdentityKey, error){}
+	keyTypesLock sync.RWMutex
+)
+
+func init() {
+	RegisterKeyType(KeyTypeEd25519, func(buf []byte) (IdentityKey, error) {
+		if len(buf) != SizePublicKey {
+			return nil, fmt.Errorf("got ed25519 key of %d byte(s), but expected %d byte(s)", len(buf), SizePublicKey)
+		}
+
+		var key PublicKey
+		copy(key[:], buf)
+
+		return key, nil
+	})
+
+	RegisterKeyType(KeyTypeSecp256k1, func(buf []byte) (IdentityKey, error) {
+		return UnmarshalSecp256k1PublicKey(buf)
+	})
+}
+
+// RegisterKeyType registers a decoder for identity keys of type typ, replacing any decoder previously registered
+// for typ. It allows for nodes to be identified by keys of types other than those noise provides out of the box.
+//
+// RegisterKeyType may be called concurrently.
+func RegisterKeyType(typ KeyType, decode func(buf []byte) (IdentityKey, error)) {
+	keyTypesLock.Lock()
+	defer keyTypesLock.Unlock()
+
+	keyTypes[typ] = decode
+}
+
+// UnmarshalIdentityKey decodes buf into an identity key of type typ. It returns an error should typ not be
+// registered, or should buf be malformed.
+func UnmarshalIdentityKey(typ KeyType, buf []byte) (IdentityKey, error) {
+	keyTypesLock.RLock()
+	decode, exists := keyTypes[typ]
+	keyTypesLock.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrKeyTypeUnsupported, typ)
+	}
+
+	return decode(buf)
+}
+
+// signerKey returns the identity key of the private key signer signs data with.
+func signerKey(signer Signer) IdentityKey {
+	if keyed, ok := signer.(KeyedSigner); ok {
+		return keyed.Key()
+	}
+
+	return signer.Public()
+}
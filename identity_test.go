@@ -0,0 +1,160 @@
+package noise_test
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"net"
+	"testing"
+)
+
+func TestSecp256k1PublicKeyDerivation(t *testing.T) {
+	t.Parallel()
+
+	vectors := map[byte]string{
+		1: "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		2: "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+		3: "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+	}
+
+	for scalar, expected := range vectors {
+		var buf [noise.SizeSecp256k1PrivateKey]byte
+		buf[len(buf)-1] = scalar
+
+		privateKey, err := noise.UnmarshalSecp256k1PrivateKey(buf[:])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, privateKey.Public().String())
+
+		decoded, err := hex.DecodeString(expected)
+		assert.NoError(t, err)
+
+		publicKey, err := noise.UnmarshalSecp256k1PublicKey(decoded)
+		assert.NoError(t, err)
+		assert.Equal(t, privateKey.Public(), publicKey)
+	}
+
+	_, err := noise.UnmarshalSecp256k1PrivateKey(make([]byte, noise.SizeSecp256k1PrivateKey))
+	assert.Error(t, err)
+
+	_, err = noise.UnmarshalSecp256k1PublicKey(make([]byte, noise.SizeSecp256k1PublicKey))
+	assert.Error(t, err)
+}
+
+func TestSecp256k1SignAndVerify(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := noise.GenerateSecp256k1Key(nil)
+	assert.NoError(t, err)
+
+	publicKey := privateKey.Public()
+
+	signature := privateKey.Sign([]byte("data"))
+	assert.True(t, publicKey.Verify([]byte("data"), signature))
+	assert.False(t, publicKey.Verify([]byte("other data"), signature))
+
+	tampered := signature
+	tampered[10] ^= 1
+	assert.False(t, publicKey.Verify([]byte("data"), tampered))
+
+	other, err := noise.GenerateSecp256k1Key(nil)
+	assert.NoError(t, err)
+	assert.False(t, other.Public().Verify([]byte("data"), signature))
+
+	// The identifier of a secp256k1 key is the hash of its encoding, and it round-trips through its key type.
+
+	key, err := noise.UnmarshalIdentityKey(noise.KeyTypeSecp256k1, publicKey.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey.ID(), key.ID())
+	assert.NotEqual(t, noise.ZeroPublicKey, key.ID())
+
+	_, err = noise.UnmarshalIdentityKey(noise.KeyType(255), publicKey.Bytes())
+	assert.True(t, errors.Is(err, noise.ErrKeyTypeUnsupported))
+}
+
+func TestSecp256k1SignRFC6979(t *testing.T) {
+	t.Parallel()
+
+	var buf [noise.SizeSecp256k1PrivateKey]byte
+	buf[len(buf)-1] = 1
+
+	privateKey, err := noise.UnmarshalSecp256k1PrivateKey(buf[:])
+	assert.NoError(t, err)
+
+	// Nonces are derived per RFC 6979, and so signatures are deterministic.
+
+	signature := privateKey.Sign([]byte("Satoshi Nakamoto"))
+	assert.Equal(t, signature, privateKey.Sign([]byte("Satoshi Nakamoto")))
+
+	assert.Equal(t,
+		"934b1ea10a4b3c1757e2b0c017d0b6143ce3c9a7e6a4a49860d7a6ab210ee3d8"+
+			"2442ce9d2b916064108014783e923ec36b49743e2ffa1c4496f01a512aafd9e5",
+		hex.EncodeToString(signature[:]),
+	)
+}
+
+func TestIDRecordWithSecp256k1Key(t *testing.T) {
+	t.Parallel()
+
+	privateKey, err := noise.GenerateSecp256k1Key(nil)
+	assert.NoError(t, err)
+
+	signer := noise.NewSecp256k1Signer(privateKey)
+
+	id := noise.NewIDWithAddresses(signer.Public(), noise.NewIPAddress(net.IPv4(1, 2, 3, 4), 3000))
+	id.Key = signer.Key()
+	id.Seq = 1
+
+	assert.NoError(t, id.Sign(signer))
+	assert.NoError(t, id.Verify())
+
+	decoded, err := noise.UnmarshalID(id.Marshal())
+	assert.NoError(t, err)
+	assert.Equal(t, id.ID, decoded.ID)
+	assert.Equal(t, signer.Key(), decoded.Key)
+	assert.NoError(t, decoded.Verify())
+
+	// An ID whose identifier does not match its key fails verification.
+
+	mismatched := id
+	mismatched.ID = privateKey.Public().ID()
+	mismatched.ID[0] ^= 1
+
+	assert.True(t, errors.Is(mismatched.Verify(), noise.ErrRecordSignatureInvalid))
+}
+
+func TestNodesWithDifferentKeyTypes(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	privateKey, err := noise.GenerateSecp256k1Key(nil)
+	assert.NoError(t, err)
+
+	a, err := noise.NewNode(noise.WithNodeSigner(noise.NewSecp256k1Signer(privateKey)))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	assert.Equal(t, privateKey.Public().ID(), a.ID().ID)
+	assert.Equal(t, noise.IdentityKey(privateKey.Public()), a.ID().IdentityKey())
+	assert.NoError(t, a.ID().Verify())
+
+	client, err := b.Ping(context.Background(), a.Addr())
+	assert.NoError(t, err)
+	assert.Equal(t, a.ID().ID, client.ID().ID)
+	assert.Equal(t, noise.KeyTypeSecp256k1, client.ID().IdentityKey().Type())
+
+	client, err = a.Ping(context.Background(), b.Addr())
+	assert.NoError(t, err)
+	assert.Equal(t, b.ID().ID, client.ID().ID)
+	assert.Equal(t, noise.KeyTypeEd25519, client.ID().IdentityKey().Type())
+}
@@ -2,6 +2,7 @@ package kademlia
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"github.com/perlin-network/noise"
 	"math/bits"
 	"sort"
@@ -53,10 +54,24 @@ func PrefixLen(a []byte) int {
 	return len(a) * 8
 }
 
-// SortByDistance sorts ids by descending XOR distance with respect to id.
+// Key returns the position of id within the keyspace XOR distances are computed over, which is the SHA-256 hash of
+// id. Hashing ids places peers whose identity keys are of different types and lengths uniformly within one keyspace.
+func Key(id noise.PublicKey) []byte {
+	key := sha256.Sum256(id[:])
+	return key[:]
+}
+
+// SortByDistance sorts ids by descending XOR distance of their keys with respect to the key of id.
 func SortByDistance(id noise.PublicKey, ids []noise.ID) []noise.ID {
+	target := Key(id)
+
+	distances := make(map[noise.PublicKey][]byte, len(ids))
+	for _, e := range ids {
+		distances[e.ID] = XOR(Key(e.ID), target)
+	}
+
 	sort.Slice(ids, func(i, j int) bool {
-		return bytes.Compare(XOR(ids[i].ID[:], id[:]), XOR(ids[j].ID[:], id[:])) == -1
+		return bytes.Compare(distances[ids[i].ID], distances[ids[j].ID]) == -1
 	})
 
 	return ids
@@ -16,8 +16,11 @@ type Iterator struct {
 	node   *noise.Node
 	table  *Table
 	logger *zap.Logger
+	ctx    context.Context
 
 	visited map[noise.PublicKey]struct{}
+	addrs   map[noise.PublicKey][]string
+	seen    map[noise.PublicKey]noise.ID
 	results chan noise.ID
 	buckets [][]noise.ID
 
@@ -35,6 +38,7 @@ func NewIterator(node *noise.Node, table *Table, opts ...IteratorOption) *Iterat
 		node:   node,
 		table:  table,
 		logger: node.Logger(),
+		ctx:    context.Background(),
 
 		maxNumResults:                BucketSize,
 		numParallelLookups:           3,
@@ -79,10 +83,14 @@ func (it *Iterator) init(target noise.PublicKey) {
 		target:          {},
 	}
 
+	it.addrs = make(map[noise.PublicKey][]string)
+	it.seen = make(map[noise.PublicKey]noise.ID)
+
 	it.buckets = make([][]noise.ID, it.numParallelLookups)
 
 	for i, id := range it.table.Peers() {
 		it.visited[id.ID] = struct{}{}
+		it.recordAddress(id)
 		it.buckets[i%it.numParallelLookups] = append(it.buckets[i%it.numParallelLookups], id)
 	}
 }
@@ -137,8 +145,7 @@ func (it *Iterator) processLookupBucket(i int) {
 
 		it.Lock()
 		for _, id := range ids {
-			if _, visited := it.visited[id.ID]; !visited {
-				it.visited[id.ID] = struct{}{}
+			if it.observe(id) {
 				it.buckets[i] = append(it.buckets[i], id)
 			}
 		}
@@ -152,17 +159,90 @@ func (it *Iterator) processLookupBucket(i int) {
 	close(results)
 }
 
+// observe records id as having been reported by a peer, and returns true should the peer with id's public key not
+// have been visited yet. It must be called with the lock held.
+func (it *Iterator) observe(id noise.ID) bool {
+	// Ignore IDs that were not signed by their own private key, so that peers may not point public keys at addresses
+	// of their choosing, and IDs superseded by IDs that have already been seen.
+
+	if err := id.Verify(); err != nil {
+		return false
+	}
+
+	seen, exists := it.seen[id.ID]
+	if exists && seen.Supersedes(id) {
+		return false
+	}
+
+	// Addresses of a record superseded by id are no longer candidate addresses of the peer.
+
+	if exists && id.Supersedes(seen) {
+		delete(it.addrs, id.ID)
+	}
+
+	it.seen[id.ID] = id
+	it.recordAddress(id)
+
+	if _, visited := it.visited[id.ID]; visited {
+		return false
+	}
+
+	it.visited[id.ID] = struct{}{}
+
+	return true
+}
+
 func (it *Iterator) processLookupRequests(in <-chan noise.ID, out chan<- []noise.ID) {
 	for id := range in {
 		it.lookupRequest(id, out)
 	}
 }
 
+// recordAddress records all TCP addresses of id as being candidate addresses for the peer with id's public key. Peers
+// may be reported by different peers under different addresses, i.e. through both an IPv4 and IPv6 address, which are
+// all kept until a newer record of the peer is seen. It must be called with the lock held.
+func (it *Iterator) recordAddress(id noise.ID) {
+	candidates := []string{id.Address}
+
+	for _, addr := range id.Addrs {
+		if addr.Network() == "tcp" {
+			candidates = append(candidates, addr.String())
+		}
+	}
+
+Candidates:
+	for _, candidate := range candidates {
+		for _, addr := range it.addrs[id.ID] {
+			if addr == candidate {
+				continue Candidates
+			}
+		}
+
+		it.addrs[id.ID] = append(it.addrs[id.ID], candidate)
+	}
+}
+
 func (it *Iterator) lookupRequest(id noise.ID, out chan<- []noise.ID) {
-	ctx, cancel := context.WithTimeout(context.Background(), it.lookupTimeout)
+	ctx, cancel := context.WithTimeout(it.ctx, it.lookupTimeout)
 	defer cancel()
 
-	obj, err := it.node.RequestMessage(ctx, id.Address, FindNodeRequest{Target: id.ID})
+	it.Lock()
+	addrs := append([]string(nil), it.addrs[id.ID]...)
+	it.Unlock()
+
+	if len(addrs) == 0 {
+		addrs = []string{id.Address}
+	}
+
+	// Race all candidate addresses of the peer should there not be a live connection to the peer yet.
+
+	client, err := it.node.PingAny(ctx, addrs...)
+	if err != nil {
+		out <- nil
+		return
+	}
+
+	obj, err := it.node.RequestMessage(ctx, client.Addr(), FindNodeRequest{Target: id.ID})
 	if err != nil {
 		out <- nil
 		return
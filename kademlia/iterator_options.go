@@ -1,6 +1,7 @@
 package kademlia
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"time"
 )
@@ -41,6 +42,15 @@ func WithIteratorNumParallelRequestsPerLookup(numParallelRequestsPerLookup int)
 	}
 }
 
+// WithIteratorContext sets the context lookup requests are derived from, such that lookups are cancelled should ctx
+// be cancelled, and such that lookup requests carry any trace context ctx carries (see noise.ContextWithTrace). By
+// default, it is set to context.Background().
+func WithIteratorContext(ctx context.Context) IteratorOption {
+	return func(it *Iterator) {
+		it.ctx = ctx
+	}
+}
+
 // WithIteratorLookupTimeout sets the max duration to wait until we declare a lookup request sent in amidst
 // a single disjoint lookup to have timed out. By default, it is set to 3 seconds.
 func WithIteratorLookupTimeout(lookupTimeout time.Duration) IteratorOption {
@@ -0,0 +1,47 @@
+package kademlia
+
+import (
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"net"
+	"testing"
+)
+
+func TestIteratorReplacesSupersededAddresses(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	assert.NoError(t, node.Listen())
+
+	defer node.Close()
+
+	it := NewIterator(node, NewTable(node.ID()))
+	it.init(noise.PublicKey{})
+
+	pub, priv, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	record := func(seq uint64, host string) noise.ID {
+		id := noise.NewID(pub, net.ParseIP(host), 3000)
+		id.Seq = seq
+		assert.NoError(t, id.Sign(noise.NewSigner(priv)))
+
+		return id
+	}
+
+	assert.True(t, it.observe(record(1, "1.2.3.4")))
+	assert.False(t, it.observe(record(1, "1.2.3.4")))
+	assert.Equal(t, []string{"1.2.3.4:3000"}, it.addrs[pub])
+
+	// Records that are superseded by a record that has already been seen are ignored.
+
+	assert.False(t, it.observe(record(2, "5.6.7.8")))
+	assert.Equal(t, []string{"5.6.7.8:3000"}, it.addrs[pub])
+
+	assert.False(t, it.observe(record(1, "1.2.3.4")))
+	assert.Equal(t, []string{"5.6.7.8:3000"}, it.addrs[pub])
+	assert.Equal(t, uint64(2), it.seen[pub].Seq)
+}
@@ -1,9 +1,11 @@
 package kademlia
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/perlin-network/noise"
 	"io"
+	"net"
 )
 
 // Ping represents an empty ping message.
@@ -57,20 +59,39 @@ type FindNodeResponse struct {
 	Results []noise.ID
 }
 
-// Marshal implements noise.Serializable and encodes the list of closest peer ID results into a byte representative
-// of the length of the list, concatenated with the serialized byte representation of the peer IDs themselves.
+// findNodeResponseVersion prefixes every FindNodeResponse that encodes its results as peer records. Peers that
+// predate peer records respond with at most BucketSize IDs in the legacy fixed-size format, prefixed by the number
+// of IDs, such that their responses never start with findNodeResponseVersion.
+const findNodeResponseVersion = 0xff
+
+// legacyIDSize is the number of bytes an ID encoded in the legacy fixed-size format comprises of.
+const legacyIDSize = noise.SizePublicKey + net.IPv6len + 2
+
+// Marshal implements noise.Serializable and encodes the list of closest peer ID results into a version byte, followed
+// by a byte representative of the length of the list, concatenated with the serialized peer records of the peer IDs
+// themselves, each prefixed by their length as a big-endian uint32. Only the first 255 results are encoded.
 func (r FindNodeResponse) Marshal() []byte {
-	buf := []byte{byte(len(r.Results))}
+	results := r.Results
+	if len(results) > 255 {
+		results = results[:255]
+	}
+
+	buf := []byte{findNodeResponseVersion, byte(len(results))}
+
+	for _, result := range results {
+		record := result.Marshal()
 
-	for _, result := range r.Results {
-		buf = append(buf, result.Marshal()...)
+		buf = append(buf, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(len(record)))
+		buf = append(buf, record...)
 	}
 
 	return buf
 }
 
 // UnmarshalFindNodeResponse decodes buf, which is expected to encode a list of closest peer ID results, into a
-// FindNodeResponse. It throws an io.ErrUnexpectedEOF if buf is malformed.
+// FindNodeResponse. Responses from peers that encode IDs in the legacy fixed-size format are supported as well. It
+// throws an io.ErrUnexpectedEOF if buf is malformed.
 func UnmarshalFindNodeResponse(buf []byte) (FindNodeResponse, error) {
 	var res FindNodeResponse
 
@@ -78,22 +99,69 @@ func UnmarshalFindNodeResponse(buf []byte) (FindNodeResponse, error) {
 		return res, io.ErrUnexpectedEOF
 	}
 
-	size := buf[0]
-	buf = buf[1:]
+	if buf[0] != findNodeResponseVersion {
+		results, err := unmarshalLegacyResults(buf[1:], int(buf[0]))
+		if err != nil {
+			return res, err
+		}
+
+		res.Results = results
+
+		return res, nil
+	}
+
+	if len(buf) < 2 {
+		return res, io.ErrUnexpectedEOF
+	}
+
+	size := int(buf[1])
+	buf = buf[2:]
 
 	results := make([]noise.ID, 0, size)
 
-	for i := 0; i < cap(results); i++ {
-		id, err := noise.UnmarshalID(buf)
+	for i := 0; i < size; i++ {
+		if len(buf) < 4 {
+			return res, io.ErrUnexpectedEOF
+		}
+
+		length := binary.BigEndian.Uint32(buf[:4])
+		buf = buf[4:]
+
+		if uint64(len(buf)) < uint64(length) {
+			return res, io.ErrUnexpectedEOF
+		}
+
+		id, err := noise.UnmarshalID(buf[:length])
 		if err != nil {
 			return res, io.ErrUnexpectedEOF
 		}
 
 		results = append(results, id)
-		buf = buf[id.Size():]
+		buf = buf[length:]
 	}
 
 	res.Results = results
 
 	return res, nil
 }
+
+func unmarshalLegacyResults(buf []byte, size int) ([]noise.ID, error) {
+	if len(buf) != size*legacyIDSize {
+		return nil, fmt.Errorf("expected %d legacy IDs to be %d bytes, but got %d bytes: %w",
+			size, size*legacyIDSize, len(buf), io.ErrUnexpectedEOF,
+		)
+	}
+
+	results := make([]noise.ID, 0, size)
+
+	for i := 0; i < size; i++ {
+		id, err := noise.UnmarshalID(buf[i*legacyIDSize : (i+1)*legacyIDSize])
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, id)
+	}
+
+	return results, nil
+}
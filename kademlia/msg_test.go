@@ -0,0 +1,75 @@
+package kademlia
+
+import (
+	"encoding/binary"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+)
+
+func TestFindNodeResponseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var results []noise.ID
+
+	for i := 0; i < BucketSize; i++ {
+		pub, _, err := noise.GenerateKeys(nil)
+		assert.NoError(t, err)
+
+		id := noise.NewIDWithAddresses(pub, noise.NewIPAddress(net.IPv4(1, 2, 3, byte(i)), 3000))
+		id.Seq = uint64(i)
+
+		results = append(results, id)
+	}
+
+	buf := FindNodeResponse{Results: results}.Marshal()
+
+	res, err := UnmarshalFindNodeResponse(buf)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.Results, len(results)) {
+		for i := range results {
+			assert.True(t, results[i].Equal(res.Results[i]))
+		}
+	}
+
+	_, err = UnmarshalFindNodeResponse(buf[:len(buf)-1])
+	assert.Error(t, err)
+
+	res, err = UnmarshalFindNodeResponse(FindNodeResponse{}.Marshal())
+	assert.NoError(t, err)
+	assert.Empty(t, res.Results)
+}
+
+func TestFindNodeResponseLegacy(t *testing.T) {
+	t.Parallel()
+
+	// Peers that predate peer records respond with IDs in the legacy fixed-size format.
+
+	buf := []byte{2}
+
+	for i := 0; i < 2; i++ {
+		pub, _, err := noise.GenerateKeys(nil)
+		assert.NoError(t, err)
+
+		buf = append(buf, pub[:]...)
+		buf = append(buf, net.IPv4(1, 2, 3, byte(i)).To16()...)
+		buf = append(buf, 0, 0)
+		binary.BigEndian.PutUint16(buf[len(buf)-2:], 3000)
+	}
+
+	res, err := UnmarshalFindNodeResponse(buf)
+	assert.NoError(t, err)
+
+	if assert.Len(t, res.Results, 2) {
+		assert.Equal(t, "1.2.3.1:3000", res.Results[1].Address)
+	}
+
+	_, err = UnmarshalFindNodeResponse(buf[:len(buf)-1])
+	assert.Error(t, err)
+
+	res, err = UnmarshalFindNodeResponse([]byte{0})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Results)
+}
@@ -28,6 +28,13 @@ type Protocol struct {
 	events Events
 
 	pingTimeout time.Duration
+
+	metrics struct {
+		admitted       *noise.Counter
+		evicted        *noise.Counter
+		lookups        *noise.Counter
+		lookupDuration *noise.Histogram
+	}
 }
 
 // New returns a new instance of the Kademlia protocol.
@@ -46,6 +53,10 @@ func New(opts ...ProtocolOption) *Protocol {
 // Find executes the FIND_NODE S/Kademlia RPC call to find the closest peers to some given target public key. It
 // returns the IDs of the closest peers it finds.
 func (p *Protocol) Find(target noise.PublicKey, opts ...IteratorOption) []noise.ID {
+	defer p.metrics.lookupDuration.ObserveSince(time.Now())
+
+	p.metrics.lookups.Inc()
+
 	return NewIterator(p.node, p.table, opts...).Find(target)
 }
 
@@ -79,7 +90,20 @@ func (p *Protocol) Table() *Table {
 // Ack attempts to insert a peer ID into your nodes routing table. If the routing table bucket in which your peer ID
 // was expected to be inserted on is full, the peer ID at the tail of the bucket is pinged. If the ping fails, the
 // peer ID at the tail of the bucket is evicted and your peer ID is inserted to the head of the bucket.
+//
+// The peer ID must be signed by its own private key and not have expired, otherwise it is ignored. Should the peer
+// ID already exist in the routing table, it replaces the existing entry only if its sequence number is not smaller.
 func (p *Protocol) Ack(id noise.ID) {
+	if err := id.Verify(); err != nil {
+		p.logger.Debug("Peer was not inserted into routing table as its record failed to be verified.",
+			zap.String("peer_id", id.String()),
+			zap.String("peer_addr", id.Address),
+			zap.Error(err),
+		)
+
+		return
+	}
+
 	for {
 		inserted, err := p.table.Update(id)
 		if err == nil {
@@ -91,6 +115,8 @@ func (p *Protocol) Ack(id noise.ID) {
 			}
 
 			if inserted {
+				p.metrics.admitted.Inc()
+
 				if p.events.OnPeerAdmitted != nil {
 					p.events.OnPeerAdmitted(id)
 				}
@@ -117,6 +143,8 @@ func (p *Protocol) Ack(id noise.ID) {
 					zap.Error(err),
 				)
 
+				p.metrics.evicted.Inc()
+
 				if p.events.OnPeerEvicted != nil {
 					p.events.OnPeerEvicted(id)
 				}
@@ -132,6 +160,8 @@ func (p *Protocol) Ack(id noise.ID) {
 					zap.Error(err),
 				)
 
+				p.metrics.evicted.Inc()
+
 				if p.events.OnPeerEvicted != nil {
 					p.events.OnPeerEvicted(id)
 				}
@@ -163,8 +193,8 @@ func (p *Protocol) Protocol() noise.Protocol {
 	}
 }
 
-// Bind registers messages Ping, Pong, FindNodeRequest, FindNodeResponse, and handles them by registering the
-// (*Protocol).Handle Handler.
+// Bind registers messages Ping, Pong, FindNodeRequest, FindNodeResponse, and routes Ping and FindNodeRequest
+// messages to their handlers via (*noise.Node).HandleMessage.
 func (p *Protocol) Bind(node *noise.Node) error {
 	p.node = node
 	p.table = NewTable(p.node.ID())
@@ -173,12 +203,30 @@ func (p *Protocol) Bind(node *noise.Node) error {
 		p.logger = p.node.Logger()
 	}
 
+	metrics := node.Metrics()
+
+	p.metrics.admitted = metrics.Counter("noise_kademlia_peers_admitted_total",
+		"Number of peers inserted into the routing table.")
+	p.metrics.evicted = metrics.Counter("noise_kademlia_peers_evicted_total",
+		"Number of peers evicted from the routing table.")
+	p.metrics.lookups = metrics.Counter("noise_kademlia_lookups_total",
+		"Number of FIND_NODE lookups performed.")
+	p.metrics.lookupDuration = metrics.Histogram("noise_kademlia_lookup_duration_seconds",
+		"Latency of FIND_NODE lookups.")
+
+	table := p.table
+
+	metrics.GaugeFunc("noise_kademlia_table_entries", "Number of peers in the routing table.", func() float64 {
+		return float64(table.NumEntries())
+	})
+
 	node.RegisterMessage(Ping{}, UnmarshalPing)
 	node.RegisterMessage(Pong{}, UnmarshalPong)
 	node.RegisterMessage(FindNodeRequest{}, UnmarshalFindNodeRequest)
 	node.RegisterMessage(FindNodeResponse{}, UnmarshalFindNodeResponse)
 
-	node.Handle(p.Handle)
+	node.HandleMessage(Ping{}, p.handlePing)
+	node.HandleMessage(FindNodeRequest{}, p.handleFindNodeRequest)
 
 	return nil
 }
@@ -194,6 +242,8 @@ func (p *Protocol) OnPingFailed(addr string, err error) {
 	if id, deleted := p.table.DeleteByAddress(addr); deleted {
 		p.logger.Debug("Peer was evicted from routing table by failing to be dialed.", zap.Error(err))
 
+		p.metrics.evicted.Inc()
+
 		if p.events.OnPeerEvicted != nil {
 			p.events.OnPeerEvicted(id)
 		}
@@ -212,7 +262,8 @@ func (p *Protocol) OnMessageRecv(client *noise.Client) {
 	p.Ack(client.ID())
 }
 
-// Handle implements noise.Protocol and handles Ping and FindNodeRequest messages.
+// Handle implements noise.Handler and handles Ping and FindNodeRequest messages. Bind routes both messages to their
+// handlers directly, such that Handle need only be registered to nodes whose messages are not routed.
 func (p *Protocol) Handle(ctx noise.HandlerContext) error {
 	msg, err := ctx.DecodeMessage()
 	if err != nil {
@@ -221,16 +272,26 @@ func (p *Protocol) Handle(ctx noise.HandlerContext) error {
 
 	switch msg := msg.(type) {
 	case Ping:
-		if !ctx.IsRequest() {
-			return errors.New("got a ping that was not sent as a request")
-		}
-		return ctx.SendMessage(Pong{})
+		return p.handlePing(ctx, msg)
 	case FindNodeRequest:
-		if !ctx.IsRequest() {
-			return errors.New("got a find node request that was not sent as a request")
-		}
-		return ctx.SendMessage(FindNodeResponse{Results: p.table.FindClosest(msg.Target, BucketSize)})
+		return p.handleFindNodeRequest(ctx, msg)
 	}
 
 	return nil
 }
+
+func (p *Protocol) handlePing(ctx noise.HandlerContext, _ Ping) error {
+	if !ctx.IsRequest() {
+		return errors.New("got a ping that was not sent as a request")
+	}
+
+	return ctx.SendMessage(Pong{})
+}
+
+func (p *Protocol) handleFindNodeRequest(ctx noise.HandlerContext, msg FindNodeRequest) error {
+	if !ctx.IsRequest() {
+		return errors.New("got a find node request that was not sent as a request")
+	}
+
+	return ctx.SendMessage(FindNodeResponse{Results: p.table.FindClosest(msg.Target, BucketSize)})
+}
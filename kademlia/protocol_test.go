@@ -2,11 +2,14 @@ package kademlia_test
 
 import (
 	"context"
+	"encoding/binary"
 	"github.com/perlin-network/noise"
 	"github.com/perlin-network/noise/kademlia"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/goleak"
+	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -21,7 +24,7 @@ func merge(clients ...[]*noise.Client) []*noise.Client {
 }
 
 func getBucketIndex(self, target noise.PublicKey) int {
-	l := kademlia.PrefixLen(kademlia.XOR(target[:], self[:]))
+	l := kademlia.PrefixLen(kademlia.XOR(kademlia.Key(target), kademlia.Key(self)))
 	if l == noise.SizePublicKey*8 {
 		return l - 1
 	}
@@ -29,6 +32,28 @@ func getBucketIndex(self, target noise.PublicKey) int {
 	return l
 }
 
+func TestLegacyOpcodes(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := noise.NewNode(noise.WithNodeLegacyOpcodes(true))
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	node.Bind(kademlia.New().Protocol())
+	assert.NoError(t, node.Listen())
+
+	// Messages are registered under the same opcodes peers that predate opcode negotiation register them under.
+
+	for expected, msg := range []noise.Serializable{
+		kademlia.Ping{}, kademlia.Pong{}, kademlia.FindNodeRequest{}, kademlia.FindNodeResponse{},
+	} {
+		data, err := node.EncodeMessage(msg)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, binary.BigEndian.Uint16(data[:2]))
+	}
+}
+
 func TestTableEviction(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -186,4 +211,87 @@ func TestDiscoveryAcrossThreeNodes(t *testing.T) {
 	assert.Len(t, ka.Discover(), 2)
 	assert.Len(t, kb.Discover(), 2)
 	assert.Len(t, kc.Discover(), 2)
+
+	stats := a.Stats()
+	assert.EqualValues(t, 1, stats.Counters["noise_kademlia_lookups_total"])
+	assert.EqualValues(t, 1, stats.Histograms["noise_kademlia_lookup_duration_seconds"].Count)
+	assert.EqualValues(t, ka.Table().NumEntries(), stats.Gauges["noise_kademlia_table_entries"])
+}
+
+func TestMessagesPassThroughMiddlewareAndInterceptors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var handled, intercepted int32
+
+	a, err := noise.NewNode(
+		noise.WithNodeMiddleware(func(next noise.Handler) noise.Handler {
+			return func(ctx noise.HandlerContext) error {
+				atomic.AddInt32(&handled, 1)
+				return next(ctx)
+			}
+		}),
+		noise.WithNodeInterceptors(func(ctx context.Context, call *noise.Call, invoke noise.Invoker) error {
+			atomic.AddInt32(&intercepted, 1)
+			return invoke(ctx, call)
+		}),
+	)
+	assert.NoError(t, err)
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+	defer b.Close()
+
+	ka := kademlia.New()
+	a.Bind(ka.Protocol())
+
+	kb := kademlia.New()
+	b.Bind(kb.Protocol())
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// Kademlia messages sent and received by a pass through the middleware and interceptors registered to a.
+
+	assert.NoError(t, kb.Ping(context.TODO(), a.Addr()))
+	assert.NotZero(t, atomic.LoadInt32(&handled))
+
+	assert.Len(t, ka.Discover(), 1)
+	assert.NotZero(t, atomic.LoadInt32(&intercepted))
+}
+
+func TestAckIgnoresUnverifiedRecords(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+	defer a.Close()
+
+	ka := kademlia.New()
+	a.Bind(ka.Protocol())
+
+	assert.NoError(t, a.Listen())
+
+	pub, priv, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	id := noise.NewID(pub, net.ParseIP("1.2.3.4"), 3000)
+
+	ka.Ack(id)
+	assert.False(t, ka.Table().Recorded(pub))
+
+	// An ID signed by a private key other than its own is ignored.
+
+	_, other, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	assert.NoError(t, id.Sign(noise.NewSigner(other)))
+
+	ka.Ack(id)
+	assert.False(t, ka.Table().Recorded(pub))
+
+	assert.NoError(t, id.Sign(noise.NewSigner(priv)))
+
+	ka.Ack(id)
+	assert.True(t, ka.Table().Recorded(pub))
 }
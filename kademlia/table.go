@@ -13,13 +13,14 @@ type Table struct {
 
 	entries [noise.SizePublicKey * 8]*list.List
 	self    noise.ID
+	selfKey []byte
 	size    int
 }
 
 // NewTable instantiates a new routing table whose XOR distance metric is defined with respect to some
-// given ID.
+// given ID. Distances are computed over the keys of IDs. See Key.
 func NewTable(self noise.ID) *Table {
-	table := &Table{self: self}
+	table := &Table{self: self, selfKey: Key(self.ID)}
 
 	for i := 0; i < len(table.entries); i++ {
 		table.entries[i] = list.New()
@@ -62,8 +63,9 @@ func (t *Table) Bucket(target noise.PublicKey) []noise.ID {
 
 // Update attempts to insert the target node/peer ID into this routing table. If the bucket it was expected
 // to be inserted within is full, ErrBucketFull is returned. If the ID already exists in its respective routing
-// table bucket, it is moved to the head of the bucket and false is returned. If the ID has yet to exist, it is
-// appended to the head of its intended bucket and true is returned.
+// table bucket, it is moved to the head of the bucket and false is returned, with the existing ID being replaced
+// by target should target not have a smaller sequence number. If the ID has yet to exist, it is appended to the
+// head of its intended bucket and true is returned.
 func (t *Table) Update(target noise.ID) (bool, error) {
 	if target.ID == noise.ZeroPublicKey {
 		return false, nil
@@ -75,7 +77,11 @@ func (t *Table) Update(target noise.ID) (bool, error) {
 	bucket := t.entries[t.getBucketIndex(target.ID)]
 
 	for e := bucket.Front(); e != nil; e = e.Next() {
-		if e.Value.(noise.ID).ID == target.ID { // Found the target ID already inside the routing table.
+		if existing := e.Value.(noise.ID); existing.ID == target.ID { // Found the target ID already inside the routing table.
+			if !existing.Supersedes(target) {
+				e.Value = target
+			}
+
 			bucket.MoveToFront(e)
 			return false, nil
 		}
@@ -220,7 +226,7 @@ func (t *Table) NumEntries() int {
 }
 
 func (t *Table) getBucketIndex(target noise.PublicKey) int {
-	l := PrefixLen(XOR(target[:], t.self.ID[:]))
+	l := PrefixLen(XOR(Key(target), t.selfKey))
 	if l == noise.SizePublicKey*8 {
 		return l - 1
 	}
@@ -59,3 +59,36 @@ func TestDistanceMetric(t *testing.T) {
 
 	assert.Len(t, seen, cap(ids))
 }
+
+func TestTableUpdateKeepsNewestRecord(t *testing.T) {
+	pub, priv, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	self, _, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	table := NewTable(noise.NewID(self, net.ParseIP("1.2.3.4"), 3000))
+
+	older := noise.NewID(pub, net.ParseIP("5.6.7.8"), 3000)
+	older.Seq = 1
+	assert.NoError(t, older.Sign(noise.NewSigner(priv)))
+
+	newer := noise.NewID(pub, net.ParseIP("9.10.11.12"), 3000)
+	newer.Seq = 2
+	assert.NoError(t, newer.Sign(noise.NewSigner(priv)))
+
+	inserted, err := table.Update(older)
+	assert.NoError(t, err)
+	assert.True(t, inserted)
+
+	inserted, err = table.Update(newer)
+	assert.NoError(t, err)
+	assert.False(t, inserted)
+	assert.Equal(t, newer.Address, table.Bucket(pub)[0].Address)
+
+	// A replayed older record does not supersede the newer record.
+
+	_, err = table.Update(older)
+	assert.NoError(t, err)
+	assert.Equal(t, newer.Address, table.Bucket(pub)[0].Address)
+}
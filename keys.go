@@ -93,6 +93,28 @@ func (k PublicKey) MarshalJSON() ([]byte, error) {
 	return json.Marshal(k.String())
 }
 
+// UnmarshalJSON decodes the hexadecimal representation of a public key in JSON into this public key.
+func (k *PublicKey) UnmarshalJSON(buf []byte) error {
+	var str string
+
+	if err := json.Unmarshal(buf, &str); err != nil {
+		return err
+	}
+
+	decoded, err := hex.DecodeString(str)
+	if err != nil {
+		return fmt.Errorf("public key provided in hex failed to be decoded: %w", err)
+	}
+
+	if len(decoded) != SizePublicKey {
+		return fmt.Errorf("public key provided in hex is %d byte(s), but expected %d byte(s)", len(decoded), SizePublicKey)
+	}
+
+	copy(k[:], decoded)
+
+	return nil
+}
+
 // Sign uses this private key to sign data and return its cryptographic signature as a slice of bytes.
 func (k PrivateKey) Sign(data []byte) Signature {
 	return UnmarshalSignature(ed25519.Sign(k[:], data))
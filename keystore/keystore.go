@@ -0,0 +1,379 @@
+// Package keystore saves and loads the private keys of noise nodes to/from passphrase-encrypted files, so that
+// private keys never have to be kept around in plaintext.
+//
+// A keystore file is a JSON document of the following format:
+//
+//	{
+//	  "version": 1,
+//	  "public_key": "<hex-encoded Ed25519 public key>",
+//	  "created_at": "<RFC 3339 timestamp>",
+//	  "crypto": {
+//	    "kdf": "scrypt",
+//	    "kdf_params": {"n": 262144, "r": 8, "p": 1, "salt": "<hex-encoded 32-byte salt>"},
+//	    "cipher": "aes-256-gcm",
+//	    "nonce": "<hex-encoded 12-byte nonce>",
+//	    "ciphertext": "<hex-encoded encrypted Ed25519 private key>"
+//	  }
+//	}
+//
+// A 32-byte key is derived from the passphrase and salt using scrypt with parameters n, r, and p. The Ed25519
+// private key is then encrypted with AES-256 in Galois Counter Mode (GCM) under the derived key and nonce, with the
+// raw bytes of the public key as additional authenticated data.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/perlin-network/noise"
+	"golang.org/x/crypto/scrypt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// Version is the version of the keystore file format written by this package.
+	Version = 1
+
+	kdfScrypt       = "scrypt"
+	cipherAES256GCM = "aes-256-gcm"
+
+	saltSize = 32
+	keySize  = 32
+
+	// maxScryptN, maxScryptR, and maxScryptP bound the scrypt parameters of a keystore file being decrypted, such
+	// that a crafted keystore file may not have a key derived with more than 1 GiB of memory or for an unbounded
+	// amount of time.
+	maxScryptN = 1 << 20
+	maxScryptR = 8
+	maxScryptP = 16
+)
+
+var (
+	// ErrDecrypt is returned when a keystore file fails to be decrypted, which is most likely due to an incorrect
+	// passphrase.
+	ErrDecrypt = errors.New("could not decrypt keystore: incorrect passphrase or corrupted file")
+
+	// ErrExists is returned when generating a new keystore file at a path that already has a file.
+	ErrExists = errors.New("keystore file already exists")
+)
+
+// ScryptParams are the scrypt parameters a key is derived from a passphrase with.
+type ScryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var (
+	// StandardScryptParams are the recommended scrypt parameters, which take roughly a second to derive a key with.
+	StandardScryptParams = ScryptParams{N: 1 << 18, R: 8, P: 1}
+
+	// LightScryptParams are scrypt parameters which are far cheaper to derive a key with than StandardScryptParams.
+	// They are only recommended for testing, or for devices with little memory.
+	LightScryptParams = ScryptParams{N: 1 << 12, R: 8, P: 6}
+)
+
+// validate returns an error should any of the scrypt parameters exceed their maxima.
+func (p ScryptParams) validate() error {
+	if p.N > maxScryptN || p.R > maxScryptR || p.P > maxScryptP {
+		return fmt.Errorf("keystore scrypt parameters n=%d, r=%d, p=%d exceed the maximum of n=%d, r=%d, p=%d",
+			p.N, p.R, p.P, maxScryptN, maxScryptR, maxScryptP,
+		)
+	}
+
+	return nil
+}
+
+// File is the JSON representation of a keystore file.
+type File struct {
+	Version   int             `json:"version"`
+	PublicKey noise.PublicKey `json:"public_key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Crypto    Crypto          `json:"crypto"`
+}
+
+// Crypto describes how the private key in a keystore file is encrypted.
+type Crypto struct {
+	KDF        string    `json:"kdf"`
+	KDFParams  KDFParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// KDFParams are the parameters of the key derivation function of a keystore file.
+type KDFParams struct {
+	ScryptParams
+	Salt string `json:"salt"`
+}
+
+// Option configures how a private key is encrypted.
+type Option func(p *ScryptParams)
+
+// WithScryptParams sets the scrypt parameters a key is derived from a passphrase with. By default, it is set to
+// StandardScryptParams.
+func WithScryptParams(params ScryptParams) Option {
+	return func(p *ScryptParams) {
+		*p = params
+	}
+}
+
+// Encrypt encrypts privateKey under passphrase, and returns the contents of a keystore file.
+func Encrypt(privateKey noise.PrivateKey, passphrase []byte, opts ...Option) ([]byte, error) {
+	params := StandardScryptParams
+
+	for _, opt := range opts {
+		opt(&params)
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	suite, err := newSuite(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, suite.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	publicKey := privateKey.Public()
+
+	file := File{
+		Version:   Version,
+		PublicKey: publicKey,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Crypto: Crypto{
+			KDF:        kdfScrypt,
+			KDFParams:  KDFParams{ScryptParams: params, Salt: hex.EncodeToString(salt)},
+			Cipher:     cipherAES256GCM,
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(suite.Seal(nil, nonce, privateKey[:], publicKey[:])),
+		},
+	}
+
+	return json.MarshalIndent(file, "", "  ")
+}
+
+// Decrypt decrypts the contents of a keystore file under passphrase, and returns the private key it holds. It
+// returns ErrDecrypt should the passphrase be incorrect, and an error should the scrypt parameters of the keystore
+// file exceed n = 2^20, r = 8, or p = 16.
+func Decrypt(buf []byte, passphrase []byte) (noise.PrivateKey, error) {
+	var file File
+
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("failed to parse keystore: %w", err)
+	}
+
+	if file.Version != Version {
+		return noise.ZeroPrivateKey, fmt.Errorf("keystore is of unsupported version %d", file.Version)
+	}
+
+	if file.Crypto.KDF != kdfScrypt {
+		return noise.ZeroPrivateKey, fmt.Errorf("keystore uses unsupported kdf %q", file.Crypto.KDF)
+	}
+
+	if file.Crypto.Cipher != cipherAES256GCM {
+		return noise.ZeroPrivateKey, fmt.Errorf("keystore uses unsupported cipher %q", file.Crypto.Cipher)
+	}
+
+	if err := file.Crypto.KDFParams.ScryptParams.validate(); err != nil {
+		return noise.ZeroPrivateKey, err
+	}
+
+	salt, err := hex.DecodeString(file.Crypto.KDFParams.Salt)
+	if err != nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("failed to decode keystore salt: %w", err)
+	}
+
+	nonce, err := hex.DecodeString(file.Crypto.Nonce)
+	if err != nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("failed to decode keystore nonce: %w", err)
+	}
+
+	ciphertext, err := hex.DecodeString(file.Crypto.Ciphertext)
+	if err != nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("failed to decode keystore ciphertext: %w", err)
+	}
+
+	suite, err := newSuite(passphrase, salt, file.Crypto.KDFParams.ScryptParams)
+	if err != nil {
+		return noise.ZeroPrivateKey, err
+	}
+
+	if len(nonce) != suite.NonceSize() {
+		return noise.ZeroPrivateKey, fmt.Errorf("keystore nonce is %d byte(s), but expected %d byte(s)",
+			len(nonce), suite.NonceSize(),
+		)
+	}
+
+	plaintext, err := suite.Open(nil, nonce, ciphertext, file.PublicKey[:])
+	if err != nil {
+		return noise.ZeroPrivateKey, ErrDecrypt
+	}
+
+	if len(plaintext) != noise.SizePrivateKey {
+		return noise.ZeroPrivateKey, fmt.Errorf("keystore private key is %d byte(s), but expected %d byte(s)",
+			len(plaintext), noise.SizePrivateKey,
+		)
+	}
+
+	var privateKey noise.PrivateKey
+	copy(privateKey[:], plaintext)
+
+	if privateKey.Public() != file.PublicKey {
+		return noise.ZeroPrivateKey, errors.New("keystore private key does not match its public key")
+	}
+
+	return privateKey, nil
+}
+
+// Save encrypts privateKey under passphrase, and atomically writes it as a keystore file to path that is only
+// readable and writable by its owner.
+func Save(path string, privateKey noise.PrivateKey, passphrase []byte, opts ...Option) error {
+	buf, err := Encrypt(privateKey, passphrase, opts...)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create keystore: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write keystore: %w", err)
+	}
+
+	return nil
+}
+
+// Load reads the keystore file at path, and decrypts it under passphrase. It returns ErrDecrypt should the
+// passphrase be incorrect.
+func Load(path string, passphrase []byte) (noise.PrivateKey, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	return Decrypt(buf, passphrase)
+}
+
+// Generate generates a new private key, and saves it encrypted under passphrase to a keystore file at path. It
+// returns ErrExists should there already be a file at path.
+func Generate(path string, passphrase []byte, opts ...Option) (noise.PrivateKey, error) {
+	if _, err := os.Stat(path); err == nil {
+		return noise.ZeroPrivateKey, fmt.Errorf("%s: %w", path, ErrExists)
+	}
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	if err != nil {
+		return noise.ZeroPrivateKey, err
+	}
+
+	if err := Save(path, privateKey, passphrase, opts...); err != nil {
+		return noise.ZeroPrivateKey, err
+	}
+
+	return privateKey, nil
+}
+
+// Rotate replaces the private key in the keystore file at path with a newly generated private key encrypted under
+// passphrase. The previous keystore file is kept, and moved to a path suffixed with the time it was rotated at so
+// that the previous private key may still be recovered. It returns the newly generated private key, and the path
+// the previous keystore file was moved to.
+func Rotate(path string, passphrase []byte, opts ...Option) (noise.PrivateKey, string, error) {
+	if _, err := Load(path, passphrase); err != nil {
+		return noise.ZeroPrivateKey, "", err
+	}
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	if err != nil {
+		return noise.ZeroPrivateKey, "", err
+	}
+
+	retired := path + "." + time.Now().UTC().Format("20060102T150405.000000000Z") + ".old"
+
+	if err := os.Link(path, retired); err != nil {
+		return noise.ZeroPrivateKey, "", fmt.Errorf("failed to keep previous keystore: %w", err)
+	}
+
+	if err := Save(path, privateKey, passphrase, opts...); err != nil {
+		return noise.ZeroPrivateKey, "", err
+	}
+
+	return privateKey, retired, nil
+}
+
+// ChangePassphrase re-encrypts the private key in the keystore file at path from under passphrase to under
+// newPassphrase.
+func ChangePassphrase(path string, passphrase, newPassphrase []byte, opts ...Option) error {
+	privateKey, err := Load(path, passphrase)
+	if err != nil {
+		return err
+	}
+
+	return Save(path, privateKey, newPassphrase, opts...)
+}
+
+// WithNodeKeystore loads the private key in the keystore file at path encrypted under passphrase, and returns a
+// noise.NodeOption which sets it as the private key of a node.
+func WithNodeKeystore(path string, passphrase []byte) (noise.NodeOption, error) {
+	privateKey, err := Load(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return noise.WithNodePrivateKey(privateKey), nil
+}
+
+func newSuite(passphrase, salt []byte, params ScryptParams) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, params.N, params.R, params.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+	}
+
+	core, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not instantiate aes: %w", err)
+	}
+
+	suite, err := cipher.NewGCM(core)
+	if err != nil {
+		return nil, fmt.Errorf("could not instantiate aes-gcm: %w", err)
+	}
+
+	return suite, nil
+}
@@ -0,0 +1,135 @@
+package keystore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/keystore"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fast has keys derived with scrypt parameters that are cheap enough to have tests run quickly.
+var fast = keystore.WithScryptParams(keystore.ScryptParams{N: 1 << 10, R: 8, P: 1})
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	buf, err := keystore.Encrypt(privateKey, []byte("passphrase"), fast)
+	assert.NoError(t, err)
+
+	// The private key never appears in plaintext within the keystore.
+
+	assert.NotContains(t, string(buf), privateKey.String())
+
+	var file keystore.File
+	assert.NoError(t, json.Unmarshal(buf, &file))
+	assert.Equal(t, keystore.Version, file.Version)
+	assert.Equal(t, privateKey.Public(), file.PublicKey)
+	assert.Equal(t, "scrypt", file.Crypto.KDF)
+	assert.Equal(t, "aes-256-gcm", file.Crypto.Cipher)
+
+	decrypted, err := keystore.Decrypt(buf, []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, privateKey, decrypted)
+
+	_, err = keystore.Decrypt(buf, []byte("wrong passphrase"))
+	assert.True(t, errors.Is(err, keystore.ErrDecrypt))
+
+	// Tampering with the public key fails authentication.
+
+	other, _, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	file.PublicKey = other
+
+	tampered, err := json.Marshal(file)
+	assert.NoError(t, err)
+
+	_, err = keystore.Decrypt(tampered, []byte("passphrase"))
+	assert.True(t, errors.Is(err, keystore.ErrDecrypt))
+}
+
+func TestDecryptRejectsExcessiveScryptParams(t *testing.T) {
+	t.Parallel()
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	buf, err := keystore.Encrypt(privateKey, []byte("passphrase"), fast)
+	assert.NoError(t, err)
+
+	for _, params := range []keystore.ScryptParams{
+		{N: 1 << 30, R: 8, P: 1},
+		{N: 1 << 10, R: 1 << 20, P: 1},
+		{N: 1 << 10, R: 8, P: 1 << 20},
+	} {
+		var file keystore.File
+		assert.NoError(t, json.Unmarshal(buf, &file))
+
+		file.Crypto.KDFParams.ScryptParams = params
+
+		crafted, err := json.Marshal(file)
+		assert.NoError(t, err)
+
+		_, err = keystore.Decrypt(crafted, []byte("passphrase"))
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, keystore.ErrDecrypt))
+	}
+}
+
+func TestGenerateRotateAndLoadIntoNode(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "keystore")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "node.json")
+
+	privateKey, err := keystore.Generate(path, []byte("passphrase"), fast)
+	assert.NoError(t, err)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	_, err = keystore.Generate(path, []byte("passphrase"), fast)
+	assert.True(t, errors.Is(err, keystore.ErrExists))
+
+	opt, err := keystore.WithNodeKeystore(path, []byte("passphrase"))
+	assert.NoError(t, err)
+
+	node, err := noise.NewNode(opt)
+	assert.NoError(t, err)
+	signature := node.Sign([]byte("data"))
+	assert.True(t, privateKey.Public().Verify([]byte("data"), signature))
+
+	_, err = keystore.WithNodeKeystore(path, []byte("wrong passphrase"))
+	assert.True(t, errors.Is(err, keystore.ErrDecrypt))
+
+	rotated, retired, err := keystore.Rotate(path, []byte("passphrase"), fast)
+	assert.NoError(t, err)
+	assert.NotEqual(t, privateKey, rotated)
+
+	loaded, err := keystore.Load(path, []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, rotated, loaded)
+
+	previous, err := keystore.Load(retired, []byte("passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, privateKey, previous)
+
+	assert.NoError(t, keystore.ChangePassphrase(path, []byte("passphrase"), []byte("new passphrase"), fast))
+
+	loaded, err = keystore.Load(path, []byte("new passphrase"))
+	assert.NoError(t, err)
+	assert.Equal(t, rotated, loaded)
+}
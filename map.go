@@ -3,8 +3,9 @@ package noise
 import (
 	"container/list"
 	"errors"
-	"math"
+	"io"
 	"sync"
+	"time"
 )
 
 type clientMapEntry struct {
@@ -16,13 +17,15 @@ type clientMap struct {
 	sync.Mutex
 
 	cap     uint
+	side    ClientSide
 	order   *list.List
 	entries map[string]clientMapEntry
 }
 
-func newClientMap(cap uint) *clientMap {
+func newClientMap(cap uint, side ClientSide) *clientMap {
 	return &clientMap{
 		cap:     cap,
+		side:    side,
 		order:   list.New(),
 		entries: make(map[string]clientMapEntry, cap),
 	}
@@ -43,10 +46,12 @@ func (c *clientMap) get(n *Node, addr string) (*Client, bool) {
 
 			e.client.close()
 			e.client.waitUntilClosed()
+
+			n.metrics.evictions.Inc()
 		}
 
 		entry.el = c.order.PushFront(addr)
-		entry.client = newClient(n)
+		entry.client = newClient(n, c.side)
 
 		c.entries[addr] = entry
 	} else {
@@ -56,6 +61,25 @@ func (c *clientMap) get(n *Node, addr string) (*Client, bool) {
 	return entry.client, exists
 }
 
+func (c *clientMap) len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.entries)
+}
+
+func (c *clientMap) find(addr string) *Client {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, exists := c.entries[addr]
+	if !exists {
+		return nil
+	}
+
+	return entry.client
+}
+
 func (c *clientMap) remove(addr string) {
 	c.Lock()
 	defer c.Unlock()
@@ -96,29 +120,100 @@ func (c *clientMap) slice() []*Client {
 	return clients
 }
 
+// abandonedRequestTTL is how long the nonce of an abandoned request is remembered for, such that a response to the
+// request arriving late is dropped rather than handled as a message from the peer.
+const abandonedRequestTTL = 1 * time.Minute
+
+// streamBufferSize is the number of responses a peer may stream back to a request ahead of those read by the
+// requester. It is the credit a peer starts streaming back responses with, and the requester grants credit back as it
+// reads responses. Responses are buffered by the requester, which never blocks reading from the connection.
+const streamBufferSize = 32
+
+// pendingStream is a request awaiting a stream of responses. Responses are delivered to responses until the stream is
+// abandoned, or until done is closed.
+type pendingStream struct {
+	responses chan message
+	abandoned chan struct{}
+	done      <-chan struct{}
+}
+
 type requestMap struct {
 	sync.Mutex
-	entries map[uint64]chan message
-	nonce   uint64
+	entries   map[uint64]chan message
+	streams   map[uint64]*pendingStream
+	abandoned map[uint64]time.Time
+	expiry    *list.List // abandonedRequest, ordered by the time the request was abandoned
+	nonce     uint64
+	closed    bool
+}
+
+// abandonedRequest is the nonce of an abandoned request, alongside the time it was abandoned at.
+type abandonedRequest struct {
+	nonce uint64
+	at    time.Time
 }
 
 func newRequestMap() *requestMap {
-	return &requestMap{entries: make(map[uint64]chan message)}
+	return &requestMap{
+		entries:   make(map[uint64]chan message),
+		streams:   make(map[uint64]*pendingStream),
+		abandoned: make(map[uint64]time.Time),
+		expiry:    list.New(),
+	}
 }
 
-func (r *requestMap) nextNonce() (<-chan message, uint64, error) {
+func (r *requestMap) len() int {
 	r.Lock()
 	defer r.Unlock()
 
-	if r.nonce == math.MaxUint64 {
-		r.nonce = 0
+	return len(r.entries) + len(r.streams)
+}
+
+// allocate returns the next available nonce, skipping past nonces of pending and recently abandoned requests. It
+// must be called with the lock held.
+func (r *requestMap) allocate() (uint64, error) {
+	if r.closed {
+		return 0, io.EOF
 	}
 
-	r.nonce++
-	nonce := r.nonce
+	for attempts := len(r.entries) + len(r.streams) + len(r.abandoned); attempts >= 0; attempts-- {
+		if r.nonce == headerBit-1 {
+			r.nonce = 0
+		}
+
+		r.nonce++
 
+		if !r.inUse(r.nonce) {
+			return r.nonce, nil
+		}
+	}
+
+	return 0, errors.New("ran out of available nonce to use for making a new request")
+}
+
+// inUse returns true should nonce be of a pending or recently abandoned request. It must be called with the lock
+// held.
+func (r *requestMap) inUse(nonce uint64) bool {
 	if _, exists := r.entries[nonce]; exists {
-		return nil, 0, errors.New("ran out of available nonce to use for making a new request")
+		return true
+	}
+
+	if _, exists := r.streams[nonce]; exists {
+		return true
+	}
+
+	_, exists := r.abandoned[nonce]
+
+	return exists
+}
+
+func (r *requestMap) nextNonce() (<-chan message, uint64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	nonce, err := r.allocate()
+	if err != nil {
+		return nil, 0, err
 	}
 
 	ch := make(chan message, 1)
@@ -127,26 +222,135 @@ func (r *requestMap) nextNonce() (<-chan message, uint64, error) {
 	return ch, nonce, nil
 }
 
+// nextStream registers a request awaiting a stream of responses, which are no longer delivered once done is closed.
+func (r *requestMap) nextStream(done <-chan struct{}) (*pendingStream, uint64, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	nonce, err := r.allocate()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stream := &pendingStream{
+		responses: make(chan message, streamBufferSize+1),
+		abandoned: make(chan struct{}),
+		done:      done,
+	}
+
+	r.streams[nonce] = stream
+
+	return stream, nonce, nil
+}
+
+// findStream returns the request under nonce should it await a stream of responses.
+func (r *requestMap) findStream(nonce uint64) (*pendingStream, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	stream, exists := r.streams[nonce]
+
+	return stream, exists
+}
+
+// endStream removes the request under nonce, whose stream of responses has ended.
+func (r *requestMap) endStream(nonce uint64) {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.streams, nonce)
+}
+
+// abandonStream removes the request under nonce, whose requester no longer awaits a stream of responses. It returns
+// false should the stream have already ended.
+func (r *requestMap) abandonStream(nonce uint64) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	stream, exists := r.streams[nonce]
+	if !exists {
+		return false
+	}
+
+	close(stream.abandoned)
+	delete(r.streams, nonce)
+
+	return true
+}
+
 func (r *requestMap) markRequestFailed(nonce uint64) {
 	r.Lock()
 	defer r.Unlock()
 
-	close(r.entries[nonce])
+	if ch, ok := r.entries[nonce]; ok && ch != nil {
+		close(ch)
+	}
 	delete(r.entries, nonce)
 }
 
-func (r *requestMap) findRequest(nonce uint64) chan<- message {
+// abandonRequest removes the request under nonce, whose requester no longer awaits a response. It returns false
+// should the request have already been responded to.
+func (r *requestMap) abandonRequest(nonce uint64) bool {
 	r.Lock()
 	defer r.Unlock()
 
+	now := time.Now()
+
+	r.pruneAbandoned(now)
+
+	if _, exists := r.entries[nonce]; !exists {
+		return false
+	}
+
+	delete(r.entries, nonce)
+
+	r.abandoned[nonce] = now
+	r.expiry.PushBack(abandonedRequest{nonce: nonce, at: now})
+
+	return true
+}
+
+// pruneAbandoned forgets the nonces of requests abandoned longer than abandonedRequestTTL ago. Nonces are
+// abandoned in order, and so only the nonces at the front of the expiry list are visited. It must be called with the
+// lock held.
+func (r *requestMap) pruneAbandoned(now time.Time) {
+	for e := r.expiry.Front(); e != nil; e = r.expiry.Front() {
+		abandoned := e.Value.(abandonedRequest)
+
+		if now.Sub(abandoned.at) < abandonedRequestTTL {
+			break
+		}
+
+		// The nonce may have since been forgotten due to a late response, and abandoned once again.
+
+		if at, exists := r.abandoned[abandoned.nonce]; exists && at.Equal(abandoned.at) {
+			delete(r.abandoned, abandoned.nonce)
+		}
+
+		r.expiry.Remove(e)
+	}
+}
+
+// findRequest returns the channel the response to the request under nonce is to be delivered to. It returns false
+// should nonce not be of a request, and a nil channel should the request have been abandoned.
+func (r *requestMap) findRequest(nonce uint64) (chan<- message, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, abandoned := r.abandoned[nonce]; abandoned {
+		delete(r.abandoned, nonce)
+		return nil, true
+	}
+
 	ch, exists := r.entries[nonce]
 	if exists {
 		delete(r.entries, nonce)
 	}
 
-	return ch
+	return ch, exists
 }
 
+// close fails all pending requests and streams, and refuses new requests once the connection is torn down.
 func (r *requestMap) close() {
 	r.Lock()
 	defer r.Unlock()
@@ -155,4 +359,13 @@ func (r *requestMap) close() {
 		close(r.entries[nonce])
 		delete(r.entries, nonce)
 	}
+
+	for nonce, stream := range r.streams {
+		close(stream.responses)
+		delete(r.streams, nonce)
+	}
+
+	r.abandoned = make(map[uint64]time.Time)
+	r.expiry.Init()
+	r.closed = true
 }
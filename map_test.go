@@ -0,0 +1,116 @@
+package noise
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRequestNonceWrapsBeforeHeaderBit(t *testing.T) {
+	t.Parallel()
+
+	r := newRequestMap()
+	r.nonce = headerBit - 2
+
+	_, nonce, err := r.nextNonce()
+	assert.NoError(t, err)
+	assert.EqualValues(t, uint64(headerBit-1), nonce)
+
+	// Nonces never carry headerBit, nor reach controlNonce, and zero is reserved for messages that are not requests.
+
+	_, nonce, err = r.nextNonce()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, nonce)
+}
+
+func TestRequestNonceSkipsAbandonedNonce(t *testing.T) {
+	t.Parallel()
+
+	r := newRequestMap()
+
+	_, nonce, err := r.nextNonce()
+	assert.NoError(t, err)
+	assert.True(t, r.abandonRequest(nonce))
+
+	_, pending, err := r.nextNonce()
+	assert.NoError(t, err)
+
+	// Nonces of pending and recently abandoned requests are skipped once nonces wrap around.
+
+	r.nonce = headerBit - 1
+
+	_, next, err := r.nextNonce()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, next)
+	assert.NotEqual(t, nonce, next)
+	assert.NotEqual(t, pending, next)
+}
+
+func TestAbandonedRequestsPruned(t *testing.T) {
+	t.Parallel()
+
+	r := newRequestMap()
+
+	for i := 0; i < 3; i++ {
+		_, nonce, err := r.nextNonce()
+		assert.NoError(t, err)
+		assert.True(t, r.abandonRequest(nonce))
+	}
+
+	assert.Len(t, r.abandoned, 3)
+
+	// A late response to an abandoned request has its nonce forgotten straight away.
+
+	ch, exists := r.findRequest(2)
+	assert.True(t, exists)
+	assert.Nil(t, ch)
+	assert.Len(t, r.abandoned, 2)
+
+	r.pruneAbandoned(time.Now())
+	assert.Len(t, r.abandoned, 2)
+
+	r.pruneAbandoned(time.Now().Add(abandonedRequestTTL))
+	assert.Empty(t, r.abandoned)
+	assert.Zero(t, r.expiry.Len())
+}
+
+func TestRequestMapClose(t *testing.T) {
+	t.Parallel()
+
+	r := newRequestMap()
+
+	ch, _, err := r.nextNonce()
+	assert.NoError(t, err)
+
+	r.close()
+
+	// Pending requests are failed, and new requests are refused once the connection is torn down.
+
+	_, open := <-ch
+	assert.False(t, open)
+
+	_, _, err = r.nextNonce()
+	assert.Equal(t, io.EOF, err)
+
+	_, _, err = r.nextStream(nil)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMarkRequestFailedWithoutPendingRequest(t *testing.T) {
+	t.Parallel()
+
+	r := newRequestMap()
+
+	_, nonce, err := r.nextNonce()
+	assert.NoError(t, err)
+	assert.True(t, r.abandonRequest(nonce))
+
+	// Failing an abandoned, an unknown, or an already failed request is a no-op.
+
+	assert.NotPanics(t, func() {
+		r.markRequestFailed(nonce)
+		r.markRequestFailed(nonce + 1)
+		r.markRequestFailed(nonce)
+	})
+}
@@ -0,0 +1,405 @@
+package noise
+
+import (
+	"bufio"
+	"fmt"
+	"go.uber.org/atomic"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the buckets of latency histograms registered by noise.
+var DefaultLatencyBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics is a registry of counters, gauges, and latency histograms. Every node has its own registry which is
+// available via (*Node).Metrics, which protocols such as kademlia and gossip register their own metrics to.
+//
+// Metrics implements http.Handler, and serves a snapshot of all registered metrics in the Prometheus text-based
+// exposition format.
+//
+// Metrics may be used concurrently.
+type Metrics struct {
+	sync.RWMutex
+	entries map[string]metric
+}
+
+type metric interface {
+	kind() string
+	description() string
+}
+
+// NewMetrics returns a new, empty registry of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{entries: make(map[string]metric)}
+}
+
+// Counter returns the counter registered under name, registering a new counter described by help should none
+// exist. It panics should a metric of a different kind be registered under name.
+func (m *Metrics) Counter(name, help string) *Counter {
+	entry := m.register(name, func() metric { return &Counter{help: help} })
+
+	counter, ok := entry.(*Counter)
+	if !ok {
+		panic(fmt.Errorf("noise: metric %q is already registered as a %s", name, entry.kind()))
+	}
+
+	return counter
+}
+
+// Gauge returns the gauge registered under name, registering a new gauge described by help should none exist. It
+// panics should a metric of a different kind be registered under name.
+func (m *Metrics) Gauge(name, help string) *Gauge {
+	entry := m.register(name, func() metric { return &Gauge{help: help} })
+
+	gauge, ok := entry.(*Gauge)
+	if !ok {
+		panic(fmt.Errorf("noise: metric %q is already registered as a %s", name, entry.kind()))
+	}
+
+	return gauge
+}
+
+// GaugeFunc registers a gauge under name whose value is computed by calling fn whenever a snapshot of all metrics
+// is taken, replacing any gauge function previously registered under name. fn must be safe to call concurrently.
+// It panics should a metric of a different kind be registered under name.
+func (m *Metrics) GaugeFunc(name, help string, fn func() float64) {
+	m.Lock()
+	defer m.Unlock()
+
+	if existing, exists := m.entries[name]; exists {
+		if _, ok := existing.(*gaugeFunc); !ok {
+			panic(fmt.Errorf("noise: metric %q is already registered as a %s", name, existing.kind()))
+		}
+	}
+
+	m.entries[name] = &gaugeFunc{help: help, fn: fn}
+}
+
+// Histogram returns the histogram registered under name, registering a new histogram described by help whose
+// buckets have the upper bounds buckets should none exist. DefaultLatencyBuckets are used should buckets be empty.
+// It panics should a metric of a different kind be registered under name.
+func (m *Metrics) Histogram(name, help string, buckets ...float64) *Histogram {
+	entry := m.register(name, func() metric { return newHistogram(help, buckets) })
+
+	histogram, ok := entry.(*Histogram)
+	if !ok {
+		panic(fmt.Errorf("noise: metric %q is already registered as a %s", name, entry.kind()))
+	}
+
+	return histogram
+}
+
+func (m *Metrics) register(name string, create func() metric) metric {
+	m.RLock()
+	existing, exists := m.entries[name]
+	m.RUnlock()
+
+	if !exists {
+		m.Lock()
+
+		if existing, exists = m.entries[name]; !exists {
+			existing = create()
+			m.entries[name] = existing
+		}
+
+		m.Unlock()
+	}
+
+	return existing
+}
+
+// Stats returns a snapshot of the values of all registered metrics.
+func (m *Metrics) Stats() Stats {
+	m.RLock()
+	defer m.RUnlock()
+
+	stats := Stats{
+		Counters:   make(map[string]uint64),
+		Gauges:     make(map[string]float64),
+		Histograms: make(map[string]HistogramStats),
+	}
+
+	for name, entry := range m.entries {
+		switch entry := entry.(type) {
+		case *Counter:
+			stats.Counters[name] = entry.Value()
+		case *Gauge:
+			stats.Gauges[name] = float64(entry.Value())
+		case *gaugeFunc:
+			stats.Gauges[name] = entry.fn()
+		case *Histogram:
+			stats.Histograms[name] = entry.Stats()
+		}
+	}
+
+	return stats
+}
+
+// ServeHTTP implements http.Handler, and responds with all registered metrics in the Prometheus text-based
+// exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	m.RLock()
+	names := make([]string, 0, len(m.entries))
+	entries := make(map[string]metric, len(m.entries))
+
+	for name, entry := range m.entries {
+		names = append(names, name)
+		entries[name] = entry
+	}
+	m.RUnlock()
+
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+
+	for _, name := range names {
+		entry := entries[name]
+
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, entry.description(), name, entry.kind())
+
+		switch entry := entry.(type) {
+		case *Counter:
+			fmt.Fprintf(buf, "%s %d\n", name, entry.Value())
+		case *Gauge:
+			fmt.Fprintf(buf, "%s %d\n", name, entry.Value())
+		case *gaugeFunc:
+			fmt.Fprintf(buf, "%s %s\n", name, formatFloat(entry.fn()))
+		case *Histogram:
+			histogram := entry.Stats()
+
+			for _, bucket := range histogram.Buckets {
+				fmt.Fprintf(buf, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(bucket.UpperBound), bucket.Count)
+			}
+
+			fmt.Fprintf(buf, "%s_sum %s\n%s_count %d\n", name, formatFloat(histogram.Sum), name, histogram.Count)
+		}
+	}
+
+	_ = buf.Flush()
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Stats is a snapshot of the values of the metrics registered to a Metrics registry, keyed by metric name.
+type Stats struct {
+	Counters   map[string]uint64
+	Gauges     map[string]float64
+	Histograms map[string]HistogramStats
+}
+
+// HistogramStats is a snapshot of the values of a histogram.
+type HistogramStats struct {
+	// Count is the total number of observed values.
+	Count uint64
+
+	// Sum is the sum of all observed values.
+	Sum float64
+
+	// Buckets are the cumulative counts of observed values that are at most the upper bound of each bucket, in
+	// ascending order of upper bound. The last bucket has an upper bound of +Inf.
+	Buckets []HistogramBucket
+}
+
+// HistogramBucket is the cumulative count of values observed by a histogram that are at most UpperBound.
+type HistogramBucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Counter is a metric whose value only ever increases. It may be used concurrently.
+type Counter struct {
+	help  string
+	value atomic.Uint64
+}
+
+func (c *Counter) kind() string        { return "counter" }
+func (c *Counter) description() string { return c.help }
+
+// Inc increments this counter by one.
+func (c *Counter) Inc() {
+	c.value.Inc()
+}
+
+// Add increments this counter by delta.
+func (c *Counter) Add(delta uint64) {
+	c.value.Add(delta)
+}
+
+// Value returns the value of this counter.
+func (c *Counter) Value() uint64 {
+	return c.value.Load()
+}
+
+// Gauge is a metric whose value may increase or decrease. It may be used concurrently.
+type Gauge struct {
+	help  string
+	value atomic.Int64
+}
+
+func (g *Gauge) kind() string        { return "gauge" }
+func (g *Gauge) description() string { return g.help }
+
+// Set sets the value of this gauge to value.
+func (g *Gauge) Set(value int64) {
+	g.value.Store(value)
+}
+
+// Add adds delta, which may be negative, to the value of this gauge.
+func (g *Gauge) Add(delta int64) {
+	g.value.Add(delta)
+}
+
+// Value returns the value of this gauge.
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+type gaugeFunc struct {
+	help string
+	fn   func() float64
+}
+
+func (g *gaugeFunc) kind() string        { return "gauge" }
+func (g *gaugeFunc) description() string { return g.help }
+
+// Histogram is a metric which counts observed values into buckets, typically used to track latencies in seconds.
+// It may be used concurrently.
+type Histogram struct {
+	help    string
+	bounds  []float64
+	buckets []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Float64
+}
+
+func newHistogram(help string, bounds []float64) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+
+	bounds = append([]float64{}, bounds...)
+	sort.Float64s(bounds)
+
+	return &Histogram{help: help, bounds: bounds, buckets: make([]atomic.Uint64, len(bounds)+1)}
+}
+
+func (h *Histogram) kind() string        { return "histogram" }
+func (h *Histogram) description() string { return h.help }
+
+// Observe records value into this histogram.
+func (h *Histogram) Observe(value float64) {
+	h.buckets[sort.SearchFloat64s(h.bounds, value)].Inc()
+	h.count.Inc()
+
+	for {
+		sum := h.sum.Load()
+		if h.sum.CAS(sum, sum+value) {
+			break
+		}
+	}
+}
+
+// ObserveDuration records d in seconds into this histogram.
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// ObserveSince records the time elapsed since start in seconds into this histogram.
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.ObserveDuration(time.Since(start))
+}
+
+// Stats returns a snapshot of the values of this histogram.
+func (h *Histogram) Stats() HistogramStats {
+	stats := HistogramStats{
+		Count:   h.count.Load(),
+		Sum:     h.sum.Load(),
+		Buckets: make([]HistogramBucket, len(h.buckets)),
+	}
+
+	var cumulative uint64
+
+	for i := range h.buckets {
+		cumulative += h.buckets[i].Load()
+
+		bound := math.Inf(1)
+		if i < len(h.bounds) {
+			bound = h.bounds[i]
+		}
+
+		stats.Buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+
+	return stats
+}
+
+// nodeMetrics holds the metrics a node records throughout its lifecycle.
+type nodeMetrics struct {
+	registry *Metrics
+
+	bytesSent      *Counter
+	bytesRecv      *Counter
+	framesSent     *Counter
+	framesRecv     *Counter
+	messagesRecv   *Counter
+	handlerErrors  *Counter
+	handshakes     *Counter
+	handshakeFails *Counter
+	dials          *Counter
+	dialFailures   *Counter
+	evictions      *Counter
+	requests       *Counter
+	requestFails   *Counter
+
+	requestTimeouts *Counter
+	requestCancels  *Counter
+	cancelsRecv     *Counter
+	streamRecv      *Counter
+
+	handshakeDuration *Histogram
+	dialDuration      *Histogram
+	requestDuration   *Histogram
+}
+
+func newNodeMetrics() *nodeMetrics {
+	m := NewMetrics()
+
+	return &nodeMetrics{
+		registry: m,
+
+		bytesSent:      m.Counter("noise_bytes_sent_total", "Number of bytes sent to peers, including framing."),
+		bytesRecv:      m.Counter("noise_bytes_received_total", "Number of bytes received from peers, including framing."),
+		framesSent:     m.Counter("noise_frames_sent_total", "Number of frames sent to peers."),
+		framesRecv:     m.Counter("noise_frames_received_total", "Number of frames received from peers."),
+		messagesRecv:   m.Counter("noise_messages_handled_total", "Number of messages from peers dispatched to handlers."),
+		handlerErrors:  m.Counter("noise_handler_errors_total", "Number of messages whose handler returned an error."),
+		handshakes:     m.Counter("noise_handshakes_total", "Number of handshakes with peers that succeeded."),
+		handshakeFails: m.Counter("noise_handshake_failures_total", "Number of handshakes with peers that failed."),
+		dials:          m.Counter("noise_dials_total", "Number of connections dialed to peers."),
+		dialFailures:   m.Counter("noise_dial_failures_total", "Number of addresses that failed to be dialed."),
+		evictions:      m.Counter("noise_evictions_total", "Number of connections evicted from a full connection pool."),
+		requests:       m.Counter("noise_requests_total", "Number of requests to peers that were responded to."),
+		requestFails:   m.Counter("noise_request_failures_total", "Number of requests to peers that failed."),
+
+		requestTimeouts: m.Counter("noise_request_timeouts_total", "Number of requests to peers abandoned as their deadline elapsed."),
+		requestCancels:  m.Counter("noise_request_cancellations_total", "Number of requests to peers abandoned as they were cancelled."),
+		cancelsRecv:     m.Counter("noise_request_cancellations_received_total", "Number of requests from peers that peers cancelled."),
+		streamRecv:      m.Counter("noise_stream_responses_received_total", "Number of responses peers streamed back to requests."),
+
+		handshakeDuration: m.Histogram("noise_handshake_duration_seconds", "Latency of handshakes with peers."),
+		dialDuration:      m.Histogram("noise_dial_duration_seconds", "Latency of dialing and handshaking with peers."),
+		requestDuration:   m.Histogram("noise_request_duration_seconds", "Latency of requests to peers."),
+	}
+}
@@ -0,0 +1,115 @@
+package noise_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	t.Parallel()
+
+	m := noise.NewMetrics()
+
+	counter := m.Counter("test_total", "A counter.")
+	counter.Add(2)
+	counter.Inc()
+
+	assert.Equal(t, counter, m.Counter("test_total", "A counter."))
+
+	m.Gauge("test_gauge", "A gauge.").Set(-4)
+	m.GaugeFunc("test_gauge_func", "A gauge function.", func() float64 { return 1.5 })
+
+	histogram := m.Histogram("test_seconds", "A histogram.", 0.1, 1)
+	histogram.ObserveDuration(50 * time.Millisecond)
+	histogram.Observe(0.5)
+	histogram.Observe(5)
+
+	assert.Panics(t, func() { m.Gauge("test_total", "Not a counter.") })
+
+	stats := m.Stats()
+
+	assert.EqualValues(t, 3, stats.Counters["test_total"])
+	assert.EqualValues(t, -4, stats.Gauges["test_gauge"])
+	assert.EqualValues(t, 1.5, stats.Gauges["test_gauge_func"])
+
+	assert.EqualValues(t, 3, stats.Histograms["test_seconds"].Count)
+	assert.InDelta(t, 5.55, stats.Histograms["test_seconds"].Sum, 1e-9)
+	assert.Equal(t, []noise.HistogramBucket{
+		{UpperBound: 0.1, Count: 1},
+		{UpperBound: 1, Count: 2},
+		{UpperBound: math.Inf(1), Count: 3},
+	}, stats.Histograms["test_seconds"].Buckets)
+
+	recorder := httptest.NewRecorder()
+	m.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Contains(t, body, "# TYPE test_total counter\ntest_total 3\n")
+	assert.Contains(t, body, "# TYPE test_gauge gauge\ntest_gauge -4\n")
+	assert.Contains(t, body, "test_gauge_func 1.5\n")
+	assert.Contains(t, body, `test_seconds_bucket{le="0.1"} 1`)
+	assert.Contains(t, body, `test_seconds_bucket{le="+Inf"} 3`)
+	assert.Contains(t, body, "test_seconds_count 3\n")
+}
+
+func TestNodeRecordsMetrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = a.Request(context.Background(), b.Addr(), []byte("hello"))
+	assert.NoError(t, err)
+
+	stats := a.Stats()
+
+	assert.EqualValues(t, 1, stats.Counters["noise_dials_total"])
+	assert.EqualValues(t, 1, stats.Counters["noise_handshakes_total"])
+	assert.EqualValues(t, 1, stats.Counters["noise_requests_total"])
+	assert.EqualValues(t, 1, stats.Histograms["noise_request_duration_seconds"].Count)
+	assert.EqualValues(t, 1, stats.Gauges["noise_outbound_connections"])
+	assert.NotZero(t, stats.Counters["noise_bytes_sent_total"])
+	assert.NotZero(t, stats.Counters["noise_bytes_received_total"])
+
+	waitFor(t, func() bool { return b.Stats().Counters["noise_messages_handled_total"] == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err = a.Ping(ctx, "192.0.2.1:3000") // TEST-NET-1 (RFC 5737), which may not be dialed.
+	assert.Error(t, err)
+
+	assert.EqualValues(t, 1, a.Stats().Counters["noise_dial_failures_total"])
+
+	recorder := httptest.NewRecorder()
+	a.Metrics().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.True(t, strings.Contains(recorder.Body.String(), "noise_requests_total 1\n"))
+}
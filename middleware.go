@@ -0,0 +1,80 @@
+package noise
+
+import "context"
+
+// Middleware wraps a Handler with behavior that is to run before and/or after it, such as authorization checks,
+// logging, metrics, or recovering from panics. Middleware may be registered to a node via WithNodeMiddleware or
+// (*Node).Use, and wraps the dispatch of every message a node receives, such that it runs once per message before
+// and after all Handlers registered to the node handle it, including those registered by protocols bound to the node.
+//
+// Middleware may skip all handlers by not calling the handler it wraps, and may return a *RemoteError to respond to a
+// request with an error. See Handler.
+type Middleware func(next Handler) Handler
+
+// Call describes data being sent to a peer via (*Node).Send, (*Node).Request, (*Node).SendMessage,
+// (*Node).RequestMessage, or their streaming variants, which interceptors may inspect and modify before it is sent.
+type Call struct {
+	// Addr is the address of the peer the call is made to, which interceptors may change to redirect the call to
+	// another peer. The peer is dialed through Addr once all interceptors have been run through, should it not have
+	// been connected to before, after which Peer is set to the ID of the peer.
+	Peer ID
+	Addr string
+
+	// Request is true should the call await a response from the peer.
+	Request bool
+
+	// Stream is true should the call await a stream of responses from the peer. The context the call is invoked
+	// with governs the stream for as long as it is read from. See (*Node).RequestStream.
+	Stream bool
+
+	// Message is the message being sent should the call have been made via (*Node).SendMessage or
+	// (*Node).RequestMessage, and Opcode is the opcode its type was registered under.
+	Message Serializable
+	Opcode  uint16
+
+	// Data is the data being sent, which includes the opcode of Message should Message be set.
+	Data []byte
+
+	// Header holds the headers the data is sent alongside, which initially are the headers carried by the context
+	// of the call. See ContextWithHeader.
+	Header Header
+
+	// Response is the data the peer responded with should the call be of a request that does not await a stream of
+	// responses. It is set once the call has been invoked.
+	Response []byte
+
+	// stream is the stream of responses the peer sends back should the call await a stream of responses. It is set
+	// once the call has been invoked.
+	stream *ResponseStream
+}
+
+// Invoker sends the data described by call to the peer of call.
+type Invoker func(ctx context.Context, call *Call) error
+
+// Interceptor wraps calls to peers made via (*Node).Send, (*Node).Request, (*Node).SendMessage,
+// (*Node).RequestMessage, and their streaming variants. An interceptor sends the data described by call by calling
+// invoke, and may skip sending the data altogether by not calling it. Interceptors may be registered to a node via
+// WithNodeInterceptors or (*Node).Intercept.
+type Interceptor func(ctx context.Context, call *Call, invoke Invoker) error
+
+// chainHandler wraps handler with middleware, such that the first middleware is the outermost.
+func chainHandler(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
+
+// chainInvoker wraps invoke with interceptors, such that the first interceptor is the outermost.
+func chainInvoker(invoke Invoker, interceptors []Interceptor) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoke
+
+		invoke = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return invoke
+}
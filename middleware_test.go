@@ -0,0 +1,485 @@
+package noise_test
+
+import (
+	"context"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type echo struct {
+	data []byte
+}
+
+func (e echo) Marshal() []byte {
+	return e.data
+}
+
+func unmarshalEcho(buf []byte) (echo, error) {
+	return echo{data: buf}, nil
+}
+
+func TestMiddlewareAndInterceptors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		order = append(order, event)
+	}
+
+	tag := func(name string) noise.Middleware {
+		return func(next noise.Handler) noise.Handler {
+			return func(ctx noise.HandlerContext) error {
+				record(name)
+				return next(ctx)
+			}
+		}
+	}
+
+	// Reject requests that do not carry a token.
+
+	auth := func(next noise.Handler) noise.Handler {
+		return func(ctx noise.HandlerContext) error {
+			if ctx.Header().Get("token") != "secret" {
+				return &noise.RemoteError{Code: 401, Message: "unauthorized"}
+			}
+
+			return next(ctx)
+		}
+	}
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(noise.WithNodeMiddleware(tag("first")))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	a.RegisterMessage(echo{}, unmarshalEcho)
+	b.RegisterMessage(echo{}, unmarshalEcho)
+
+	b.Use(tag("second"), auth)
+
+	opcodes := make(chan uint16, 1)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		record("handler")
+
+		opcode, ok := ctx.Opcode()
+		assert.True(t, ok)
+		opcodes <- opcode
+
+		return ctx.Send(ctx.Data())
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = a.RequestMessage(context.Background(), b.Addr(), echo{data: []byte("hello")})
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.EqualValues(t, 401, remote.Code)
+
+	mu.Lock()
+	assert.Equal(t, []string{"first", "second"}, order)
+	mu.Unlock()
+
+	// Have all calls from c carry a token.
+
+	calls := make(chan noise.Call, 1)
+
+	c, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer c.Close()
+
+	c.RegisterMessage(echo{}, unmarshalEcho)
+
+	c.Intercept(func(ctx context.Context, call *noise.Call, invoke noise.Invoker) error {
+		call.Header.Set("token", "secret")
+		return invoke(ctx, call)
+	}, func(ctx context.Context, call *noise.Call, invoke noise.Invoker) error {
+		err := invoke(ctx, call)
+		calls <- *call
+		return err
+	})
+
+	assert.NoError(t, c.Listen())
+
+	mu.Lock()
+	order = nil
+	mu.Unlock()
+
+	res, err := c.RequestMessage(context.Background(), b.Addr(), echo{data: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, echo{data: []byte("hello")}, res)
+
+	mu.Lock()
+	assert.Equal(t, []string{"first", "second", "handler"}, order)
+	mu.Unlock()
+
+	call := <-calls
+	assert.Equal(t, b.ID().ID, call.Peer.ID)
+	assert.True(t, call.Request)
+	assert.Equal(t, echo{data: []byte("hello")}, call.Message)
+	assert.Equal(t, <-opcodes, call.Opcode)
+	assert.Equal(t, "secret", call.Header.Get("token"))
+	assert.Equal(t, call.Data, call.Response)
+}
+
+func TestMiddlewareRunsOncePerMessage(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var wrapped, handled int32
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(noise.WithNodeMiddleware(func(next noise.Handler) noise.Handler {
+		return func(ctx noise.HandlerContext) error {
+			atomic.AddInt32(&wrapped, 1)
+			return next(ctx)
+		}
+	}))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	for i := 0; i < 3; i++ {
+		b.Handle(func(ctx noise.HandlerContext) error {
+			atomic.AddInt32(&handled, 1)
+			return nil
+		})
+	}
+
+	b.HandleFallback(func(ctx noise.HandlerContext) error {
+		return ctx.Send(ctx.Data())
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = a.Request(context.Background(), b.Addr(), []byte("hello"))
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&wrapped))
+	assert.EqualValues(t, 3, atomic.LoadInt32(&handled))
+}
+
+func TestInterceptorsRunBeforeDialing(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, b.Listen())
+
+	denied := errors.New("denied")
+
+	route := func(ctx context.Context, call *noise.Call, invoke noise.Invoker) error {
+		switch call.Addr {
+		case "denied":
+			return denied
+		case "redirected":
+			call.Addr = b.Addr()
+		}
+
+		return invoke(ctx, call)
+	}
+
+	a, err := noise.NewNode(noise.WithNodeInterceptors(route))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	// Calls denied by an interceptor never dial the peer, and calls redirected by an interceptor dial the address
+	// they were redirected to.
+
+	_, err = a.Request(context.Background(), "denied", []byte("hello"))
+	assert.True(t, errors.Is(err, denied))
+	assert.Empty(t, a.Outbound())
+
+	res, err := a.Request(context.Background(), "redirected", []byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), res)
+
+	if assert.Len(t, a.Outbound(), 1) {
+		assert.Equal(t, b.ID().ID, a.Outbound()[0].ID().ID)
+	}
+}
+
+type ping struct{}
+
+func (ping) Marshal() []byte {
+	return nil
+}
+
+func unmarshalPing([]byte) (ping, error) {
+	return ping{}, nil
+}
+
+type unregistered struct{}
+
+func (unregistered) Marshal() []byte {
+	return nil
+}
+
+func TestHandleMessageRoutesByType(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	for _, node := range []*noise.Node{a, b} {
+		node.RegisterMessage(echo{}, unmarshalEcho)
+		node.RegisterMessage(ping{}, unmarshalPing)
+	}
+
+	assert.Panics(t, func() { b.HandleMessage(unregistered{}, func(noise.HandlerContext, unregistered) error { return nil }) })
+	assert.Panics(t, func() { b.HandleMessage(echo{}, func(noise.HandlerContext, ping) error { return nil }) })
+
+	var untyped int32
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		atomic.AddInt32(&untyped, 1)
+		return nil
+	})
+
+	b.HandleMessage(echo{}, func(ctx noise.HandlerContext, msg echo) error {
+		decoded, err := ctx.DecodeMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, msg, decoded)
+
+		return ctx.SendMessage(msg)
+	})
+
+	assert.Panics(t, func() { b.HandleMessage(echo{}, func(noise.HandlerContext, echo) error { return nil }) })
+
+	b.HandleFallback(func(ctx noise.HandlerContext) error {
+		if !ctx.IsRequest() {
+			return nil
+		}
+
+		return &noise.RemoteError{Code: 404, Message: "no handler"}
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	res, err := a.RequestMessage(context.Background(), b.Addr(), echo{data: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, echo{data: []byte("hello")}, res)
+	assert.Zero(t, atomic.LoadInt32(&untyped))
+
+	_, err = a.RequestMessage(context.Background(), b.Addr(), ping{})
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.EqualValues(t, 404, remote.Code)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&untyped))
+}
+
+func TestConcurrentHandlerRegistration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	node, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer node.Close()
+
+	node.RegisterMessage(echo{}, unmarshalEcho)
+	node.RegisterMessage(ping{}, unmarshalPing)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go func() {
+		defer wg.Done()
+		node.Handle(func(noise.HandlerContext) error { return nil })
+	}()
+
+	go func() {
+		defer wg.Done()
+		node.HandleFallback(func(noise.HandlerContext) error { return nil })
+	}()
+
+	go func() {
+		defer wg.Done()
+		node.HandleMessage(echo{}, func(noise.HandlerContext, echo) error { return nil })
+	}()
+
+	go func() {
+		defer wg.Done()
+		node.HandleMessage(ping{}, func(noise.HandlerContext, ping) error { return nil })
+	}()
+
+	wg.Wait()
+
+	assert.NoError(t, node.Listen())
+}
+
+func TestHandlersRegisteredAfterListen(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	for _, node := range []*noise.Node{a, b} {
+		node.RegisterMessage(echo{}, unmarshalEcho)
+		node.RegisterMessage(ping{}, unmarshalPing)
+	}
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// Handlers, middleware, and interceptors registered once a node is listening take effect straight away.
+
+	var intercepted, wrapped, untyped int32
+
+	a.Intercept(func(ctx context.Context, call *noise.Call, invoke noise.Invoker) error {
+		atomic.AddInt32(&intercepted, 1)
+		return invoke(ctx, call)
+	})
+
+	b.Use(func(next noise.Handler) noise.Handler {
+		return func(ctx noise.HandlerContext) error {
+			atomic.AddInt32(&wrapped, 1)
+			return next(ctx)
+		}
+	})
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		atomic.AddInt32(&untyped, 1)
+		return nil
+	})
+
+	b.HandleMessage(echo{}, func(ctx noise.HandlerContext, msg echo) error {
+		return ctx.SendMessage(msg)
+	})
+
+	b.HandleFallback(func(ctx noise.HandlerContext) error {
+		return &noise.RemoteError{Code: 404, Message: "no handler"}
+	})
+
+	res, err := a.RequestMessage(context.Background(), b.Addr(), echo{data: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, echo{data: []byte("hello")}, res)
+
+	_, err = a.RequestMessage(context.Background(), b.Addr(), ping{})
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.EqualValues(t, 404, remote.Code)
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&intercepted))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&wrapped))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&untyped))
+}
+
+func TestOpcodeNegotiation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	handle := func(ctx noise.HandlerContext, msg echo) error {
+		return ctx.SendMessage(msg)
+	}
+
+	// a registers echo under an opcode other than the one b derives for it.
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	opcode := a.RegisterMessageWithOpcode(echo{}, unmarshalEcho, 1)
+	assert.EqualValues(t, 1, opcode)
+
+	assert.Panics(t, func() { a.RegisterMessageWithOpcode(ping{}, unmarshalPing, 1) })
+
+	a.HandleMessage(echo{}, handle)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NotEqual(t, opcode, b.RegisterMessage(echo{}, unmarshalEcho))
+	b.HandleMessage(echo{}, handle)
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = a.RequestMessage(context.Background(), b.Addr(), echo{data: []byte("hello")})
+	assert.True(t, errors.Is(err, noise.ErrOpcodeMismatch), err)
+
+	// Nodes that remap opcodes are able to exchange messages regardless.
+
+	c, err := noise.NewNode(noise.WithNodeOpcodeRemapping(true))
+	assert.NoError(t, err)
+
+	defer c.Close()
+
+	c.RegisterMessageWithOpcode(echo{}, unmarshalEcho, 1)
+	c.HandleMessage(echo{}, handle)
+
+	d, err := noise.NewNode(noise.WithNodeOpcodeRemapping(true))
+	assert.NoError(t, err)
+
+	defer d.Close()
+
+	d.RegisterMessage(echo{}, unmarshalEcho)
+	d.HandleMessage(echo{}, handle)
+
+	assert.NoError(t, c.Listen())
+	assert.NoError(t, d.Listen())
+
+	res, err := c.RequestMessage(context.Background(), d.Addr(), echo{data: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, echo{data: []byte("hello")}, res)
+
+	res, err = d.RequestMessage(context.Background(), c.Addr(), echo{data: []byte("world")})
+	assert.NoError(t, err)
+	assert.Equal(t, echo{data: []byte("world")}, res)
+}
@@ -9,7 +9,9 @@ package noise
 // may be registered to a node by (*Node).Handle before the node starts listening for new peers.
 //
 // Returning an error in a handler closes the connection and marks the connection to have closed unexpectedly or due
-// to error. Should you intend to wish to skip a handler from processing some given data, return a nil error.
+// to error, which is suited for peers that violate a protocol. Returning a *RemoteError instead skips all remaining
+// handlers and keeps the connection open, and should the data be of a request, responds to the request with the
+// error. Should you intend to wish to skip a handler from processing some given data, return a nil error.
 type Handler func(ctx HandlerContext) error
 
 // Protocol is an interface that may be implemented by libraries and projects built on top of Noise to hook callbacks
@@ -36,9 +38,16 @@ type Protocol struct {
 	// has been terminated.
 	OnPeerDisconnected func(client *Client)
 
-	// OnPingFailed is called whenever any attempt by a node to dial a peer at addr fails.
+	// OnPingFailed is called whenever any attempt by a node to dial a peer at addr fails. err is always of type
+	// *DialError, which reports the number of times addr was dialed, and whether the failure was served from the
+	// cache of addresses that recently failed to be dialed.
 	OnPingFailed func(addr string, err error)
 
+	// OnIDUpdated is called whenever the ID of a node changes after the node has started listening for new peers,
+	// either through (*Node).UpdateAddress, or through the node detecting its public address from the addresses peers
+	// observe it to have.
+	OnIDUpdated func(id ID)
+
 	// OnMessageSent is called whenever bytes of a message or request or response have been flushed/sent to a peer.
 	OnMessageSent func(client *Client)
 
@@ -1,24 +1,77 @@
 package noise
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
-	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"io"
+	"math"
+	"sync"
 )
 
+// controlNonce is never handed out by a requestMap, whose nonces wrap around before reaching headerBit, and marks a
+// message as being a control frame. The first byte of the data of a control frame denotes its kind, and the remaining
+// bytes are its payload. Control frames are only sent to peers that advertise during the handshake that they
+// understand them.
+const controlNonce = math.MaxUint64
+
+// headerBit is set on the nonce of a message which carries headers, whose header block follows the nonce. It is never
+// set on a nonce handed out by a requestMap, nor on the nonce of a control frame.
+const headerBit = 1 << 63
+
+type controlKind byte
+
+const (
+	controlHeartbeat controlKind = iota + 1
+	controlHeartbeatAck
+	controlObservedAddress
+	controlRelayOpen
+	controlRelayOpenAck
+	controlRelayIncoming
+	controlRelayData
+	controlRelayClose
+	controlCancel
+	controlStreamCredit
+)
+
+func marshalControl(kind controlKind, payload []byte) []byte {
+	return append([]byte{byte(kind)}, payload...)
+}
+
+// message is comprised of an 8-byte big-endian nonce followed by its data. Should the message carry any headers,
+// headerBit is set on its nonce, and the nonce is followed by the header block of the message before its data. See
+// marshalHeader for the format of the header block.
 type message struct {
-	nonce uint64
-	data  []byte
+	nonce  uint64
+	header Header
+	data   []byte
 }
 
-func (m message) marshal(dst []byte) []byte {
+// marshal appends the message to dst. It returns an error should the headers of the message not fit within its
+// header block.
+func (m message) marshal(dst []byte) ([]byte, error) {
+	nonce := m.nonce
+
+	headers := nonce != controlNonce && !m.header.IsZero()
+	if headers {
+		nonce |= headerBit
+	}
+
 	dst = append(dst, make([]byte, 8)...)
-	binary.BigEndian.PutUint64(dst[:8], m.nonce)
+	binary.BigEndian.PutUint64(dst[len(dst)-8:], nonce)
+
+	if headers {
+		var err error
+
+		if dst, err = marshalHeader(dst, m.header); err != nil {
+			return dst[:len(dst)-8], err
+		}
+	}
+
 	dst = append(dst, m.data...)
 
-	return dst
+	return dst, nil
 }
 
 func unmarshalMessage(data []byte) (message, error) {
@@ -26,10 +79,48 @@ func unmarshalMessage(data []byte) (message, error) {
 		return message{}, io.ErrUnexpectedEOF
 	}
 
-	nonce := binary.BigEndian.Uint64(data[:8])
+	msg := message{nonce: binary.BigEndian.Uint64(data[:8])}
 	data = data[8:]
 
-	return message{nonce: nonce, data: data}, nil
+	if msg.nonce != controlNonce && msg.nonce&headerBit != 0 {
+		header, n, err := unmarshalHeader(data)
+		if err != nil {
+			return message{}, err
+		}
+
+		msg.nonce &^= headerBit
+		msg.header = header
+
+		data = data[n:]
+	}
+
+	msg.data = data
+
+	return msg, nil
+}
+
+type responseStatus byte
+
+const (
+	responsePending responseStatus = iota
+	responseStreaming
+	responseSent
+)
+
+// responseState tracks whether the request being handled has been responded to, and is shared amongst all copies of
+// the HandlerContext the request is handled under.
+type responseState struct {
+	sync.Mutex
+	status responseStatus
+
+	// credit is the number of responses that may yet be streamed back before the requester grants more credit, and
+	// granted is signalled whenever credit is granted.
+	credit  int
+	granted chan struct{}
+}
+
+func newResponseState() *responseState {
+	return &responseState{credit: streamBufferSize, granted: make(chan struct{}, 1)}
 }
 
 // HandlerContext provides contextual information upon the recipient of data from an inbound/outbound connection. It
@@ -37,7 +128,14 @@ func unmarshalMessage(data []byte) (message, error) {
 type HandlerContext struct {
 	client *Client
 	msg    message
-	sent   atomic.Bool
+
+	response *responseState
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// decoded is the message the data was decoded into should it have been routed via (*Node).HandleMessage.
+	decoded Serializable
 }
 
 // ID returns the ID of the inbound/outbound peer that sent you the data that is currently being handled.
@@ -50,6 +148,20 @@ func (ctx *HandlerContext) Logger() *zap.Logger {
 	return ctx.client.Logger()
 }
 
+// Context returns the context the data that is currently being handled is handled under. It is cancelled once the
+// connection to the peer is closed, once the node is closed, once the deadline the peer set on its request elapses,
+// or once the peer gives up on its request. It is also cancelled once all handlers have handled the data, and thus
+// should not be used by work that outlives the handler.
+//
+// Context may be called concurrently.
+func (ctx *HandlerContext) Context() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+
+	return ctx.ctx
+}
+
 // Data returns the raw bytes that some peer has sent to you.
 //
 // Data may be called concurrently.
@@ -57,6 +169,30 @@ func (ctx *HandlerContext) Data() []byte {
 	return ctx.msg.data
 }
 
+// Trace returns the trace context the peer sent the data that is currently being handled under. It returns an
+// invalid, zero-value trace context should the peer not have sent one. See ContextWithTrace.
+//
+// Trace may be called concurrently.
+func (ctx *HandlerContext) Trace() TraceContext {
+	return ctx.msg.header.Trace
+}
+
+// Header returns the headers the peer sent the data that is currently being handled with. It returns a zero-value
+// header should the peer not have sent any. See ContextWithHeader.
+//
+// Header may be called concurrently.
+func (ctx *HandlerContext) Header() Header {
+	return ctx.msg.header
+}
+
+// Opcode returns the opcode the data that is currently being handled is prefixed with, should the data be of a Go
+// type registered via (*Node).RegisterMessage. It returns false otherwise.
+//
+// Opcode may be called concurrently.
+func (ctx *HandlerContext) Opcode() (uint16, bool) {
+	return ctx.client.node.codec.opcode(ctx.Data())
+}
+
 // IsRequest marks whether or not the data received was intended to be of a request.
 //
 // IsRequest may be called concurrently.
@@ -66,17 +202,62 @@ func (ctx *HandlerContext) IsRequest() bool {
 
 // Send sends data back to the peer that has sent you data. Should the data the peer send you be of a request, Send
 // will send data back as a response. It returns an error if multiple responses attempt to be sent to a single request,
-// or if an error occurred while attempting to send the peer a message.
+// if responses are being streamed back to the request, or if an error occurred while attempting to send the peer a
+// message.
 //
 // Send may be called concurrently.
 func (ctx *HandlerContext) Send(data []byte) error {
-	if ctx.IsRequest() && !ctx.sent.CAS(false, true) {
+	if !ctx.IsRequest() {
+		return ctx.client.send(ctx.msg.nonce, data)
+	}
+
+	ctx.response.Lock()
+	defer ctx.response.Unlock()
+
+	switch ctx.response.status {
+	case responseStreaming:
+		return errors.New("server-side may not send back a single response to a request it streams responses to")
+	case responseSent:
 		return errors.New("server-side may only send back a single response to a request")
 	}
 
+	ctx.response.status = responseSent
+
 	return ctx.client.send(ctx.msg.nonce, data)
 }
 
+// SendError responds to the request that some peer has sent you with err, which the peer receives as a *RemoteError
+// returned from (*Node).Request. Should responses be being streamed back to the request, SendError ends the stream
+// with err. It returns an error if the data received is not of a request, if a response was already sent to the
+// request, or if the peer predates error responses. See (*Client).EnvelopeVersion.
+//
+// SendError may be called concurrently.
+func (ctx *HandlerContext) SendError(err *RemoteError) error {
+	if !ctx.IsRequest() {
+		return errors.New("server-side may only send back an error in response to a request")
+	}
+
+	if ctx.client.envelope == 0 {
+		return errors.New("peer does not support receiving errors in response to a request")
+	}
+
+	ctx.response.Lock()
+	defer ctx.response.Unlock()
+
+	header := Header{err: err}
+
+	switch ctx.response.status {
+	case responseStreaming:
+		header.stream = streamEnd
+	case responseSent:
+		return errors.New("server-side may only send back a single response to a request")
+	}
+
+	ctx.response.status = responseSent
+
+	return ctx.client.enqueue(message{nonce: ctx.msg.nonce, header: header})
+}
+
 // DecodeMessage decodes the raw bytes that some peer has sent you into a Go type. The Go type must have previously
 // been registered to the node to which the handler this context is under was registered on. An error is thrown
 // otherwise.
@@ -86,6 +267,10 @@ func (ctx *HandlerContext) Send(data []byte) error {
 //
 // DecodeMessage may be called concurrently.
 func (ctx *HandlerContext) DecodeMessage() (Serializable, error) {
+	if ctx.decoded != nil {
+		return ctx.decoded, nil
+	}
+
 	return ctx.client.node.DecodeMessage(ctx.Data())
 }
 
@@ -104,5 +289,9 @@ func (ctx *HandlerContext) SendMessage(msg Serializable) error {
 		return err
 	}
 
+	if data, err = ctx.client.remap.apply(data); err != nil {
+		return err
+	}
+
 	return ctx.Send(data)
 }
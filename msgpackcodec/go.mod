@@ -0,0 +1,12 @@
+module github.com/perlin-network/noise/msgpackcodec
+
+go 1.13
+
+require (
+	github.com/perlin-network/noise v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.2
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	go.uber.org/goleak v1.0.0
+)
+
+replace github.com/perlin-network/noise => ../
@@ -0,0 +1,44 @@
+// Package msgpackcodec provides a noise.Codec which encodes Go types as MessagePack via
+// github.com/vmihailenco/msgpack/v5, such that structs annotated with `msgpack` field tags may be registered to a
+// node without hand-written Marshal methods nor deserialize functions. They are sent and received wrapped in a
+// noise.CodecMessage:
+//
+//	type Page struct {
+//		Cursor  string   `msgpack:"cursor"`
+//		Entries []string `msgpack:"entries"`
+//	}
+//
+//	msgpackcodec.Register(node, Page{})
+//	node.SendMessage(ctx, addr, noise.CodecMessage{Value: Page{...}})
+package msgpackcodec
+
+import (
+	"github.com/perlin-network/noise"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes and decodes Go types as MessagePack.
+var Codec noise.Codec = codec{}
+
+type codec struct{}
+
+func (codec) Marshal(msg interface{}) ([]byte, error) {
+	return msgpack.Marshal(msg)
+}
+
+func (codec) Unmarshal(data []byte, msg interface{}) error {
+	return msgpack.Unmarshal(data, msg)
+}
+
+// Register registers the Go types of all msgs to node under Codec, and returns the opcodes they were registered
+// under. It panics should any of the opcodes collide with those of types already registered to node. See
+// (*noise.Node).RegisterMessageCodec.
+func Register(node *noise.Node, msgs ...interface{}) []uint16 {
+	opcodes := make([]uint16, 0, len(msgs))
+
+	for _, msg := range msgs {
+		opcodes = append(opcodes, node.RegisterMessageCodec(msg, Codec))
+	}
+
+	return opcodes
+}
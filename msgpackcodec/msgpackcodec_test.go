@@ -0,0 +1,107 @@
+package msgpackcodec_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/msgpackcodec"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"testing"
+)
+
+type query struct {
+	Cursor string `msgpack:"cursor"`
+	Limit  int    `msgpack:"limit"`
+}
+
+type page struct {
+	Entries []string          `msgpack:"entries"`
+	Labels  map[string]uint64 `msgpack:"labels"`
+	Next    *query            `msgpack:"next,omitempty"`
+}
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	node, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	opcodes := msgpackcodec.Register(node, query{}, &page{})
+	assert.Len(t, opcodes, 2)
+
+	for _, msg := range []interface{}{
+		query{Cursor: "abc", Limit: 10},
+		&page{Entries: []string{"a", "b"}, Labels: map[string]uint64{"size": 2}, Next: &query{Cursor: "abd"}},
+		&page{},
+	} {
+		data, err := node.EncodeMessage(noise.CodecMessage{Value: msg})
+		assert.NoError(t, err)
+
+		decoded, err := node.DecodeMessage(data)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, decoded.(noise.CodecMessage).Value)
+	}
+
+	// Values of a type registered as a pointer are encoded all the same.
+
+	data, err := node.EncodeMessage(noise.CodecMessage{Value: &query{Cursor: "abc"}})
+	assert.NoError(t, err)
+
+	decoded, err := node.DecodeMessage(data)
+	assert.NoError(t, err)
+	assert.Equal(t, query{Cursor: "abc"}, decoded.(noise.CodecMessage).Value)
+
+	_, err = node.DecodeMessage(append(data[:2:2], 0xc1))
+	assert.Error(t, err)
+}
+
+func TestRequestMessage(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	for _, node := range []*noise.Node{a, b} {
+		msgpackcodec.Register(node, query{}, page{})
+	}
+
+	b.HandleMessage(noise.CodecMessage{Value: query{}}, func(ctx noise.HandlerContext, req query) error {
+		for i := 0; i < req.Limit; i++ {
+			if err := ctx.SendStreamMessage(noise.CodecMessage{Value: page{Entries: []string{req.Cursor}}}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	req := noise.CodecMessage{Value: query{Cursor: "abc", Limit: 3}}
+
+	stream, err := a.RequestMessageStream(context.Background(), b.Addr(), req)
+	assert.NoError(t, err)
+
+	defer stream.Close()
+
+	var pages []interface{}
+
+	for stream.Next() {
+		msg, err := stream.Message()
+		assert.NoError(t, err)
+
+		pages = append(pages, msg.(noise.CodecMessage).Value)
+	}
+
+	assert.NoError(t, stream.Err())
+	assert.Len(t, pages, 3)
+	assert.Equal(t, page{Entries: []string{"abc"}}, pages[0])
+}
@@ -2,12 +2,14 @@ package noise
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/oasislabs/ed25519"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 	"net"
+	"reflect"
 	"runtime"
 	"strconv"
 	"sync"
@@ -30,10 +32,28 @@ type Node struct {
 
 	publicKey  PublicKey
 	privateKey PrivateKey
+	signer     Signer
+	key        IdentityKey
 
-	id ID
+	id     ID
+	idLock sync.RWMutex
+
+	// signLock serializes updates to the ID of this node, which are signed without the ID lock held as the signer of
+	// this node may be remote.
+	signLock sync.Mutex
+
+	detectAddress            bool
+	observedAddressThreshold uint
+
+	advertised []PeerAddress
+	metadata   map[string]string
+	observed   *observedAddrMap
 
 	maxDialAttempts        uint
+	minDialBackoff         time.Duration
+	maxDialBackoff         time.Duration
+	dialFailureCooldown    time.Duration
+	dialStagger            time.Duration
 	maxInboundConnections  uint
 	maxOutboundConnections uint
 	maxRecvMessageSize     uint32
@@ -41,19 +61,51 @@ type Node struct {
 
 	idleTimeout time.Duration
 
+	heartbeatInterval  time.Duration
+	heartbeatMaxMissed uint
+
+	recordTTL time.Duration
+
 	listener  net.Listener
 	listening atomic.Bool
 
 	outbound *clientMap
 	inbound  *clientMap
 
-	codec     *codec
-	protocols []Protocol
-	handlers  []Handler
+	dialFailures *dialFailureMap
+	resolver     Resolver
+	resolved     *resolvedHostMap
+	persistent   *persistentPeerMap
+	relays       *relayMap
+
+	metrics *nodeMetrics
+
+	codec         *codec
+	remapOpcodes  bool
+	legacyOpcodes bool
+	protocols     []Protocol
+
+	// handlersLock guards handlers, routes, fallback, middleware, interceptors, and dispatch, which may be
+	// registered concurrently, including once the node has started listening.
+	handlersLock sync.RWMutex
+	handlers     []Handler
+	routes       map[uint16]Handler
+	fallback     Handler
+	middleware   []Middleware
+	interceptors []Interceptor
+
+	// dispatch dispatches a message to all handlers and routes, and is wrapped once with all middleware. It is set
+	// once the node starts listening, and is rebuilt whenever handlers, routes, fallback, or middleware are
+	// registered thereafter.
+	dispatch Handler
 
 	workers sync.WaitGroup
 	work    chan HandlerContext
 
+	// ctx is cancelled once the node is closed, and is the parent of the context of every client.
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	listenerDone chan error
 }
 
@@ -63,13 +115,27 @@ func NewNode(opts ...NodeOption) (*Node, error) {
 	n := &Node{
 		listenerDone: make(chan error, 1),
 
+		persistent: newPersistentPeerMap(),
+		observed:   newObservedAddrMap(),
+		relays:     newRelayMap(),
+		metrics:    newNodeMetrics(),
+
 		maxDialAttempts:        3,
+		minDialBackoff:         50 * time.Millisecond,
+		maxDialBackoff:         1 * time.Second,
+		dialFailureCooldown:    3 * time.Second,
+		dialStagger:            250 * time.Millisecond,
 		maxInboundConnections:  128,
 		maxOutboundConnections: 128,
 		maxRecvMessageSize:     4 << 20,
 		numWorkers:             uint(runtime.NumCPU()),
+
+		heartbeatMaxMissed: 3,
+		recordTTL:          1 * time.Hour,
 	}
 
+	n.ctx, n.cancel = context.WithCancel(context.Background())
+
 	for _, opt := range opts {
 		opt(n)
 	}
@@ -78,25 +144,52 @@ func NewNode(opts ...NodeOption) (*Node, error) {
 		n.logger = zap.NewNop()
 	}
 
-	if n.privateKey == ZeroPrivateKey {
-		_, privateKey, err := ed25519.GenerateKey(nil)
-		if err != nil {
-			return nil, err
+	if n.signer == nil {
+		if n.privateKey == ZeroPrivateKey {
+			_, privateKey, err := ed25519.GenerateKey(nil)
+			if err != nil {
+				return nil, err
+			}
+
+			copy(n.privateKey[:], privateKey)
 		}
 
-		copy(n.privateKey[:], privateKey)
+		n.signer = NewSigner(n.privateKey)
 	}
 
-	copy(n.publicKey[:], ed25519.PrivateKey(n.privateKey[:]).Public().(ed25519.PublicKey)[:])
+	n.key = signerKey(n.signer)
+	n.publicKey = n.key.ID()
 
 	if n.id.ID == ZeroPublicKey && n.host != nil && n.port > 0 {
-		n.id = NewID(n.publicKey, n.host, n.port)
+		id, err := n.record(NewID(n.publicKey, n.host, n.port), ID{})
+		if err != nil {
+			n.logger.Warn("Failed to sign the ID of this node.", zap.Error(err))
+		}
+
+		n.id = id
 	}
 
-	n.inbound = newClientMap(n.maxInboundConnections)
-	n.outbound = newClientMap(n.maxOutboundConnections)
+	n.inbound = newClientMap(n.maxInboundConnections, ClientSideInbound)
+	n.outbound = newClientMap(n.maxOutboundConnections, ClientSideOutbound)
+
+	n.dialFailures = newDialFailureMap()
+
+	n.resolved = newResolvedHostMap()
+
+	if n.resolver == nil {
+		n.resolver = net.DefaultResolver
+	}
 
 	n.codec = newCodec()
+	n.codec.legacy = n.legacyOpcodes
+
+	n.metrics.registry.GaugeFunc("noise_inbound_connections", "Number of pooled inbound connections.", func() float64 {
+		return float64(n.inbound.len())
+	})
+
+	n.metrics.registry.GaugeFunc("noise_outbound_connections", "Number of pooled outbound connections.", func() float64 {
+		return float64(n.outbound.len())
+	})
 
 	return n, nil
 }
@@ -133,35 +226,18 @@ func (n *Node) Listen() error {
 	n.host = addr.IP
 	n.port = uint16(addr.Port)
 
+	n.detectAddress = n.addr == "" && n.observedAddressThreshold > 0
+
 	if n.addr == "" {
-		n.addr = net.JoinHostPort(normalizeIP(n.host), strconv.FormatUint(uint64(n.port), 10))
-		n.id = NewID(n.publicKey, n.host, n.port)
+		n.setID(NewID(n.publicKey, n.host, n.port))
 	} else {
-		resolved, err := ResolveAddress(n.addr)
-		if err != nil {
-			n.listener.Close()
-			return err
-		}
-
-		hostStr, portStr, err := net.SplitHostPort(resolved)
+		id, err := n.resolveID(n.addr)
 		if err != nil {
 			n.listener.Close()
 			return err
 		}
 
-		host := net.ParseIP(hostStr)
-		if host == nil {
-			n.listener.Close()
-			return errors.New("host in provided public address is invalid (must be IPv4/IPv6)")
-		}
-
-		port, err := strconv.ParseUint(portStr, 10, 16)
-		if err != nil {
-			n.listener.Close()
-			return err
-		}
-
-		n.id = NewID(n.publicKey, host, uint16(port))
+		n.setID(id)
 	}
 
 	for _, protocol := range n.protocols {
@@ -175,30 +251,32 @@ func (n *Node) Listen() error {
 		}
 	}
 
-	n.work = make(chan HandlerContext, int(n.numWorkers))
+	n.handlersLock.Lock()
+	n.rebuildDispatch()
+	n.handlersLock.Unlock()
+
+	work := make(chan HandlerContext, int(n.numWorkers))
+
+	n.work = work
 	n.workers.Add(int(n.numWorkers))
 
+	n.metrics.registry.GaugeFunc("noise_worker_queue_depth", "Number of messages awaiting a handler worker.", func() float64 {
+		return float64(len(work))
+	})
+
 	for i := uint(0); i < n.numWorkers; i++ {
 		go func() {
 			defer n.workers.Done()
 
 			for ctx := range n.work {
-				for _, handler := range n.handlers {
-					if err := handler(ctx); err != nil {
-						ctx.client.Logger().Warn("Got an error executing a message handler.", zap.Error(err))
-						ctx.client.reportError(err)
-						ctx.client.close()
-
-						return
-					}
-				}
+				n.process(ctx)
 			}
 		}()
 	}
 
-	go func() {
-		n.listening.Store(true)
+	n.listening.Store(true)
 
+	go func() {
 		defer func() {
 			n.inbound.release()
 
@@ -211,9 +289,8 @@ func (n *Node) Listen() error {
 
 		n.logger.Info("Listening for incoming peers.",
 			zap.String("bind_addr", addr.String()),
-			zap.String("id_addr", n.id.Address),
+			zap.String("id_addr", n.ID().Address),
 			zap.String("public_key", n.publicKey.String()),
-			zap.String("private_key", n.privateKey.String()),
 		)
 
 		for {
@@ -232,6 +309,8 @@ func (n *Node) Listen() error {
 		}
 	}()
 
+	n.persistent.start(n)
+
 	return nil
 }
 
@@ -241,18 +320,53 @@ func (n *Node) Listen() error {
 //
 //  RegisterMessage(T{}, func([]byte) (T, error) { ... })
 //
-// It returns a 16-bit unsigned integer (opcode) that is associated to the type T on-the-wire. Once a Go type has been
-// registered, it may be used in a Handler, or via (*Node).EncodeMessage, (*Node).DecodeMessage, (*Node).SendMessage,
-// and (*Node).RequestMessage.
+// It returns a 16-bit unsigned integer (opcode) that is associated to the type T on-the-wire, which is derived from the
+// package path and name of T such that it does not depend on the order types are registered in, unless legacy opcodes
+// are enabled via WithNodeLegacyOpcodes. RegisterMessage panics should the opcode collide with the opcode of a type
+// already registered, in which case either type should be registered under an explicit opcode via
+// (*Node).RegisterMessageWithOpcode. Once a Go type has been registered, it may be used in a Handler, or via
+// (*Node).EncodeMessage, (*Node).DecodeMessage, (*Node).SendMessage, and (*Node).RequestMessage.
 //
 // The wire format of a type registered comprises of
 // append([]byte{16-bit big-endian integer (opcode)}, ser.Marshal()...).
 //
+// The opcodes of all registered types are exchanged with peers during the handshake. See WithNodeOpcodeRemapping and
+// WithNodeLegacyOpcodes.
+//
 // RegisterMessage may be called concurrently, though is discouraged.
 func (n *Node) RegisterMessage(ser Serializable, de interface{}) uint16 {
 	return n.codec.register(ser, de)
 }
 
+// RegisterMessageWithOpcode registers a Go type T under an explicitly assigned opcode. It panics should opcode
+// already be registered to another type. For more details, refer to (*Node).RegisterMessage.
+//
+// RegisterMessageWithOpcode may be called concurrently, though is discouraged.
+func (n *Node) RegisterMessageWithOpcode(ser Serializable, de interface{}, opcode uint16) uint16 {
+	return n.codec.registerWithOpcode(ser, de, opcode)
+}
+
+// RegisterMessageCodec registers the Go type T of msg, whose values are encoded and decoded by codec rather than by
+// a Marshal method and a deserialize function. T may be a pointer type, in which case values decoded are pointers to
+// newly allocated values. Values of T are wrapped in a CodecMessage to be encoded, sent, and decoded via
+// (*Node).EncodeMessage, (*Node).SendMessage, (*Node).RequestMessage, and their variants. Adapters for protocol
+// buffers and MessagePack are available in the protocodec and msgpackcodec modules. For more details, refer to
+// (*Node).RegisterMessage.
+//
+// RegisterMessageCodec may be called concurrently, though is discouraged.
+func (n *Node) RegisterMessageCodec(msg interface{}, codec Codec) uint16 {
+	return n.codec.registerCodec(msg, codec)
+}
+
+// RegisterMessageCodecWithOpcode registers the Go type T of msg, whose values are encoded and decoded by codec, under
+// an explicitly assigned opcode. It panics should opcode already be registered to another type. For more details,
+// refer to (*Node).RegisterMessageCodec.
+//
+// RegisterMessageCodecWithOpcode may be called concurrently, though is discouraged.
+func (n *Node) RegisterMessageCodecWithOpcode(msg interface{}, codec Codec, opcode uint16) uint16 {
+	return n.codec.registerCodecWithOpcode(msg, codec, opcode)
+}
+
 // EncodeMessage encodes msg which must be a registered Go type T into its wire representation. It throws an error
 // if the Go type of msg has not yet been registered through (*Node).RegisterMessage. For more details, refer to
 // (*Node).RegisterMessage.
@@ -279,7 +393,7 @@ func (n *Node) SendMessage(ctx context.Context, addr string, msg Serializable) e
 		return err
 	}
 
-	return n.Send(ctx, addr, data)
+	return n.invoke(ctx, &Call{Addr: addr, Message: msg, Opcode: binary.BigEndian.Uint16(data[:2]), Data: data})
 }
 
 // RequestMessage encodes msg which is a Go type registered via (*Node).RegisterMessage, and sends it as a request
@@ -291,12 +405,13 @@ func (n *Node) RequestMessage(ctx context.Context, addr string, req Serializable
 		return nil, fmt.Errorf("failed to encode request: %w", err)
 	}
 
-	data, err = n.Request(ctx, addr, data)
-	if err != nil {
+	call := &Call{Addr: addr, Request: true, Message: req, Opcode: binary.BigEndian.Uint16(data[:2]), Data: data}
+
+	if err := n.invoke(ctx, call); err != nil {
 		return nil, err
 	}
 
-	res, err := n.DecodeMessage(data)
+	res, err := n.DecodeMessage(call.Response)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode request: %w", err)
 	}
@@ -313,17 +428,11 @@ func (n *Node) RequestMessage(ctx context.Context, addr string, req Serializable
 //
 // If there is no available connection from this nodes connection pool, the connection that is at the tail of the pool
 // is closed and evicted and used to send data to addr.
+//
+// Should ctx carry headers via ContextWithHeader, or a trace context via ContextWithTrace, they are sent alongside
+// data.
 func (n *Node) Send(ctx context.Context, addr string, data []byte) error {
-	c, err := n.dialIfNotExists(ctx, addr)
-	if err != nil {
-		return err
-	}
-
-	if err := c.send(0, data); err != nil {
-		return err
-	}
-
-	return nil
+	return n.invoke(ctx, &Call{Addr: addr, Data: data})
 }
 
 // Request takes an available connection from this nodes connection pool if the peer at addr has never been connected
@@ -332,7 +441,7 @@ func (n *Node) Send(ctx context.Context, addr string, data []byte) error {
 //
 // Once the request has been sent, the current goroutine Request was called in will block until either
 // a response has been received which will be subsequently returned, ctx was canceled/expired, or the connection was
-// dropped.
+// dropped, in which case io.EOF is returned.
 //
 // If there already exists a live connection to the peer at addr, no new connection is established and the request
 // will follow through. An error is returned if connecting to the peer should it not have been connected to before
@@ -340,18 +449,75 @@ func (n *Node) Send(ctx context.Context, addr string, data []byte) error {
 //
 // If there is no available connection from this nodes connection pool, the connection that is at the tail of the pool
 // is closed and evicted and used to send a request to addr.
+//
+// Should ctx carry headers via ContextWithHeader, or a trace context via ContextWithTrace, they are sent alongside
+// the request.
 func (n *Node) Request(ctx context.Context, addr string, data []byte) ([]byte, error) {
-	c, err := n.dialIfNotExists(ctx, addr)
-	if err != nil {
-		return nil, err
-	}
+	call := &Call{Addr: addr, Request: true, Data: data}
 
-	msg, err := c.request(ctx, data)
-	if err != nil {
+	if err := n.invoke(ctx, call); err != nil {
 		return nil, err
 	}
 
-	return msg.data, nil
+	return call.Response, nil
+}
+
+// invoke sends the data described by call through all interceptors registered to this node. The peer of call is
+// dialed once all interceptors have been run through should it not have been connected to before, such that
+// interceptors may deny a call before the peer is dialed, or redirect it to another address.
+func (n *Node) invoke(ctx context.Context, call *Call) error {
+	call.Header = HeaderFromContext(ctx)
+
+	invoke := chainInvoker(func(ctx context.Context, call *Call) error {
+		c, err := n.dialIfNotExists(ctx, call.Addr)
+		if err != nil {
+			return err
+		}
+
+		call.Peer = c.ID()
+
+		data := call.Data
+
+		if call.Message != nil {
+			if data, err = c.remap.apply(data); err != nil {
+				return err
+			}
+		}
+
+		if !call.Request {
+			return c.enqueue(message{header: call.Header, data: data})
+		}
+
+		if call.Stream {
+			stream, err := c.requestStream(ctx, call.Header, data)
+			if err != nil {
+				return err
+			}
+
+			call.stream = stream
+
+			return nil
+		}
+
+		msg, err := c.request(ctx, call.Header, data)
+		if err != nil {
+			return err
+		}
+
+		call.Response = msg.data
+
+		return nil
+	}, n.registeredInterceptors())
+
+	return invoke(ctx, call)
+}
+
+// registeredInterceptors returns all interceptors registered to this node.
+func (n *Node) registeredInterceptors() []Interceptor {
+	n.handlersLock.RLock()
+	defer n.handlersLock.RUnlock()
+
+	return n.interceptors
 }
 
 // Ping takes an available connection from this nodes connection pool if the peer at addr has never been connected
@@ -370,12 +536,204 @@ func (n *Node) Ping(ctx context.Context, addr string) (*Client, error) {
 	return n.dialIfNotExists(ctx, addr)
 }
 
-// Close gracefully stops all live inbound/outbound peer connections registered on this node, and stops the node
-// from handling/accepting new incoming peer connections. It returns an error if an error occurs closing the nodes
-// listener. Nodes that are closed should not ever be re-used.
+// AddPersistentPeer registers addr as the address of a persistent peer. A node keeps a live connection to all of its
+// persistent peers at all times once it starts listening for new peers, redialing them with a jittered exponential
+// backoff configured via WithNodeDialBackoff whenever connecting to them fails or their connection drops. Persistent
+// peers are redialed even if they recently failed to be dialed. Registering an address which is already registered
+// as a persistent peer does nothing.
+//
+// AddPersistentPeer may be called concurrently.
+func (n *Node) AddPersistentPeer(addr string) {
+	n.persistent.add(addr, ZeroPublicKey)
+}
+
+// AddPersistentPeerID registers id as a persistent peer. It is the same as (*Node).AddPersistentPeer, except that
+// a connection to the peer at id.Address is dropped and redialed should the peer not have id.ID as its public key.
+//
+// AddPersistentPeerID may be called concurrently.
+func (n *Node) AddPersistentPeerID(id ID) {
+	n.persistent.add(id.Address, id.ID)
+}
+
+// RemovePersistentPeer stops keeping a live connection to the persistent peer at addr. Any live connection to the
+// peer is left open, and is subject to being evicted from the nodes connection pool as any other connection is.
+//
+// RemovePersistentPeer may be called concurrently.
+func (n *Node) RemovePersistentPeer(addr string) {
+	n.persistent.remove(addr)
+}
+
+// PersistentPeers returns a snapshot of the state of all persistent peers of this node sorted by their address.
+//
+// PersistentPeers may be called concurrently.
+func (n *Node) PersistentPeers() []PersistentPeer {
+	return n.persistent.slice()
+}
+
+// PingAny is the same as (*Node).Ping, except that addrs are all candidate addresses of a single peer. Should there
+// not already be a live connection to the peer through any one of addrs, the peer is dialed through addrs with
+// staggered connection attempts following RFC 8305 (Happy Eyeballs Version 2).
+//
+// Candidate addresses are ordered by interleaving their address families while preserving the order of addresses
+// within each family, with the family of the first address in addrs being preferred. The first candidate is dialed
+// immediately, and every subsequent candidate is dialed either once the delay configured via WithNodeDialStagger
+// has elapsed, or once the previously dialed candidate fails. The first connection to successfully complete noise's
+// handshake is returned, and all other connection attempts are cancelled and closed.
+//
+// Failures to dial candidates are only reported to (Protocol).OnPingFailed should all candidates fail to be dialed,
+// in which case the error of the last candidate to fail is returned.
+//
+// It is safe to call PingAny concurrently.
+func (n *Node) PingAny(ctx context.Context, addrs ...string) (*Client, error) {
+	return n.dialIfNotExists(ctx, addrs...)
+}
+
+// process handles a message taken off of the work queue by a worker.
+func (n *Node) process(ctx HandlerContext) {
+	n.metrics.messagesRecv.Inc()
+
+	n.handle(ctx)
+	ctx.client.handled(ctx)
+}
+
+// lendWorker starts an extra worker which takes over handling messages off of the work queue while a handler is
+// blocked, such that blocked handlers do not starve the node of workers. The extra worker exits once resume is
+// called and it is done handling its current message, if any.
+func (n *Node) lendWorker() (resume func()) {
+	resumed := make(chan struct{})
+
+	n.workers.Add(1)
+
+	go func() {
+		defer n.workers.Done()
+
+		for {
+			select {
+			case ctx, ok := <-n.work:
+				if !ok {
+					return
+				}
+
+				n.process(ctx)
+			case <-resumed:
+				return
+			}
+		}
+	}()
+
+	return func() { close(resumed) }
+}
+
+// handle dispatches ctx through all middleware to all handlers. Should a handler or middleware return a *RemoteError,
+// the error is sent back in response to the request being handled, if any. Should a handler or middleware return any
+// other error, or should the peer predate error responses, the connection to the peer is closed.
+func (n *Node) handle(ctx HandlerContext) {
+	n.handlersLock.RLock()
+	dispatch := n.dispatch
+	n.handlersLock.RUnlock()
+
+	if err := dispatch(ctx); err != nil {
+		n.handlerFailed(ctx, err)
+	}
+}
+
+// rebuildDispatch snapshots all handlers, routes, the fallback handler, and all middleware registered to this node
+// into dispatch. It must be called with handlersLock held.
+func (n *Node) rebuildDispatch() {
+	handlers := append([]Handler{}, n.handlers...)
+
+	if n.fallback != nil {
+		handlers = append(handlers, n.fallback)
+	}
+
+	routes := make(map[uint16]Handler, len(n.routes))
+
+	for opcode, route := range n.routes {
+		routes[opcode] = route
+	}
+
+	middleware := append([]Middleware{}, n.middleware...)
+
+	n.dispatch = chainHandler(n.dispatcher(handlers, routes), middleware)
+}
+
+// handlersUpdated rebuilds dispatch should the node have started listening, such that handlers, routes, and
+// middleware registered thereafter take effect. It must be called with handlersLock held.
+func (n *Node) handlersUpdated() {
+	if n.dispatch != nil {
+		n.rebuildDispatch()
+	}
+}
+
+// dispatcher returns a Handler which has the handler in routes routed to the opcode of a message handle it, or
+// otherwise has all handlers handle it in order. Should a handler return an error, the remaining handlers are
+// skipped and the error is returned.
+func (n *Node) dispatcher(handlers []Handler, routes map[uint16]Handler) Handler {
+	return func(ctx HandlerContext) error {
+		if opcode, ok := n.codec.opcode(ctx.Data()); ok {
+			if route, routed := routes[opcode]; routed {
+				msg, err := n.DecodeMessage(ctx.Data())
+				if err != nil {
+					return fmt.Errorf("failed to decode message under opcode %d: %w", opcode, err)
+				}
+
+				ctx.decoded = msg
+
+				return route(ctx)
+			}
+		}
+
+		for _, handler := range handlers {
+			if err := handler(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// handlerFailed responds to the request being handled under ctx with err should err be a *RemoteError, or otherwise
+// closes the connection to the peer.
+func (n *Node) handlerFailed(ctx HandlerContext, err error) {
+	n.metrics.handlerErrors.Inc()
+
+	// Handlers that give up as the peer gave up on its request, or as the connection closed, do not fail the
+	// connection.
+
+	if cause := ctx.Context().Err(); cause != nil && errors.Is(err, cause) {
+		ctx.client.Logger().Debug("A message handler gave up handling a message.", zap.Error(err))
+		return
+	}
+
+	var remote *RemoteError
+
+	if errors.As(err, &remote) && ctx.client.envelope > 0 {
+		if ctx.IsRequest() {
+			if err := ctx.SendError(remote); err != nil {
+				ctx.client.Logger().Warn("Got an error responding to a request with an error.", zap.Error(err))
+			}
+		}
+
+		ctx.client.Logger().Debug("A message handler returned an error to the peer.", zap.Error(err))
+
+		return
+	}
+
+	ctx.client.Logger().Warn("Got an error executing a message handler.", zap.Error(err))
+	ctx.client.reportError(err)
+	ctx.client.close()
+}
+
+// Close gracefully stops all live inbound/outbound peer connections registered on this node, stops maintaining
+// connections to persistent peers, and stops the node from handling/accepting new incoming peer connections. It
+// returns an error if an error occurs closing the nodes listener. Nodes that are closed should not ever be re-used.
 //
 // Close may be called concurrently.
 func (n *Node) Close() error {
+	n.persistent.stop()
+	n.cancel()
+
 	if n.listening.CAS(true, false) {
 		if err := n.listener.Close(); err != nil {
 			return err
@@ -387,12 +745,61 @@ func (n *Node) Close() error {
 	return nil
 }
 
-func (n *Node) dialIfNotExists(ctx context.Context, addr string) (*Client, error) {
-	var err error
+func (n *Node) dialIfNotExists(ctx context.Context, addrs ...string) (*Client, error) {
+	if len(addrs) > 1 {
+		addrs = interleaveAddrs(addrs)
+	}
+
+	if len(addrs) == 1 {
+		client, err := n.dial(ctx, addrs[0], true)
+		if err != nil {
+			n.reportPingFailed(err)
+			return nil, err
+		}
+
+		return client, nil
+	}
+
+	client, errs := n.dialAny(ctx, addrs)
+	if client != nil {
+		return client, nil
+	}
+
+	for _, err := range errs {
+		n.reportPingFailed(err)
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("no addresses were provided to dial")
+	}
+
+	return nil, errs[len(errs)-1]
+}
+
+func (n *Node) dial(ctx context.Context, addr string, cached bool) (*Client, *DialError) {
+	if cached {
+		if err := n.dialFailures.find(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		err    error
+		dialed bool
+	)
+
+	start := time.Now()
 
 	for i := uint(0); i < n.maxDialAttempts; i++ {
+		if err = waitDialBackoff(ctx, dialBackoff(i, n.minDialBackoff, n.maxDialBackoff)); err != nil {
+			return nil, &DialError{Addr: addr, Attempts: i, Err: err}
+		}
+
 		client, exists := n.outbound.get(n, addr)
 		if !exists {
+			n.metrics.dials.Inc()
+			dialed = true
+
 			go client.outbound(ctx, addr)
 		}
 
@@ -408,6 +815,12 @@ func (n *Node) dialIfNotExists(ctx context.Context, addr string) (*Client, error
 		}
 
 		if err == nil {
+			if dialed {
+				n.metrics.dialDuration.ObserveSince(start)
+			}
+
+			n.dialFailures.forget(addr)
+
 			return client, nil
 		}
 
@@ -415,29 +828,26 @@ func (n *Node) dialIfNotExists(ctx context.Context, addr string) (*Client, error
 		client.waitUntilClosed()
 
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-			for _, protocol := range n.protocols {
-				if protocol.OnPingFailed == nil {
-					continue
-				}
-
-				protocol.OnPingFailed(addr, err)
-			}
-
-			return nil, err
+			return nil, &DialError{Addr: addr, Attempts: i + 1, Err: err}
 		}
 	}
 
-	err = fmt.Errorf("attempted to dial %s several times but failed: %w", addr, err)
+	dialErr := &DialError{Addr: addr, Attempts: n.maxDialAttempts, Err: err}
+	n.dialFailures.record(dialErr, n.dialFailureCooldown)
+
+	return nil, dialErr
+}
+
+func (n *Node) reportPingFailed(err *DialError) {
+	n.metrics.dialFailures.Inc()
 
 	for _, protocol := range n.protocols {
 		if protocol.OnPingFailed == nil {
 			continue
 		}
 
-		protocol.OnPingFailed(addr, err)
+		protocol.OnPingFailed(err.Addr, err)
 	}
-
-	return nil, err
 }
 
 // Bind registers a Protocol to this node, which implements callbacks for all events this node can emit throughout
@@ -456,21 +866,162 @@ func (n *Node) Bind(protocols ...Protocol) {
 
 // Handle registers a Handler to this node, which is executed every time this node receives a message from an
 // inbound/outbound connection. For more information on how to write a Handler, refer to the documentation for
-// Handler. Handle only registers Handler's should the node not yet be listening for new connections. If the node
-// is already listening for new peers, Handle silently returns and does nothing.
+// Handler. Should the node already be listening for new connections, the handlers take effect for all messages
+// dispatched thereafter, including those already awaiting a worker.
 //
 // Handle may be called concurrently.
 func (n *Node) Handle(handlers ...Handler) {
-	if n.listening.Load() {
-		return
-	}
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
 
 	n.handlers = append(n.handlers, handlers...)
+	n.handlersUpdated()
+}
+
+// HandleMessage routes all messages of the Go type of msg, which must have been registered via
+// (*Node).RegisterMessage or (*Node).RegisterMessageCodec, to handler, which must be of the signature
+// func(HandlerContext, T) error. Go types registered via (*Node).RegisterMessageCodec are routed by wrapping a value
+// of them in a CodecMessage, and are handed to handler unwrapped. Messages are decoded once before being handed to
+// handler, and are not handled by any Handler registered via (*Node).Handle.
+// Messages that are not routed to a handler are handled by all Handlers registered via (*Node).Handle, followed by the
+// fallback handler registered via (*Node).HandleFallback. A message of a routed type that fails to be decoded is
+// considered to be a protocol violation, and closes the connection to the peer that sent it.
+//
+// HandleMessage panics should the Go type of msg not be registered, should it already be routed to a handler, or
+// should handler be of an unexpected signature. Should the node already be listening for new connections, handler
+// takes effect for all messages dispatched thereafter, including those already awaiting a worker.
+//
+// HandleMessage may be called concurrently.
+func (n *Node) HandleMessage(msg Serializable, handler interface{}) {
+	t := messageType(msg)
+
+	opcode, registered := n.codec.opcodeOf(t)
+	if !registered {
+		panic(fmt.Errorf("attempted to route type %+v which is not registered", t))
+	}
+
+	h := reflect.ValueOf(handler)
+
+	expected := reflect.FuncOf(
+		[]reflect.Type{reflect.TypeOf(HandlerContext{}), t},
+		[]reflect.Type{reflect.TypeOf((*error)(nil)).Elem()},
+		false,
+	)
+
+	if h.Type() != expected {
+		panic(fmt.Errorf("provided handler for message type %+v is %s, but expected %s", t, h.Type(), expected))
+	}
+
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
+
+	if _, routed := n.routes[opcode]; routed {
+		panic(fmt.Errorf("attempted to route type %+v which is already routed under opcode %d", t, opcode))
+	}
+
+	if n.routes == nil {
+		n.routes = make(map[uint16]Handler)
+	}
+
+	n.routes[opcode] = func(ctx HandlerContext) error {
+		var value interface{} = ctx.decoded
+		if m, ok := value.(CodecMessage); ok {
+			value = m.Value
+		}
+
+		results := h.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(value)})
+
+		if err, _ := results[0].Interface().(error); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	n.handlersUpdated()
+}
+
+// HandleFallback registers a Handler to this node which handles all messages that are not routed to a handler via
+// (*Node).HandleMessage, after all Handlers registered via (*Node).Handle have handled them. It may, for example,
+// respond to requests carrying an unknown opcode with a *RemoteError. Should the node already be listening for new
+// connections, handler takes effect for all messages dispatched thereafter, including those already awaiting a
+// worker.
+//
+// HandleFallback may be called concurrently.
+func (n *Node) HandleFallback(handler Handler) {
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
+
+	n.fallback = handler
+	n.handlersUpdated()
+}
+
+// Use registers Middleware to this node, which wraps the dispatch of every message to all Handlers registered to this
+// node in the order the middleware was registered, such that the first middleware registered is the outermost. For
+// more information, refer to the documentation for Middleware. Should the node already be listening for new
+// connections, the middleware takes effect for all messages dispatched thereafter, including those already awaiting
+// a worker.
+//
+// Use may be called concurrently.
+func (n *Node) Use(middleware ...Middleware) {
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
+
+	n.middleware = append(n.middleware, middleware...)
+	n.handlersUpdated()
+}
+
+// Intercept registers Interceptors to this node, which wrap every call made via (*Node).Send, (*Node).Request,
+// (*Node).SendMessage, and (*Node).RequestMessage in the order the interceptors were registered, such that the first
+// interceptor registered is the outermost. For more information, refer to the documentation for Interceptor.
+// Interceptors registered while calls are in flight only wrap calls made thereafter.
+//
+// Intercept may be called concurrently.
+func (n *Node) Intercept(interceptors ...Interceptor) {
+	n.handlersLock.Lock()
+	defer n.handlersLock.Unlock()
+
+	n.interceptors = append(n.interceptors, interceptors...)
+}
+
+// Metrics returns the registry of metrics recorded by the node, which protocols bound to the node may register their
+// own metrics to. It may be served over HTTP in the Prometheus text-based exposition format, as it implements
+// http.Handler.
+//
+// Metrics may be called concurrently.
+func (n *Node) Metrics() *Metrics {
+	return n.metrics.registry
+}
+
+// Stats returns a snapshot of the values of all metrics recorded by the node. It is shorthand for
+// n.Metrics().Stats().
+//
+// Stats may be called concurrently.
+func (n *Node) Stats() Stats {
+	return n.metrics.registry.Stats()
 }
 
-// Sign uses the nodes private key to sign data and return its cryptographic signature as a slice of bytes.
+// Sign uses the Signer of the node to sign data and return its cryptographic signature. Should the Signer fail to
+// sign data, the failure is logged and a zero signature is returned, which fails to be verified. Use
+// (*Node).TrySign to be returned the error instead.
+//
+// Sign may be called concurrently.
 func (n *Node) Sign(data []byte) Signature {
-	return n.privateKey.Sign(data)
+	signature, err := n.TrySign(data)
+	if err != nil {
+		n.logger.Warn("Failed to sign data.", zap.Error(err))
+		return ZeroSignature
+	}
+
+	return signature
+}
+
+// TrySign uses the Signer of the node to sign data and return its cryptographic signature. It returns an error
+// should the Signer fail to sign data.
+//
+// TrySign may be called concurrently.
+func (n *Node) TrySign(data []byte) (Signature, error) {
+	return n.signer.Sign(data)
 }
 
 // Inbound returns a cloned slice of all inbound connections to this node as Client instances. It is useful
@@ -489,13 +1040,50 @@ func (n *Node) Outbound() []*Client {
 
 // Addr returns the public address of this node. The public address, should it not be configured through the
 // WithNodeAddress functional option when calling NewNode, is initialized to 'host:port' after a successful
-// call to (*Node).Listen.
+// call to (*Node).Listen. Should WithNodeObservedAddressThreshold be specified, the public address may later be
+// updated based on the addresses peers observe this node to have. A public address configured through WithNodeAddress
+// is returned as it was configured, rather than as it was resolved, until it is updated via (*Node).UpdateAddress.
 //
 // Addr may be called concurrently.
 func (n *Node) Addr() string {
+	n.idLock.RLock()
+	defer n.idLock.RUnlock()
+
 	return n.addr
 }
 
+// UpdateAddress resolves and sets addr as the public address of this node, and updates the ID of this node to
+// advertise addr to peers this node has yet to handshake with. Every Protocol bound to this node has
+// OnIDUpdated called should the ID of this node change. It returns an error if addr fails to be resolved. Should
+// the host of addr be a hostname, it is advertised as-is, and resolved by peers whenever they dial this node.
+//
+// Calling UpdateAddress stops this node from updating its public address based on the addresses peers observe this
+// node to have. Should the updated ID of this node fail to be signed, the current ID of this node is kept and an error
+// is returned.
+//
+// UpdateAddress may be called concurrently.
+func (n *Node) UpdateAddress(addr string) error {
+	id, err := n.resolveID(addr)
+	if err != nil {
+		return err
+	}
+
+	n.idLock.Lock()
+	n.detectAddress = false
+	n.idLock.Unlock()
+
+	return n.updateID(id)
+}
+
+// ObservedAddresses returns the public addresses peers have recently observed this node to have, alongside the
+// number of distinct networks peers have observed each address from. For more details, refer to
+// WithNodeObservedAddressThreshold.
+//
+// ObservedAddresses may be called concurrently.
+func (n *Node) ObservedAddresses() map[string]int {
+	return n.observed.counts()
+}
+
 // Logger returns the underlying logger associated to this node. The logger, should it not be configured through the
 // WithNodeLogger functional option when calling NewNode, is by default zap.NewNop().
 //
@@ -509,5 +1097,200 @@ func (n *Node) Logger() *zap.Logger {
 //
 // ID may be called concurrently.
 func (n *Node) ID() ID {
-	return n.id
+	n.idLock.RLock()
+	id := n.id
+	n.idLock.RUnlock()
+
+	// Re-sign the ID with a new sequence number and expiry should it be halfway through to expiring. The current ID
+	// is kept should it fail to be re-signed, as it remains valid until it expires.
+
+	if id.Expiry.IsZero() || time.Until(id.Expiry) > n.recordTTL/2 {
+		return id
+	}
+
+	n.signLock.Lock()
+	defer n.signLock.Unlock()
+
+	n.idLock.RLock()
+	current := n.id
+	n.idLock.RUnlock()
+
+	if current.Seq != id.Seq {
+		return current
+	}
+
+	signed, err := n.sign(current, current)
+	if err != nil {
+		n.logger.Warn("Failed to re-sign the ID of this node.", zap.Error(err))
+		return current
+	}
+
+	n.idLock.Lock()
+	n.id = signed
+	n.idLock.Unlock()
+
+	return signed
+}
+
+// setID sets the ID of this node, and sets the public address of this node to the address of id should it not have
+// been configured via WithNodeAddress. A configured public address is kept as it was configured.
+func (n *Node) setID(id ID) {
+	n.signLock.Lock()
+	defer n.signLock.Unlock()
+
+	id, err := n.replaceID(id)
+	if err != nil {
+		n.logger.Warn("Failed to sign the ID of this node.", zap.Error(err))
+	}
+
+	n.idLock.Lock()
+	defer n.idLock.Unlock()
+
+	if n.addr == "" {
+		n.addr = id.Address
+	}
+}
+
+// updateID sets the ID of this node, and notifies all protocols should the ID have changed. It returns an error
+// should id fail to be signed.
+func (n *Node) updateID(id ID) error {
+	n.signLock.Lock()
+
+	n.idLock.RLock()
+	prev := n.id
+	n.idLock.RUnlock()
+
+	id, err := n.replaceID(id)
+
+	n.idLock.Lock()
+	n.addr = id.Address
+	n.idLock.Unlock()
+
+	n.signLock.Unlock()
+
+	if err != nil {
+		n.logger.Warn("Failed to sign the ID of this node.", zap.Error(err))
+	}
+
+	if prev.Host.Equal(id.Host) && prev.Port == id.Port && prev.Address == id.Address {
+		return err
+	}
+
+	n.logger.Info("Updated public address.", zap.String("id_addr", id.Address), zap.Stringer("id_host", id.Host))
+
+	for _, protocol := range n.protocols {
+		if protocol.OnIDUpdated == nil {
+			continue
+		}
+
+		protocol.OnIDUpdated(id)
+	}
+
+	return err
+}
+
+// replaceID records and signs id, and sets it as the ID of this node, which it returns. Should id fail to be signed,
+// the current ID of this node is kept and returned instead, unless the current ID is itself unsigned. It must be
+// called with the sign lock held.
+func (n *Node) replaceID(id ID) (ID, error) {
+	n.idLock.RLock()
+	prev := n.id
+	n.idLock.RUnlock()
+
+	id, err := n.record(id, prev)
+	if err != nil && prev.Signature != ZeroSignature {
+		return prev, err
+	}
+
+	n.idLock.Lock()
+	n.id = id
+	n.idLock.Unlock()
+
+	return id, err
+}
+
+// record attaches to id all addresses and metadata this node advertises, and signs it with a sequence number
+// superseding the sequence number of prev.
+func (n *Node) record(id, prev ID) (ID, error) {
+	if len(n.advertised) > 0 {
+		id.Addrs = append(append([]PeerAddress{}, id.Addrs...), n.advertised...)
+	}
+
+	if len(n.metadata) > 0 {
+		id.Metadata = make(map[string]string, len(n.metadata))
+
+		for key, value := range n.metadata {
+			id.Metadata[key] = value
+		}
+	}
+
+	return n.sign(id, prev)
+}
+
+// sign signs id with a sequence number superseding the sequence number of prev, and with an expiry configured via
+// WithNodeRecordTTL. The sequence number is derived from the current time so that IDs advertised after a node
+// restarts supersede those advertised before. Should signing fail, the returned ID is left unsigned. It is called
+// without the ID lock held, as the signer of this node may be remote.
+func (n *Node) sign(id, prev ID) (ID, error) {
+	now := time.Now()
+
+	id.Seq = uint64(now.UnixNano())
+
+	if id.Seq <= prev.Seq {
+		id.Seq = prev.Seq + 1
+	}
+
+	id.Expiry = time.Time{}
+	id.Signature = ZeroSignature
+
+	id.Key = nil
+
+	if n.key.Type() != KeyTypeEd25519 {
+		id.Key = n.key
+	}
+
+	if n.recordTTL > 0 {
+		id.Expiry = now.Add(n.recordTTL)
+	}
+
+	if err := id.Sign(n.signer); err != nil {
+		return id, err
+	}
+
+	return id, nil
+}
+
+// resolveID resolves addr into an ID bearing the public key of this node. Should the host of addr be a hostname,
+// the ID carries the hostname as-is so that peers resolve it whenever they dial this node.
+func (n *Node) resolveID(addr string) (ID, error) {
+	if hostStr, portStr, err := net.SplitHostPort(addr); err == nil && hostStr != "" && net.ParseIP(hostStr) == nil {
+		port, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil {
+			return ID{}, err
+		}
+
+		return NewIDWithAddresses(n.publicKey, NewDNSAddress(hostStr, uint16(port))), nil
+	}
+
+	resolved, err := ResolveAddress(addr)
+	if err != nil {
+		return ID{}, err
+	}
+
+	hostStr, portStr, err := net.SplitHostPort(resolved)
+	if err != nil {
+		return ID{}, err
+	}
+
+	host := net.ParseIP(hostStr)
+	if host == nil {
+		return ID{}, errors.New("host in provided public address is invalid (must be IPv4/IPv6)")
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return ID{}, err
+	}
+
+	return NewID(n.publicKey, host, uint16(port)), nil
 }
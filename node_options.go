@@ -22,6 +22,40 @@ func WithNodeMaxDialAttempts(maxDialAttempts uint) NodeOption {
 	}
 }
 
+// WithNodeDialBackoff sets the range of delays to wait for in between attempts of dialing a peer. The delay starts
+// at minBackoff, doubles every attempt up until maxBackoff, and has half of it randomly jittered. By default, the
+// delay starts at 50 milliseconds and is at most 1 second. If a minBackoff of 0 is specified, peers will be redialed
+// immediately.
+func WithNodeDialBackoff(minBackoff, maxBackoff time.Duration) NodeOption {
+	return func(n *Node) {
+		if maxBackoff < minBackoff {
+			maxBackoff = minBackoff
+		}
+
+		n.minDialBackoff = minBackoff
+		n.maxDialBackoff = maxBackoff
+	}
+}
+
+// WithNodeDialFailureCooldown sets the duration in which an address that failed to be dialed the max number of
+// attempts is not dialed again. Dialing an address that is cooling down fails immediately with a *DialError whose
+// Cached field is true. By default, the cooldown is set to be 3 seconds. If a cooldown of 0 is specified, failures
+// to dial an address will not be remembered.
+func WithNodeDialFailureCooldown(dialFailureCooldown time.Duration) NodeOption {
+	return func(n *Node) {
+		n.dialFailureCooldown = dialFailureCooldown
+	}
+}
+
+// WithNodeDialStagger sets the delay in between dialing each candidate address of a peer that is being dialed
+// through several candidate addresses via (*Node).PingAny. By default, the delay is set to be 250 milliseconds as
+// recommended by RFC 8305. If a delay of 0 is specified, all candidate addresses will be dialed at once.
+func WithNodeDialStagger(dialStagger time.Duration) NodeOption {
+	return func(n *Node) {
+		n.dialStagger = dialStagger
+	}
+}
+
 // WithNodeMaxInboundConnections sets the max number of inbound connections the connection pool a node maintains allows
 // at any given moment in time. By default, the max number of inbound connections is 128. Exceeding the max number
 // causes the connection pool to release the oldest inbound connection in the pool.
@@ -79,6 +113,31 @@ func WithNodeIdleTimeout(idleTimeout time.Duration) NodeOption {
 	}
 }
 
+// WithNodeHeartbeatInterval sets the interval in which a heartbeat is sent to a peer should nothing have been
+// received from the peer for at least the interval. Peers acknowledge heartbeats, which keeps idle connections to
+// healthy peers from timing out, detects half-open connections to dead peers, and allows for round-trip times to be
+// sampled via (*Client).RTT. Heartbeats are only sent to peers that advertise during the handshake that they
+// understand control frames, as peers that predate heartbeats would handle them as requests. By default, heartbeats
+// are disabled. If a heartbeat interval of 0 is specified, heartbeats will be disabled.
+func WithNodeHeartbeatInterval(heartbeatInterval time.Duration) NodeOption {
+	return func(n *Node) {
+		n.heartbeatInterval = heartbeatInterval
+	}
+}
+
+// WithNodeHeartbeatMaxMissed sets the max number of consecutive heartbeats a peer may leave unacknowledged before the
+// peer is declared dead, and its connection is closed with ErrPeerUnresponsive. By default, the max number of missed
+// heartbeats is 3. The minimum number of missed heartbeats is 1.
+func WithNodeHeartbeatMaxMissed(heartbeatMaxMissed uint) NodeOption {
+	return func(n *Node) {
+		if heartbeatMaxMissed == 0 {
+			heartbeatMaxMissed = 1
+		}
+
+		n.heartbeatMaxMissed = heartbeatMaxMissed
+	}
+}
+
 // WithNodeLogger sets the logger implementation that the node shall use. By default, zap.NewNop() is assigned which
 // disables any logs.
 func WithNodeLogger(logger *zap.Logger) NodeOption {
@@ -100,14 +159,109 @@ func WithNodeID(id ID) NodeOption {
 	}
 }
 
-// WithNodePrivateKey sets the private key of the node. By default, a random private key is generated using
-// GenerateKeys should no private key be configured.
+// WithNodePrivateKey sets the private key of the node, which is used to sign data in-process. By default, a random
+// private key is generated using GenerateKeys should neither a private key nor a Signer be configured.
 func WithNodePrivateKey(privateKey PrivateKey) NodeOption {
 	return func(n *Node) {
 		n.privateKey = privateKey
 	}
 }
 
+// WithNodeSigner sets the Signer used to sign data on behalf of the identity of the node, in place of a private key
+// set via WithNodePrivateKey. It allows for the private key of the node to be kept outside of the node, i.e. within
+// a separate signing process or a hardware security module. Should signer be a KeyedSigner (i.e. one returned by
+// NewSecp256k1Signer), the node is identified by the key of signer. By default, data is signed in-process with the
+// private key of the node.
+func WithNodeSigner(signer Signer) NodeOption {
+	return func(n *Node) {
+		n.signer = signer
+	}
+}
+
+// WithNodePersistentPeers registers the addresses of peers which the node keeps a live connection to at all times.
+// For more details, refer to (*Node).AddPersistentPeer. By default, a node has no persistent peers.
+func WithNodePersistentPeers(addrs ...string) NodeOption {
+	return func(n *Node) {
+		for _, addr := range addrs {
+			n.persistent.add(addr, ZeroPublicKey)
+		}
+	}
+}
+
+// WithNodePersistentPeerIDs registers the IDs of peers which the node keeps a live connection to at all times. For
+// more details, refer to (*Node).AddPersistentPeerID. By default, a node has no persistent peers.
+func WithNodePersistentPeerIDs(ids ...ID) NodeOption {
+	return func(n *Node) {
+		for _, id := range ids {
+			n.persistent.add(id.Address, id.ID)
+		}
+	}
+}
+
+// WithNodeObservedAddressThreshold sets the number of distinct networks peers must agree on the address they observe
+// this node to have from before the node adopts the address as its public address. Peers are grouped into networks
+// by the /24 prefix of their IPv4 address or the /48 prefix of their IPv6 address, such that any number of peers
+// connecting from a single network count only once. Peers report the address they observe this node to have right
+// after handshaking with the node. Observations are only taken into account should the public address of the node
+// not be configured via WithNodeAddress or WithNodeID. By default, the threshold is set to 0, in which case the node
+// does not update its public address based on observations from peers. A threshold of 3 is recommended for nodes
+// whose public address is not known up front, such as nodes behind a NAT.
+func WithNodeObservedAddressThreshold(observedAddressThreshold uint) NodeOption {
+	return func(n *Node) {
+		n.observedAddressThreshold = observedAddressThreshold
+	}
+}
+
+// WithNodeRelay has the node act as a relay, forwarding circuits between peers that are connected to it so that
+// peers which may not be dialed directly may still be reached through the node via RelayAddress. The resources the
+// node spends on forwarding circuits is bounded by limits, for which DefaultRelayLimits is recommended. By default,
+// a node does not act as a relay.
+func WithNodeRelay(limits RelayLimits) NodeOption {
+	return func(n *Node) {
+		n.relays.enabled = true
+		n.relays.limits = limits
+	}
+}
+
+// WithNodeAdvertisedAddresses sets additional addresses this node may be reached at, which are advertised on the ID
+// sent to peers alongside its public address. The addresses may be of any type, i.e. a DNS name or a Unix socket
+// path. By default, only the public address of this node is advertised.
+func WithNodeAdvertisedAddresses(addrs ...PeerAddress) NodeOption {
+	return func(n *Node) {
+		n.advertised = append(n.advertised, addrs...)
+	}
+}
+
+// WithNodeMetadata sets a key/value pair of application-specific metadata advertised on the ID sent to peers. Keys
+// must be at most 255 bytes, and values must be at most 65535 bytes. By default, no metadata is advertised.
+func WithNodeMetadata(key, value string) NodeOption {
+	return func(n *Node) {
+		if n.metadata == nil {
+			n.metadata = make(map[string]string)
+		}
+
+		n.metadata[key] = value
+	}
+}
+
+// WithNodeRecordTTL sets the duration the ID of this node remains valid for once signed. The ID is automatically
+// re-signed with a new sequence number and expiry once it is halfway through to expiring. Setting it to zero has
+// the ID never expire. By default, it is set to 1 hour.
+func WithNodeRecordTTL(recordTTL time.Duration) NodeOption {
+	return func(n *Node) {
+		n.recordTTL = recordTTL
+	}
+}
+
+// WithNodeResolver sets the resolver used to resolve the hostnames of addresses at the time they are dialed. The
+// IP addresses a hostname resolves to are cached for a short period of time, and are resolved again should all of
+// them fail to be dialed. By default, it is set to net.DefaultResolver.
+func WithNodeResolver(resolver Resolver) NodeOption {
+	return func(n *Node) {
+		n.resolver = resolver
+	}
+}
+
 // WithNodeBindHost sets the TCP host IP address which the node binds itself to and listens for new incoming peer
 // connections on. By default, it is unspecified (0.0.0.0).
 func WithNodeBindHost(host net.IP) NodeOption {
@@ -125,10 +279,62 @@ func WithNodeBindPort(port uint16) NodeOption {
 }
 
 // WithNodeAddress sets the public address of this node which is advertised on the ID sent to peers during a handshake
-// protocol which is performed when interacting with peers this node has had no live connection to beforehand. By
-// default, it is left blank, and initialized to 'binding host:binding port' upon calling (*Node).Listen.
+// protocol which is performed when interacting with peers this node has had no live connection to beforehand. The
+// host of the address may be a hostname (i.e. a Kubernetes service name), which is advertised as-is and resolved by
+// peers whenever they dial this node. By default, it is left blank, and initialized to 'binding host:binding port'
+// upon calling (*Node).Listen.
 func WithNodeAddress(addr string) NodeOption {
 	return func(n *Node) {
 		n.addr = addr
 	}
 }
+
+// WithNodeMiddleware registers middleware to the node, which wraps the dispatch of every message to all Handlers
+// registered to the node. It is equivalent to calling (*Node).Use. By default, no middleware is registered.
+func WithNodeMiddleware(middleware ...Middleware) NodeOption {
+	return func(n *Node) {
+		n.middleware = append(n.middleware, middleware...)
+	}
+}
+
+// WithNodeInterceptors registers interceptors to the node, which wrap every call made via (*Node).Send,
+// (*Node).Request, (*Node).SendMessage, and (*Node).RequestMessage. It is equivalent to calling (*Node).Intercept. By
+// default, no interceptors are registered.
+func WithNodeInterceptors(interceptors ...Interceptor) NodeOption {
+	return func(n *Node) {
+		n.interceptors = append(n.interceptors, interceptors...)
+	}
+}
+
+// WithNodeOpcodeRemapping sets whether or not the opcodes of messages sent to peers that registered Go types under
+// different opcodes than this node did are transparently rewritten to the opcodes of the peer. Messages sent via
+// (*Node).SendMessage, (*Node).RequestMessage, and (*HandlerContext).SendMessage are rewritten. Messages of Go types
+// that the peer did not register, but whose opcode the peer registered another Go type under, fail to be sent.
+//
+// By default, it is disabled, and the handshake with peers whose opcodes mismatch fails with ErrOpcodeMismatch. Peers
+// that predate opcode negotiation are presumed to have registered the same Go types in the same order under opcodes
+// counted up from zero. As they are unable to rewrite the opcodes of the messages they send, the handshake with them
+// fails with ErrOpcodeMismatch should any opcode differ regardless of whether or not remapping is enabled. See
+// WithNodeLegacyOpcodes.
+func WithNodeOpcodeRemapping(remap bool) NodeOption {
+	return func(n *Node) {
+		n.remapOpcodes = remap
+	}
+}
+
+// WithNodeLegacyOpcodes sets whether or not Go types registered via (*Node).RegisterMessage and
+// (*Node).RegisterMessageCodec are registered under opcodes counted up from zero in the order they are registered,
+// as peers that predate opcode negotiation register them, rather than under opcodes derived from their names. The
+// kademlia and gossip protocols register their messages via (*Node).RegisterMessage, and thus follow suit. Go types
+// must then be registered sequentially, as their opcodes depend on the order they are registered in.
+//
+// By default, it is disabled, which breaks wire compatibility with peers that predate opcode negotiation: the
+// handshake with them fails with ErrOpcodeMismatch unless every Go type is registered under the opcode the peer
+// registered it under via (*Node).RegisterMessageWithOpcode. Nodes that need to reach such peers should enable it,
+// and register the same Go types in the same order the peers do. Such nodes handshake with newer peers that register
+// Go types under derived opcodes only should both enable WithNodeOpcodeRemapping.
+func WithNodeLegacyOpcodes(legacy bool) NodeOption {
+	return func(n *Node) {
+		n.legacyOpcodes = legacy
+	}
+}
@@ -183,4 +183,54 @@ func TestNodeOptions(t *testing.T) {
 	}
 
 	assert.NoError(t, quick.Check(i, &quick.Config{MaxCount: 10}))
+
+	j := func(interval time.Duration, maxMissed uint) bool {
+		n, err := NewNode(WithNodeHeartbeatInterval(interval), WithNodeHeartbeatMaxMissed(maxMissed))
+		if !assert.NoError(t, err) {
+			return false
+		}
+
+		if !assert.EqualValues(t, n.heartbeatInterval, interval) {
+			return false
+		}
+
+		if maxMissed > 0 && !assert.EqualValues(t, n.heartbeatMaxMissed, maxMissed) {
+			return false
+		}
+
+		if maxMissed == 0 && !assert.EqualValues(t, n.heartbeatMaxMissed, 1) {
+			return false
+		}
+
+		return true
+	}
+
+	assert.NoError(t, quick.Check(j, &quick.Config{MaxCount: 10}))
+
+	k := func(minBackoff, maxBackoff, cooldown time.Duration) bool {
+		n, err := NewNode(WithNodeDialBackoff(minBackoff, maxBackoff), WithNodeDialFailureCooldown(cooldown))
+		if !assert.NoError(t, err) {
+			return false
+		}
+
+		if maxBackoff < minBackoff {
+			maxBackoff = minBackoff
+		}
+
+		if !assert.EqualValues(t, n.minDialBackoff, minBackoff) {
+			return false
+		}
+
+		if !assert.EqualValues(t, n.maxDialBackoff, maxBackoff) {
+			return false
+		}
+
+		if !assert.EqualValues(t, n.dialFailureCooldown, cooldown) {
+			return false
+		}
+
+		return true
+	}
+
+	assert.NoError(t, quick.Check(k, &quick.Config{MaxCount: 10}))
 }
@@ -10,6 +10,7 @@ import (
 	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -318,6 +319,142 @@ func TestIdleTimeoutClientSide(t *testing.T) {
 	assert.Len(t, b.Outbound(), 0)
 }
 
+func TestHeartbeatKeepsIdleConnectionAlive(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(
+		noise.WithNodeIdleTimeout(100*time.Millisecond),
+		noise.WithNodeHeartbeatInterval(20*time.Millisecond),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(noise.WithNodeIdleTimeout(100 * time.Millisecond))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = b.Ping(context.Background(), a.Addr())
+	assert.NoError(t, err)
+
+	time.Sleep(300 * time.Millisecond)
+
+	assert.Len(t, a.Inbound(), 1)
+	assert.Len(t, b.Outbound(), 1)
+
+	ab, ba := a.Inbound()[0], b.Outbound()[0]
+
+	assert.NoError(t, ab.Error())
+	assert.NoError(t, ba.Error())
+
+	assert.True(t, ab.RTT() > 0)
+	assert.Zero(t, ba.RTT())
+}
+
+func TestHeartbeatDetectsUnresponsivePeer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(
+		noise.WithNodeHeartbeatInterval(20*time.Millisecond),
+		noise.WithNodeHeartbeatMaxMissed(2),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(noise.WithNodeNumWorkers(1))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	// Block b's only worker so that b's connection stops reading from a once b's work queue is full.
+
+	release := make(chan struct{})
+	defer close(release)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		<-release
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, a.Send(context.Background(), b.Addr(), []byte("hello")))
+	}
+
+	ab := a.Outbound()[0]
+	ab.WaitUntilClosed()
+
+	assert.True(t, errors.Is(ab.Error(), noise.ErrPeerUnresponsive))
+	assert.Len(t, a.Outbound(), 0)
+}
+
+func TestDialBackoffAndFailureCooldown(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	// Find an address that nobody is listening on.
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	addr := l.Addr().String()
+	assert.NoError(t, l.Close())
+
+	var failures []*noise.DialError
+
+	a, err := noise.NewNode(
+		noise.WithNodeMaxDialAttempts(3),
+		noise.WithNodeDialBackoff(20*time.Millisecond, 40*time.Millisecond),
+		noise.WithNodeDialFailureCooldown(time.Minute),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	a.Bind(noise.Protocol{
+		OnPingFailed: func(addr string, err error) {
+			var dialErr *noise.DialError
+			if assert.True(t, errors.As(err, &dialErr)) {
+				failures = append(failures, dialErr)
+			}
+		},
+	})
+
+	assert.NoError(t, a.Listen())
+
+	start := time.Now()
+
+	_, err = a.Ping(context.Background(), addr)
+	assert.Error(t, err)
+
+	// Backoff between 3 attempts should be at least 20ms/2 + 40ms/2 after jitter.
+
+	assert.True(t, time.Since(start) >= 30*time.Millisecond)
+
+	var dialErr *noise.DialError
+	assert.True(t, errors.As(err, &dialErr))
+	assert.EqualValues(t, 3, dialErr.Attempts)
+	assert.False(t, dialErr.Cached)
+
+	// Dialing the address again should fail immediately as it is cooling down.
+
+	_, err = a.Ping(context.Background(), addr)
+	assert.True(t, errors.As(err, &dialErr))
+	assert.EqualValues(t, 3, dialErr.Attempts)
+	assert.True(t, dialErr.Cached)
+
+	if assert.Len(t, failures, 2) {
+		assert.False(t, failures[0].Cached)
+		assert.True(t, failures[1].Cached)
+	}
+}
+
 func TestHandlerErrorCausesConnToClose(t *testing.T) {
 	defer goleak.VerifyNone(t)
 
@@ -419,3 +556,214 @@ func BenchmarkSend(b *testing.B) {
 		}
 	})
 }
+
+func TestPeerRecordAdvertisedDuringHandshake(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(
+		noise.WithNodeAdvertisedAddresses(noise.NewDNSAddress("peer.example.com", 3000)),
+		noise.WithNodeMetadata("role", "archive"),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	client, err := b.Ping(context.Background(), a.Addr())
+	assert.NoError(t, err)
+
+	id := client.ID()
+
+	assert.Equal(t, a.ID().Seq, id.Seq)
+	assert.Equal(t, a.ID().Address, id.Address)
+	assert.Equal(t, map[string]string{"role": "archive"}, id.Metadata)
+	assert.Len(t, id.Addrs, 2)
+	assert.Equal(t, "peer.example.com:3000", id.Addrs[1].String())
+}
+
+func TestHandlerContextCancellation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	type result struct {
+		deadline bool
+		err      error
+	}
+
+	started := make(chan struct{}, 1)
+	results := make(chan result, 1)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		started <- struct{}{}
+
+		<-ctx.Context().Done()
+
+		_, deadline := ctx.Context().Deadline()
+		results <- result{deadline: deadline, err: ctx.Context().Err()}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// The deadline of the requester is carried to the handler.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = a.Request(ctx, b.Addr(), []byte("slow"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	<-started
+	res := <-results
+	assert.True(t, res.deadline)
+	assert.Error(t, res.err)
+
+	assert.Zero(t, a.Outbound()[0].Stats().PendingRequests)
+
+	// The requester giving up cancels the handler.
+
+	ctx, cancel = context.WithCancel(context.Background())
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	_, err = a.Request(ctx, b.Addr(), []byte("cancelled"))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	res = <-results
+	assert.False(t, res.deadline)
+	assert.True(t, errors.Is(res.err, context.Canceled))
+
+	assert.EqualValues(t, 1, a.Stats().Counters["noise_request_timeouts_total"])
+	assert.EqualValues(t, 1, a.Stats().Counters["noise_request_cancellations_total"])
+	waitFor(t, func() bool { return b.Stats().Counters["noise_request_cancellations_received_total"] == 2 })
+
+	// The connection closing cancels the handler.
+
+	assert.NoError(t, a.Send(context.Background(), b.Addr(), []byte("message")))
+	<-started
+
+	a.Outbound()[0].Close()
+
+	res = <-results
+	assert.True(t, errors.Is(res.err, context.Canceled))
+}
+
+func TestLateResponseToAbandonedRequestIsDropped(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	var handled int32
+
+	a.Handle(func(ctx noise.HandlerContext) error {
+		atomic.AddInt32(&handled, 1)
+		return nil
+	})
+
+	responded := make(chan struct{}, 1)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if string(ctx.Data()) == "slow" {
+			<-ctx.Context().Done()
+			defer func() { responded <- struct{}{} }()
+		}
+
+		return ctx.Send(ctx.Data())
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = a.Request(ctx, b.Addr(), []byte("slow"))
+	assert.Error(t, err)
+
+	<-responded
+
+	res, err := a.Request(context.Background(), b.Addr(), []byte("fast"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("fast"), res)
+
+	assert.Zero(t, atomic.LoadInt32(&handled))
+	assert.Zero(t, a.Outbound()[0].Stats().PendingRequests)
+}
+
+func TestHandlerRespondsWithRemoteError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	sendErrs := make(chan error, 1)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		switch string(ctx.Data()) {
+		case "fail":
+			return fmt.Errorf("wrapped: %w", &noise.RemoteError{Code: 7, Message: "not found", Details: []byte{1, 2}})
+		case "message":
+			sendErrs <- ctx.SendError(&noise.RemoteError{Code: 1})
+			return nil
+		default:
+			return ctx.Send(ctx.Data())
+		}
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	_, err = a.Request(context.Background(), b.Addr(), []byte("fail"))
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.Equal(t, &noise.RemoteError{Code: 7, Message: "not found", Details: []byte{1, 2}}, remote)
+
+	// The connection stays open, and errors may not be sent in response to messages.
+
+	assert.NoError(t, a.Send(context.Background(), b.Addr(), []byte("message")))
+	assert.Error(t, <-sendErrs)
+
+	res, err := a.Request(context.Background(), b.Addr(), []byte("ok"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), res)
+
+	assert.Len(t, a.Outbound(), 1)
+	assert.NoError(t, a.Outbound()[0].Error())
+}
@@ -0,0 +1,157 @@
+package noise
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// observedAddressTTL is the duration in which an address observed by a peer is taken into account.
+	observedAddressTTL = 30 * time.Minute
+
+	// maxObservers is the max number of observer groups whose observations are kept track of at any given moment in
+	// time.
+	maxObservers = 256
+
+	// observerPrefixIPv4 and observerPrefixIPv6 are the lengths of the IP prefixes observers are grouped by.
+	observerPrefixIPv4 = 24
+	observerPrefixIPv6 = 48
+)
+
+type observedAddr struct {
+	host net.IP
+	port uint16
+	at   time.Time
+}
+
+func (o observedAddr) String() string {
+	return net.JoinHostPort(o.host.String(), strconv.FormatUint(uint64(o.port), 10))
+}
+
+// observerGroup returns the group observations made by a peer at ip are counted under, which is the /24 prefix of
+// ip for IPv4 and the /48 prefix of ip for IPv6. Public keys cost nothing to generate, whereas addresses spread out
+// across many networks do not, such that a single host may not have a node adopt an address of its choosing.
+func observerGroup(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(observerPrefixIPv4, 8*net.IPv4len)).String()
+	}
+
+	return ip.Mask(net.CIDRMask(observerPrefixIPv6, 8*net.IPv6len)).String()
+}
+
+// observedAddrMap aggregates the addresses peers observe a node to have. Observations are grouped by the network the
+// observing peer is connected from, and every group only has its most recent observation taken into account. See
+// observerGroup.
+type observedAddrMap struct {
+	sync.Mutex
+	entries map[string]observedAddr
+}
+
+func newObservedAddrMap() *observedAddrMap {
+	return &observedAddrMap{entries: make(map[string]observedAddr)}
+}
+
+// record records the address a peer in the observer group group has observed us to have. It returns the address
+// that has been observed by the most number of groups should the address have been observed by at least threshold
+// groups.
+func (o *observedAddrMap) record(group string, addr observedAddr, threshold uint) (observedAddr, bool) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.prune(addr.at)
+
+	if _, exists := o.entries[group]; !exists && len(o.entries) >= maxObservers {
+		var oldest string
+
+		for key, entry := range o.entries {
+			if oldest == "" || entry.at.Before(o.entries[oldest].at) {
+				oldest = key
+			}
+		}
+
+		delete(o.entries, oldest)
+	}
+
+	o.entries[group] = addr
+
+	var (
+		best      observedAddr
+		bestCount int
+	)
+
+	counts := make(map[string]int, len(o.entries))
+
+	for _, entry := range o.entries {
+		key := entry.String()
+		counts[key]++
+
+		if counts[key] > bestCount {
+			best, bestCount = entry, counts[key]
+		}
+	}
+
+	if uint(bestCount) < threshold {
+		return observedAddr{}, false
+	}
+
+	return best, true
+}
+
+func (o *observedAddrMap) counts() map[string]int {
+	o.Lock()
+	defer o.Unlock()
+
+	o.prune(time.Now())
+
+	counts := make(map[string]int, len(o.entries))
+	for _, entry := range o.entries {
+		counts[entry.String()]++
+	}
+
+	return counts
+}
+
+func (o *observedAddrMap) prune(now time.Time) {
+	for key, entry := range o.entries {
+		if now.Sub(entry.at) > observedAddressTTL {
+			delete(o.entries, key)
+		}
+	}
+}
+
+// observeAddress takes into account the address the peer of client has observed this node to have, and updates the
+// ID of this node should peers from enough networks agree on a public address different from the one this node
+// currently has.
+func (n *Node) observeAddress(client *Client, host net.IP, port uint16) {
+	n.idLock.RLock()
+	detect, current := n.detectAddress, n.id
+	n.idLock.RUnlock()
+
+	if !detect || host.IsUnspecified() {
+		return
+	}
+
+	// Observations made over relayed connections may not be attributed to the network of the observing peer.
+
+	remote, ok := client.conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return
+	}
+
+	// The port a peer observes over a connection we dialed is ephemeral, so only the host it observed is of use.
+
+	if client.side == ClientSideOutbound {
+		port = current.Port
+	}
+
+	best, ok := n.observed.record(observerGroup(remote.IP), observedAddr{host: host, port: port, at: time.Now()},
+		n.observedAddressThreshold,
+	)
+	if !ok || (best.host.Equal(current.Host) && best.port == current.Port) {
+		return
+	}
+
+	n.updateID(NewID(n.publicKey, best.host, best.port))
+}
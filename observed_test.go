@@ -0,0 +1,180 @@
+package noise_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/goleak"
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestPublicAddressDetectedFromObservations(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeObservedAddressThreshold(1))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	updated := make(chan noise.ID, 1)
+
+	a.Bind(noise.Protocol{
+		OnIDUpdated: func(id noise.ID) {
+			updated <- id
+		},
+	})
+
+	assert.NoError(t, a.Listen())
+
+	// Have three peers dial a through the loopback alias 127.0.0.2, which they then report back to a as being
+	// the address they observe a to have. The peers all share a single network, which suffices given a threshold
+	// of 1.
+
+	addr := net.JoinHostPort("127.0.0.2", strconv.FormatUint(uint64(a.ID().Port), 10))
+
+	for i := 0; i < 3; i++ {
+		peer, err := noise.NewNode()
+		assert.NoError(t, err)
+
+		defer peer.Close()
+
+		assert.NoError(t, peer.Listen())
+
+		_, err = peer.Ping(context.Background(), addr)
+		assert.NoError(t, err)
+	}
+
+	id := <-updated
+
+	assert.True(t, id.Host.Equal(net.IPv4(127, 0, 0, 2)))
+	assert.Equal(t, a.ID().Port, id.Port)
+	assert.Equal(t, id, a.ID())
+
+	// All three peers connect from the same network, and thus only count as a single observer.
+
+	assert.Equal(t, 1, a.ObservedAddresses()[addr])
+}
+
+func TestPublicAddressNotDetectedByDefault(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	a.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(nil)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+
+	id := a.ID()
+
+	addr := net.JoinHostPort("127.0.0.2", strconv.FormatUint(uint64(id.Port), 10))
+
+	peer, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer peer.Close()
+
+	assert.NoError(t, peer.Listen())
+
+	// Observations are reported before any request, and are thus handled by a before a responds.
+
+	_, err = peer.Request(context.Background(), addr, nil)
+	assert.NoError(t, err)
+
+	assert.Empty(t, a.ObservedAddresses())
+	assert.Equal(t, id, a.ID())
+}
+
+func TestPublicAddressNotDetectedFromSingleNetwork(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeObservedAddressThreshold(2))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	a.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(nil)
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+
+	id := a.ID()
+
+	// Peers sharing a single network, no matter how many public keys they bear, may not have a adopt an address of
+	// their choosing.
+
+	addr := net.JoinHostPort("127.0.0.2", strconv.FormatUint(uint64(id.Port), 10))
+
+	for i := 0; i < 4; i++ {
+		peer, err := noise.NewNode()
+		assert.NoError(t, err)
+
+		defer peer.Close()
+
+		assert.NoError(t, peer.Listen())
+
+		// Observations are reported before any request, and are thus handled by a before a responds.
+
+		_, err = peer.Request(context.Background(), addr, nil)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, map[string]int{addr: 1}, a.ObservedAddresses())
+	assert.Equal(t, id.Address, a.ID().Address)
+}
+
+func TestPublicAddressNotDetectedIfConfigured(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeObservedAddressThreshold(1))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	updates := atomic.NewUint32(0)
+
+	a.Bind(noise.Protocol{
+		OnIDUpdated: func(id noise.ID) {
+			updates.Inc()
+		},
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, a.UpdateAddress(net.JoinHostPort("10.0.0.1", strconv.FormatUint(uint64(a.ID().Port), 10))))
+	assert.EqualValues(t, 1, updates.Load())
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	client, err := b.Ping(context.Background(), net.JoinHostPort("127.0.0.2", strconv.FormatUint(uint64(a.ID().Port), 10)))
+	assert.NoError(t, err)
+
+	assert.True(t, client.ID().Host.Equal(net.IPv4(10, 0, 0, 1)))
+
+	client.Close()
+	client.WaitUntilClosed()
+
+	assert.Empty(t, a.ObservedAddresses())
+	assert.True(t, a.ID().Host.Equal(net.IPv4(10, 0, 0, 1)))
+	assert.EqualValues(t, 1, updates.Load())
+}
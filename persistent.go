@@ -0,0 +1,256 @@
+package noise
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+)
+
+// PeerState represents the state of a nodes connection to a persistent peer.
+type PeerState uint8
+
+const (
+	// PeerDisconnected marks that there is no live connection to a persistent peer, and that the peer is waiting to
+	// be redialed.
+	PeerDisconnected PeerState = iota
+
+	// PeerConnecting marks that a persistent peer is being dialed.
+	PeerConnecting
+
+	// PeerConnected marks that there is a live connection to a persistent peer.
+	PeerConnected
+)
+
+// String returns a human-readable representation of this peer state.
+func (s PeerState) String() string {
+	switch s {
+	case PeerDisconnected:
+		return "disconnected"
+	case PeerConnecting:
+		return "connecting"
+	case PeerConnected:
+		return "connected"
+	default:
+		return fmt.Sprintf("PeerState(%d)", uint8(s))
+	}
+}
+
+// PersistentPeer is a snapshot of the state of a peer which a node keeps a live connection to at all times.
+type PersistentPeer struct {
+	// Addr is the address of the persistent peer.
+	Addr string
+
+	// ID is the public key the persistent peer is expected to have. It is ZeroPublicKey should the persistent peer
+	// have been registered only by its address.
+	ID PublicKey
+
+	// State is the state of the connection to the persistent peer.
+	State PeerState
+
+	// Client is the live connection to the persistent peer. It is nil unless State is PeerConnected.
+	Client *Client
+
+	// Attempts is the number of consecutive times the persistent peer has failed to be connected to.
+	Attempts uint
+
+	// Err is the error that caused the last connection to the persistent peer to either fail or drop.
+	Err error
+}
+
+type persistentPeer struct {
+	PersistentPeer
+	cancel context.CancelFunc
+}
+
+type persistentPeerMap struct {
+	sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	node    *Node
+	entries map[string]*persistentPeer
+}
+
+func newPersistentPeerMap() *persistentPeerMap {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &persistentPeerMap{
+		ctx:     ctx,
+		cancel:  cancel,
+		entries: make(map[string]*persistentPeer),
+	}
+}
+
+func (m *persistentPeerMap) add(addr string, id PublicKey) {
+	m.Lock()
+	defer m.Unlock()
+
+	if entry, exists := m.entries[addr]; exists {
+		if entry.ID == id {
+			return
+		}
+
+		if entry.cancel != nil {
+			entry.cancel()
+		}
+	}
+
+	entry := &persistentPeer{PersistentPeer: PersistentPeer{Addr: addr, ID: id}}
+	m.entries[addr] = entry
+
+	if m.node != nil {
+		m.maintain(entry)
+	}
+}
+
+func (m *persistentPeerMap) remove(addr string) {
+	m.Lock()
+	defer m.Unlock()
+
+	entry, exists := m.entries[addr]
+	if !exists {
+		return
+	}
+
+	if entry.cancel != nil {
+		entry.cancel()
+	}
+
+	delete(m.entries, addr)
+}
+
+func (m *persistentPeerMap) slice() []PersistentPeer {
+	m.Lock()
+	defer m.Unlock()
+
+	peers := make([]PersistentPeer, 0, len(m.entries))
+	for _, entry := range m.entries {
+		peers = append(peers, entry.PersistentPeer)
+	}
+
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].Addr < peers[j].Addr
+	})
+
+	return peers
+}
+
+// start has all persistent peers be maintained by node. It must only be called once the node is listening.
+func (m *persistentPeerMap) start(node *Node) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	m.node = node
+
+	for _, entry := range m.entries {
+		m.maintain(entry)
+	}
+}
+
+// stop stops maintaining all persistent peers, and waits for all goroutines maintaining them to exit.
+func (m *persistentPeerMap) stop() {
+	m.Lock()
+	m.cancel()
+	m.node = nil
+	m.Unlock()
+
+	m.wg.Wait()
+}
+
+// maintain spawns a goroutine which keeps a live connection to entry. It must be called with the lock held.
+func (m *persistentPeerMap) maintain(entry *persistentPeer) {
+	if m.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	entry.cancel = cancel
+
+	node := m.node
+
+	m.wg.Add(1)
+
+	go func() {
+		defer m.wg.Done()
+		m.loop(ctx, node, entry)
+	}()
+}
+
+func (m *persistentPeerMap) update(entry *persistentPeer, state PeerState, client *Client, attempts uint, err error) {
+	m.Lock()
+	defer m.Unlock()
+
+	entry.State = state
+	entry.Client = client
+	entry.Attempts = attempts
+	entry.Err = err
+}
+
+func (m *persistentPeerMap) loop(ctx context.Context, node *Node, entry *persistentPeer) {
+	defer m.update(entry, PeerDisconnected, nil, 0, nil)
+
+	for attempts := uint(0); ; {
+		if err := waitDialBackoff(ctx, dialBackoff(attempts, node.minDialBackoff, node.maxDialBackoff)); err != nil {
+			return
+		}
+
+		m.update(entry, PeerConnecting, nil, attempts, entry.Err)
+
+		// Persistent peers are dialed regardless of whether or not they recently failed to be dialed, as the
+		// backoff in between redials is already being accounted for.
+
+		var err error
+
+		client, dialErr := node.dial(ctx, entry.Addr, false)
+		if dialErr != nil {
+			node.reportPingFailed(dialErr)
+			err = dialErr
+		}
+
+		if err == nil && entry.ID != ZeroPublicKey && client.ID().ID != entry.ID {
+			client.close()
+
+			err = fmt.Errorf("persistent peer at %s has public key %s, but expected public key %s",
+				entry.Addr, client.ID().ID, entry.ID,
+			)
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			attempts++
+
+			m.update(entry, PeerDisconnected, nil, attempts, err)
+
+			node.logger.Debug("Failed to connect to persistent peer.",
+				zap.String("peer_addr", entry.Addr),
+				zap.Uint("attempts", attempts),
+				zap.Error(err),
+			)
+
+			continue
+		}
+
+		attempts = 0
+
+		m.update(entry, PeerConnected, client, 0, nil)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.clientDone:
+		}
+
+		m.update(entry, PeerDisconnected, nil, 0, client.Error())
+	}
+}
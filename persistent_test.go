@@ -0,0 +1,98 @@
+package noise_test
+
+import (
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition to be met")
+		}
+	}
+}
+
+func TestPersistentPeerReconnects(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	a, err := noise.NewNode(
+		noise.WithNodePersistentPeerIDs(b.ID()),
+		noise.WithNodeDialBackoff(10*time.Millisecond, 20*time.Millisecond),
+	)
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.Len(t, a.PersistentPeers(), 1)
+	assert.Equal(t, noise.PeerDisconnected, a.PersistentPeers()[0].State)
+
+	assert.NoError(t, a.Listen())
+
+	connected := func() bool {
+		peers := a.PersistentPeers()
+		return len(peers) == 1 && peers[0].State == noise.PeerConnected
+	}
+
+	waitFor(t, connected)
+
+	first := a.PersistentPeers()[0].Client
+	assert.Equal(t, b.ID().ID, first.ID().ID)
+
+	// Drop the connection from b's side, and have a redial b.
+
+	for _, client := range b.Inbound() {
+		client.Close()
+	}
+
+	first.WaitUntilClosed()
+
+	waitFor(t, func() bool {
+		return connected() && a.PersistentPeers()[0].Client != first
+	})
+
+	a.RemovePersistentPeer(b.Addr())
+	assert.Len(t, a.PersistentPeers(), 0)
+}
+
+func TestPersistentPeerWithUnexpectedPublicKey(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	a, err := noise.NewNode(noise.WithNodeDialBackoff(10*time.Millisecond, 20*time.Millisecond))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	publicKey, _, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	a.AddPersistentPeerID(noise.ID{ID: publicKey, Address: b.Addr()})
+
+	waitFor(t, func() bool {
+		peers := a.PersistentPeers()
+		return len(peers) == 1 && peers[0].Attempts > 0 && peers[0].Err != nil
+	})
+
+	assert.NotEqual(t, noise.PeerConnected, a.PersistentPeers()[0].State)
+}
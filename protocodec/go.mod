@@ -0,0 +1,12 @@
+module github.com/perlin-network/noise/protocodec
+
+go 1.13
+
+require (
+	github.com/perlin-network/noise v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.4.0
+	go.uber.org/goleak v1.0.0
+	google.golang.org/protobuf v1.27.1
+)
+
+replace github.com/perlin-network/noise => ../
@@ -0,0 +1,51 @@
+// Package protocodec provides a noise.Codec for protocol buffer messages generated via google.golang.org/protobuf,
+// such that they may be registered to a node without hand-written Marshal methods nor deserialize functions.
+//
+// Messages are registered as pointers to the Go types generated for them, and are encoded deterministically. They are
+// sent and received wrapped in a noise.CodecMessage:
+//
+//	protocodec.Register(node, (*pb.FindRequest)(nil), (*pb.FindResponse)(nil))
+//	node.RequestMessage(ctx, addr, noise.CodecMessage{Value: &pb.FindRequest{...}})
+package protocodec
+
+import (
+	"fmt"
+	"github.com/perlin-network/noise"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes protocol buffer messages.
+var Codec noise.Codec = codec{}
+
+type codec struct{}
+
+func (codec) Marshal(msg interface{}) ([]byte, error) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protocol buffer message", msg)
+	}
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
+
+func (codec) Unmarshal(data []byte, msg interface{}) error {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protocol buffer message", msg)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+// Register registers the Go types of all msgs to node under Codec, and returns the opcodes they were registered
+// under. It panics should any of the opcodes collide with those of types already registered to node. See
+// (*noise.Node).RegisterMessageCodec.
+func Register(node *noise.Node, msgs ...proto.Message) []uint16 {
+	opcodes := make([]uint16, 0, len(msgs))
+
+	for _, msg := range msgs {
+		opcodes = append(opcodes, node.RegisterMessageCodec(msg, Codec))
+	}
+
+	return opcodes
+}
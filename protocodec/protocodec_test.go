@@ -0,0 +1,92 @@
+package protocodec_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/protocodec"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"testing"
+	"time"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	node, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	opcodes := protocodec.Register(node, (*wrapperspb.StringValue)(nil), (*structpb.Struct)(nil))
+	assert.Len(t, opcodes, 2)
+
+	msg, err := structpb.NewStruct(map[string]interface{}{"cursor": "abc", "limit": 10.0, "tags": []interface{}{"a"}})
+	assert.NoError(t, err)
+
+	data, err := node.EncodeMessage(noise.CodecMessage{Value: msg})
+	assert.NoError(t, err)
+
+	decoded, err := node.DecodeMessage(data)
+	assert.NoError(t, err)
+
+	if assert.IsType(t, noise.CodecMessage{}, decoded) {
+		value := decoded.(noise.CodecMessage).Value
+
+		assert.IsType(t, (*structpb.Struct)(nil), value)
+		assert.True(t, proto.Equal(msg, value.(proto.Message)))
+	}
+
+	_, err = node.DecodeMessage(append(data[:2:2], 0xff))
+	assert.Error(t, err)
+
+	_, err = protocodec.Codec.Marshal("not a message")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() { protocodec.Register(node, (*wrapperspb.StringValue)(nil)) })
+}
+
+func TestRequestMessage(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	now := time.Now()
+
+	for _, node := range []*noise.Node{a, b} {
+		protocodec.Register(node, (*wrapperspb.StringValue)(nil), (*timestamppb.Timestamp)(nil))
+	}
+
+	route := noise.CodecMessage{Value: (*wrapperspb.StringValue)(nil)}
+
+	b.HandleMessage(route, func(ctx noise.HandlerContext, req *wrapperspb.StringValue) error {
+		if req.GetValue() != "time" {
+			return &noise.RemoteError{Code: 400, Message: "unknown request"}
+		}
+
+		return ctx.SendMessage(noise.CodecMessage{Value: timestamppb.New(now)})
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	res, err := a.RequestMessage(context.Background(), b.Addr(), noise.CodecMessage{Value: wrapperspb.String("time")})
+	assert.NoError(t, err)
+
+	if assert.IsType(t, noise.CodecMessage{}, res) {
+		assert.True(t, now.Equal(res.(noise.CodecMessage).Value.(*timestamppb.Timestamp).AsTime()))
+	}
+
+	_, err = a.RequestMessage(context.Background(), b.Addr(), noise.CodecMessage{Value: wrapperspb.String("date")})
+	assert.Error(t, err)
+}
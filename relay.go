@@ -0,0 +1,668 @@
+package noise
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// relayAddressSeparator separates the address of a relay from the public key of a peer in a relay address.
+	relayAddressSeparator = "/relay/"
+
+	// relayChunkSize is the max number of bytes of a relayed stream that is sent in a single control frame.
+	relayChunkSize = 16 << 10
+
+	// relayBufferSize is the max number of chunks of a relayed stream that may be buffered before being read.
+	relayBufferSize = 1024
+)
+
+const (
+	relayStatusOK byte = iota
+	relayStatusDisabled
+	relayStatusNotConnected
+	relayStatusLimitReached
+)
+
+var (
+	// ErrRelayRefused is returned when a relay refuses to open a circuit to a peer.
+	ErrRelayRefused = errors.New("relay refused to open circuit")
+
+	errRelayTimeout = &relayTimeoutError{}
+)
+
+// RelayLimits bounds the resources a node acting as a relay spends on forwarding circuits between peers. A zero
+// value for any limit disables the limit.
+type RelayLimits struct {
+	// MaxCircuits is the max number of circuits a relay forwards at any given moment in time.
+	MaxCircuits uint
+
+	// MaxCircuitsPerPeer is the max number of circuits a relay forwards at any given moment in time that a single
+	// peer is an end of.
+	MaxCircuitsPerPeer uint
+
+	// MaxCircuitDuration is the max duration a relay forwards a single circuit for before closing it.
+	MaxCircuitDuration time.Duration
+
+	// MaxCircuitBytes is the max number of bytes a relay forwards over a single circuit before closing it.
+	MaxCircuitBytes uint64
+}
+
+// DefaultRelayLimits are the recommended limits for a node acting as a relay.
+var DefaultRelayLimits = RelayLimits{
+	MaxCircuits:        128,
+	MaxCircuitsPerPeer: 8,
+	MaxCircuitDuration: 2 * time.Minute,
+	MaxCircuitBytes:    128 << 20,
+}
+
+// RelayAddress returns the address of the peer with public key target through the relay at relayAddr. The address
+// may be passed to (*Node).Ping, (*Node).Send, (*Node).Request, or any other method that dials a peer, in order to
+// connect to target through a circuit forwarded by the relay. The relay must be configured via WithNodeRelay, and
+// target must have a live connection to the relay.
+//
+// All data sent over a circuit is end-to-end encrypted between the two ends of the circuit, and the two ends
+// authenticate each others ID with the very same handshake used for direct connections.
+func RelayAddress(relayAddr string, target PublicKey) string {
+	return relayAddr + relayAddressSeparator + hex.EncodeToString(target[:])
+}
+
+func parseRelayAddress(addr string) (string, PublicKey, bool) {
+	idx := strings.LastIndex(addr, relayAddressSeparator)
+	if idx < 0 {
+		return "", ZeroPublicKey, false
+	}
+
+	buf, err := hex.DecodeString(addr[idx+len(relayAddressSeparator):])
+	if err != nil || len(buf) != SizePublicKey {
+		return "", ZeroPublicKey, false
+	}
+
+	var target PublicKey
+	copy(target[:], buf)
+
+	return addr[:idx], target, true
+}
+
+func marshalCircuitID(id uint64, payload []byte) []byte {
+	buf := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint64(buf, id)
+
+	return append(buf, payload...)
+}
+
+func unmarshalCircuitID(payload []byte) (uint64, []byte, error) {
+	if len(payload) < 8 {
+		return 0, nil, fmt.Errorf("got relay frame of %d byte(s), but expected at least 8 byte(s): %w",
+			len(payload), io.ErrUnexpectedEOF,
+		)
+	}
+
+	return binary.BigEndian.Uint64(payload[:8]), payload[8:], nil
+}
+
+type relayTimeoutError struct{}
+
+func (e *relayTimeoutError) Error() string   { return "i/o timeout" }
+func (e *relayTimeoutError) Timeout() bool   { return true }
+func (e *relayTimeoutError) Temporary() bool { return true }
+
+type relayAddr string
+
+func (a relayAddr) Network() string { return "relay" }
+func (a relayAddr) String() string  { return string(a) }
+
+// relayConn is a net.Conn over a single end of a circuit forwarded by a relay through carrier.
+type relayConn struct {
+	carrier *Client
+	id      uint64
+
+	local, remote net.Addr
+
+	incoming chan []byte
+	pending  []byte
+
+	deadline struct {
+		sync.Mutex
+		read time.Time
+	}
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newRelayConn(carrier *Client, id uint64, remote string) *relayConn {
+	return &relayConn{
+		carrier:  carrier,
+		id:       id,
+		local:    relayAddr(carrier.conn.LocalAddr().String()),
+		remote:   relayAddr(remote),
+		incoming: make(chan []byte, relayBufferSize),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (r *relayConn) Read(buf []byte) (int, error) {
+	if len(r.pending) == 0 {
+		r.deadline.Lock()
+		deadline := r.deadline.read
+		r.deadline.Unlock()
+
+		var timeout <-chan time.Time
+
+		if !deadline.IsZero() {
+			timer := time.NewTimer(time.Until(deadline))
+			defer timer.Stop()
+
+			timeout = timer.C
+		}
+
+		select {
+		case r.pending = <-r.incoming:
+		case <-r.closed:
+			select {
+			case r.pending = <-r.incoming:
+			default:
+				return 0, io.EOF
+			}
+		case <-timeout:
+			return 0, errRelayTimeout
+		}
+	}
+
+	n := copy(buf, r.pending)
+	r.pending = r.pending[n:]
+
+	return n, nil
+}
+
+func (r *relayConn) Write(buf []byte) (int, error) {
+	select {
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	case <-r.carrier.clientDone:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+
+	for i := 0; i < len(buf); i += relayChunkSize {
+		end := i + relayChunkSize
+		if end > len(buf) {
+			end = len(buf)
+		}
+
+		err := r.carrier.send(controlNonce, marshalControl(controlRelayData, marshalCircuitID(r.id, buf[i:end])))
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(buf), nil
+}
+
+// deliver buffers data received over the circuit. It returns false should the buffer be full.
+func (r *relayConn) deliver(data []byte) bool {
+	select {
+	case r.incoming <- append([]byte{}, data...):
+		return true
+	default:
+		return false
+	}
+}
+
+// Close closes this end of the circuit, and notifies the relay that the circuit has been closed.
+func (r *relayConn) Close() error {
+	if r.shutdown() {
+		r.carrier.circuits.remove(r.id)
+		_ = r.carrier.send(controlNonce, marshalControl(controlRelayClose, marshalCircuitID(r.id, nil)))
+	}
+
+	return nil
+}
+
+// shutdown closes this end of the circuit without notifying the relay. It returns true should this be the first
+// time this end of the circuit was closed.
+func (r *relayConn) shutdown() bool {
+	closed := false
+
+	r.closeOnce.Do(func() {
+		close(r.closed)
+		closed = true
+	})
+
+	return closed
+}
+
+func (r *relayConn) LocalAddr() net.Addr  { return r.local }
+func (r *relayConn) RemoteAddr() net.Addr { return r.remote }
+
+func (r *relayConn) SetDeadline(t time.Time) error {
+	return r.SetReadDeadline(t)
+}
+
+func (r *relayConn) SetReadDeadline(t time.Time) error {
+	r.deadline.Lock()
+	defer r.deadline.Unlock()
+
+	r.deadline.read = t
+
+	return nil
+}
+
+// SetWriteDeadline does nothing, as writes to a circuit are queued up to be written by the carrier of the circuit.
+func (r *relayConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// circuitMap keeps track of all ends of circuits that are carried by a single client.
+type circuitMap struct {
+	sync.Mutex
+
+	next    uint64
+	entries map[uint64]*relayConn
+	pending map[uint64]chan byte
+}
+
+func newCircuitMap() *circuitMap {
+	return &circuitMap{
+		entries: make(map[uint64]*relayConn),
+		pending: make(map[uint64]chan byte),
+	}
+}
+
+// nextID returns a new circuit ID. Circuit IDs allocated by the dialer of a carrier are even, and circuit IDs
+// allocated by the listener of a carrier are odd so that both ends of a carrier may open circuits at once.
+func (m *circuitMap) nextID(side ClientSide) uint64 {
+	m.Lock()
+	defer m.Unlock()
+
+	m.next++
+
+	if side == ClientSideOutbound {
+		return m.next * 2
+	}
+
+	return m.next*2 + 1
+}
+
+func (m *circuitMap) add(conn *relayConn) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.entries[conn.id] = conn
+}
+
+func (m *circuitMap) find(id uint64) *relayConn {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.entries[id]
+}
+
+func (m *circuitMap) remove(id uint64) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.entries, id)
+}
+
+func (m *circuitMap) await(id uint64) <-chan byte {
+	m.Lock()
+	defer m.Unlock()
+
+	ch := make(chan byte, 1)
+	m.pending[id] = ch
+
+	return ch
+}
+
+func (m *circuitMap) resolve(id uint64, status byte) {
+	m.Lock()
+	defer m.Unlock()
+
+	if ch, exists := m.pending[id]; exists {
+		ch <- status
+		delete(m.pending, id)
+	}
+}
+
+func (m *circuitMap) cancel(id uint64) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.pending, id)
+}
+
+func (m *circuitMap) close() {
+	m.Lock()
+	entries := m.entries
+	m.entries = make(map[uint64]*relayConn)
+	m.Unlock()
+
+	for _, conn := range entries {
+		conn.shutdown()
+	}
+}
+
+// dialRelay opens a circuit to the peer with public key target through the relay at relayAddr.
+func (n *Node) dialRelay(ctx context.Context, relayAddr string, target PublicKey) (net.Conn, error) {
+	carrier, err := n.dialIfNotExists(ctx, relayAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	id := carrier.circuits.nextID(carrier.side)
+	ch := carrier.circuits.await(id)
+
+	conn := newRelayConn(carrier, id, RelayAddress(relayAddr, target))
+	carrier.circuits.add(conn)
+
+	if err := carrier.send(controlNonce, marshalControl(controlRelayOpen, marshalCircuitID(id, target[:]))); err != nil {
+		carrier.circuits.cancel(id)
+		carrier.circuits.remove(id)
+
+		return nil, err
+	}
+
+	var status byte
+
+	select {
+	case <-ctx.Done():
+		carrier.circuits.cancel(id)
+		conn.Close()
+
+		return nil, fmt.Errorf("failed to open circuit: %w", ctx.Err())
+	case <-carrier.clientDone:
+		carrier.circuits.cancel(id)
+		conn.shutdown()
+
+		return nil, fmt.Errorf("relay disconnected while opening circuit: %w", io.EOF)
+	case status = <-ch:
+	}
+
+	switch status {
+	case relayStatusOK:
+		return conn, nil
+	case relayStatusDisabled:
+		err = fmt.Errorf("%s is not a relay: %w", relayAddr, ErrRelayRefused)
+	case relayStatusNotConnected:
+		err = fmt.Errorf("%s is not connected to %s: %w", relayAddr, target, ErrRelayRefused)
+	case relayStatusLimitReached:
+		err = fmt.Errorf("%s has reached its relay limits: %w", relayAddr, ErrRelayRefused)
+	default:
+		err = fmt.Errorf("%s responded with unknown status %d: %w", relayAddr, status, ErrRelayRefused)
+	}
+
+	carrier.circuits.remove(id)
+	conn.shutdown()
+
+	return nil, err
+}
+
+// handleRelayControl handles all control frames related to relaying.
+func (c *Client) handleRelayControl(kind controlKind, payload []byte) error {
+	id, payload, err := unmarshalCircuitID(payload)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case controlRelayOpen:
+		if len(payload) != SizePublicKey {
+			return fmt.Errorf("got relay target of %d byte(s), but expected %d byte(s): %w",
+				len(payload), SizePublicKey, io.ErrUnexpectedEOF,
+			)
+		}
+
+		var target PublicKey
+		copy(target[:], payload)
+
+		status := c.node.relays.open(c, id, target)
+
+		return c.send(controlNonce, marshalControl(controlRelayOpenAck, marshalCircuitID(id, []byte{status})))
+	case controlRelayOpenAck:
+		if len(payload) != 1 {
+			return fmt.Errorf("got relay status of %d byte(s), but expected 1 byte: %w", len(payload), io.ErrUnexpectedEOF)
+		}
+
+		c.circuits.resolve(id, payload[0])
+	case controlRelayIncoming:
+		c.acceptRelayed(id)
+	case controlRelayData:
+		if conn := c.circuits.find(id); conn != nil {
+			if !conn.deliver(payload) {
+				c.Logger().Warn("Closing circuit as too much data was buffered.")
+				conn.Close()
+			}
+
+			return nil
+		}
+
+		c.node.relays.forward(c, id, payload)
+	case controlRelayClose:
+		if conn := c.circuits.find(id); conn != nil {
+			c.circuits.remove(id)
+			conn.shutdown()
+
+			return nil
+		}
+
+		c.node.relays.close(c, id)
+	}
+
+	return nil
+}
+
+// acceptRelayed accepts a circuit opened by a relay on behalf of another peer as an inbound connection.
+func (c *Client) acceptRelayed(id uint64) {
+	addr := c.Addr() + relayAddressSeparator + fmt.Sprintf("%d", id)
+
+	conn := newRelayConn(c, id, addr)
+	c.circuits.add(conn)
+
+	client, exists := c.node.inbound.get(c.node, addr)
+	if exists {
+		conn.Close()
+		return
+	}
+
+	go client.inbound(conn, addr)
+}
+
+type relayLeg struct {
+	client *Client
+	id     uint64
+}
+
+type relayCircuit struct {
+	ends  [2]relayLeg
+	bytes uint64
+	timer *time.Timer
+}
+
+func (r *relayCircuit) other(leg relayLeg) relayLeg {
+	if r.ends[0] == leg {
+		return r.ends[1]
+	}
+
+	return r.ends[0]
+}
+
+// relayMap keeps track of all circuits a node forwards as a relay.
+type relayMap struct {
+	sync.Mutex
+
+	enabled bool
+	limits  RelayLimits
+
+	entries map[relayLeg]*relayCircuit
+	peers   map[PublicKey]uint
+	count   uint
+}
+
+func newRelayMap() *relayMap {
+	return &relayMap{
+		entries: make(map[relayLeg]*relayCircuit),
+		peers:   make(map[PublicKey]uint),
+	}
+}
+
+// findClient returns a live client whose peer has target as its public key.
+func (n *Node) findClient(target PublicKey) *Client {
+	for _, client := range append(n.inbound.slice(), n.outbound.slice()...) {
+		if client.isReady() && client.Error() == nil && client.ID().ID == target {
+			return client
+		}
+	}
+
+	return nil
+}
+
+func (r *relayMap) open(initiator *Client, id uint64, target PublicKey) byte {
+	r.Lock()
+	enabled, limits := r.enabled, r.limits
+	r.Unlock()
+
+	if !enabled {
+		return relayStatusDisabled
+	}
+
+	responder := initiator.node.findClient(target)
+	if responder == nil {
+		return relayStatusNotConnected
+	}
+
+	r.Lock()
+
+	if limits.MaxCircuits > 0 && r.count >= limits.MaxCircuits {
+		r.Unlock()
+		return relayStatusLimitReached
+	}
+
+	if limits.MaxCircuitsPerPeer > 0 &&
+		(r.peers[initiator.ID().ID] >= limits.MaxCircuitsPerPeer || r.peers[target] >= limits.MaxCircuitsPerPeer) {
+		r.Unlock()
+		return relayStatusLimitReached
+	}
+
+	circuit := &relayCircuit{
+		ends: [2]relayLeg{
+			{client: initiator, id: id},
+			{client: responder, id: responder.circuits.nextID(responder.side)},
+		},
+	}
+
+	if limits.MaxCircuitDuration > 0 {
+		leg := circuit.ends[0]
+		circuit.timer = time.AfterFunc(limits.MaxCircuitDuration, func() {
+			r.close(leg.client, leg.id)
+			r.notify(leg, controlRelayClose)
+		})
+	}
+
+	r.entries[circuit.ends[0]] = circuit
+	r.entries[circuit.ends[1]] = circuit
+	r.peers[initiator.ID().ID]++
+	r.peers[target]++
+	r.count++
+
+	r.Unlock()
+
+	r.notify(circuit.ends[1], controlRelayIncoming)
+
+	return relayStatusOK
+}
+
+func (r *relayMap) notify(leg relayLeg, kind controlKind) {
+	_ = leg.client.send(controlNonce, marshalControl(kind, marshalCircuitID(leg.id, nil)))
+}
+
+// forward forwards data received from one end of a circuit to the other end of the circuit.
+func (r *relayMap) forward(from *Client, id uint64, data []byte) {
+	leg := relayLeg{client: from, id: id}
+
+	r.Lock()
+
+	circuit, exists := r.entries[leg]
+	if !exists {
+		r.Unlock()
+		return
+	}
+
+	circuit.bytes += uint64(len(data))
+	exceeded := r.limits.MaxCircuitBytes > 0 && circuit.bytes > r.limits.MaxCircuitBytes
+	to := circuit.other(leg)
+
+	r.Unlock()
+
+	if exceeded {
+		r.close(from, id)
+		r.notify(leg, controlRelayClose)
+
+		return
+	}
+
+	_ = to.client.send(controlNonce, marshalControl(controlRelayData, marshalCircuitID(to.id, data)))
+}
+
+// close removes the circuit which the end (from, id) is a part of, and notifies the other end of the circuit.
+func (r *relayMap) close(from *Client, id uint64) {
+	leg := relayLeg{client: from, id: id}
+
+	r.Lock()
+
+	circuit, exists := r.entries[leg]
+	if !exists {
+		r.Unlock()
+		return
+	}
+
+	r.remove(circuit)
+
+	r.Unlock()
+
+	r.notify(circuit.other(leg), controlRelayClose)
+}
+
+// release closes all circuits which client is an end of.
+func (r *relayMap) release(client *Client) {
+	var legs []relayLeg
+
+	r.Lock()
+
+	for leg := range r.entries {
+		if leg.client == client {
+			legs = append(legs, leg)
+		}
+	}
+
+	r.Unlock()
+
+	for _, leg := range legs {
+		r.close(leg.client, leg.id)
+	}
+}
+
+// remove removes circuit. It must be called with the lock held.
+func (r *relayMap) remove(circuit *relayCircuit) {
+	if circuit.timer != nil {
+		circuit.timer.Stop()
+	}
+
+	for _, leg := range circuit.ends {
+		delete(r.entries, leg)
+
+		if r.peers[leg.client.ID().ID]--; r.peers[leg.client.ID().ID] == 0 {
+			delete(r.peers, leg.client.ID().ID)
+		}
+	}
+
+	r.count--
+}
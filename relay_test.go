@@ -0,0 +1,156 @@
+package noise_test
+
+import (
+	"context"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"testing"
+	"time"
+)
+
+func TestRelayForwardsSessionToUnreachablePeer(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	relay, err := noise.NewNode(noise.WithNodeRelay(noise.DefaultRelayLimits))
+	assert.NoError(t, err)
+
+	defer relay.Close()
+
+	assert.NoError(t, relay.Listen())
+
+	// Have c advertise an address that may not be dialed (TEST-NET-1, RFC 5737), and have it connect to the relay.
+
+	c, err := noise.NewNode(noise.WithNodeAddress("192.0.2.1:3000"), noise.WithNodeMaxDialAttempts(1))
+	assert.NoError(t, err)
+
+	defer c.Close()
+
+	c.Handle(func(ctx noise.HandlerContext) error {
+		if ctx.IsRequest() {
+			return ctx.Send(append([]byte("echo: "), ctx.Data()...))
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, c.Listen())
+
+	_, err = c.Ping(context.Background(), relay.Addr())
+	assert.NoError(t, err)
+
+	a, err := noise.NewNode(noise.WithNodeMaxDialAttempts(1), noise.WithNodeDialFailureCooldown(0))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	direct, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	_, err = a.Ping(direct, c.ID().Address)
+	assert.Error(t, err)
+
+	addr := noise.RelayAddress(relay.Addr(), c.ID().ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	// The relay may not have completed its end of the handshake with c just yet.
+
+	waitFor(t, func() bool {
+		_, err := a.Ping(ctx, addr)
+		return err == nil
+	})
+
+	res, err := a.Request(ctx, addr, []byte("hello"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "echo: hello", res)
+
+	client, err := a.Ping(ctx, addr)
+	assert.NoError(t, err)
+	assert.Equal(t, c.ID().ID, client.ID().ID)
+
+	// Closing the relayed session closes the circuit on both ends, though leaves the connections to the relay intact.
+
+	client.Close()
+	client.WaitUntilClosed()
+
+	waitFor(t, func() bool {
+		for _, client := range c.Inbound() {
+			if client.Addr() != relay.Addr() {
+				return false
+			}
+		}
+
+		return true
+	})
+
+	assert.Len(t, c.Outbound(), 1)
+}
+
+func TestRelayRefusesCircuits(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	relay, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer relay.Close()
+
+	assert.NoError(t, relay.Listen())
+
+	c, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer c.Close()
+
+	assert.NoError(t, c.Listen())
+
+	a, err := noise.NewNode(noise.WithNodeMaxDialAttempts(1), noise.WithNodeDialFailureCooldown(0))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	assert.NoError(t, a.Listen())
+
+	// The relay refuses as it has not opted into acting as a relay.
+
+	_, err = a.Ping(context.Background(), noise.RelayAddress(relay.Addr(), c.ID().ID))
+	assert.True(t, errors.Is(err, noise.ErrRelayRefused))
+
+	// The relay refuses as c is not connected to it.
+
+	other, err := noise.NewNode(noise.WithNodeRelay(noise.RelayLimits{MaxCircuitsPerPeer: 1}))
+	assert.NoError(t, err)
+
+	defer other.Close()
+
+	assert.NoError(t, other.Listen())
+
+	_, err = a.Ping(context.Background(), noise.RelayAddress(other.Addr(), c.ID().ID))
+	assert.True(t, errors.Is(err, noise.ErrRelayRefused))
+
+	// The relay refuses as it has reached its limit of circuits per peer.
+
+	_, err = c.Ping(context.Background(), other.Addr())
+	assert.NoError(t, err)
+
+	// The relay may not have completed its end of the handshake with c just yet.
+
+	waitFor(t, func() bool {
+		_, err := a.Ping(context.Background(), noise.RelayAddress(other.Addr(), c.ID().ID))
+		return err == nil
+	})
+
+	b, err := noise.NewNode(noise.WithNodeMaxDialAttempts(1))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, b.Listen())
+
+	_, err = b.Ping(context.Background(), noise.RelayAddress(other.Addr(), c.ID().ID))
+	assert.True(t, errors.Is(err, noise.ErrRelayRefused))
+}
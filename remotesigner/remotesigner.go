@@ -0,0 +1,276 @@
+// Package remotesigner is an example of a noise.Signer that keeps the private key of a node within a separate
+// signing process, which the node talks to over a local socket. It is primarily intended for testing, and as a
+// reference for implementing signers backed by hardware security modules or key management services.
+//
+// Requests and responses are exchanged over the socket as frames prefixed by their length as a big-endian uint32.
+// The first byte of a request is its operation (opPublic, opSign, or opKey), followed by its payload. The first byte
+// of a response is its status (statusOK or statusError), followed by the result of the request or an error message.
+package remotesigner
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/perlin-network/noise"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	opPublic byte = iota + 1
+	opSign
+	opKey
+)
+
+const (
+	statusOK byte = iota
+	statusError
+)
+
+// maxFrameSize is the max size of a frame exchanged between a Client and a Server.
+const maxFrameSize = 4 << 20
+
+// defaultTimeout is the duration a Client waits for a Server to respond to a request by default.
+const defaultTimeout = 5 * time.Second
+
+// Server serves signing requests from Clients with a noise.Signer, which is typically backed by an in-process
+// private key via noise.NewSigner.
+type Server struct {
+	signer noise.Signer
+
+	wg sync.WaitGroup
+}
+
+// NewServer returns a Server which signs data with signer.
+func NewServer(signer noise.Signer) *Server {
+	return &Server{signer: signer}
+}
+
+// Serve accepts connections from listener, and serves signing requests over them until listener is closed. It
+// waits for all accepted connections to be closed before returning the error that caused listener to stop accepting
+// connections.
+func (s *Server) Serve(listener net.Listener) error {
+	defer s.wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		s.wg.Add(1)
+
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	for {
+		req, err := readFrame(reader)
+		if err != nil || len(req) == 0 {
+			return
+		}
+
+		var res []byte
+
+		switch req[0] {
+		case opPublic:
+			publicKey := s.signer.Public()
+			res = append([]byte{statusOK}, publicKey[:]...)
+		case opKey:
+			key := noise.IdentityKey(s.signer.Public())
+
+			if keyed, ok := s.signer.(noise.KeyedSigner); ok {
+				key = keyed.Key()
+			}
+
+			res = append([]byte{statusOK, byte(key.Type())}, key.Bytes()...)
+		case opSign:
+			signature, err := s.signer.Sign(req[1:])
+			if err != nil {
+				res = append([]byte{statusError}, err.Error()...)
+			} else {
+				res = append([]byte{statusOK}, signature[:]...)
+			}
+		default:
+			res = append([]byte{statusError}, fmt.Sprintf("unknown operation %d", req[0])...)
+		}
+
+		if err := writeFrame(writer, res); err != nil {
+			return
+		}
+	}
+}
+
+// Client is a noise.Signer which has data signed by a Server over a connection.
+type Client struct {
+	sync.Mutex
+
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+
+	key noise.IdentityKey
+
+	timeout time.Duration
+}
+
+var _ noise.KeyedSigner = (*Client)(nil)
+
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithTimeout sets the duration a Client waits for the Server to respond to a request, including the time it takes
+// to dial the Server. Should a request time out, the connection to the Server is closed, and all subsequent requests
+// fail. By default, it is set to 5 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// Dial connects to the Server listening on address over network (i.e. "unix" or "tcp"), and fetches the public key
+// of the private key the Server signs data with. The public key may be of any key type registered with noise.
+func Dial(network, address string, opts ...Option) (*Client, error) {
+	c := &Client{timeout: defaultTimeout}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, err := net.DialTimeout(network, address, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial signer: %w", err)
+	}
+
+	c.conn, c.reader, c.writer = conn, bufio.NewReader(conn), bufio.NewWriter(conn)
+
+	buf, err := c.request(opKey, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if len(buf) < 1 {
+		conn.Close()
+		return nil, fmt.Errorf("signer responded with an empty public key: %w", io.ErrUnexpectedEOF)
+	}
+
+	if c.key, err = noise.UnmarshalIdentityKey(noise.KeyType(buf[0]), buf[1:]); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("signer responded with an invalid public key: %w", err)
+	}
+
+	return c, nil
+}
+
+// Public implements noise.Signer, and returns the identifier of the public key fetched from the Server upon
+// dialing it.
+func (c *Client) Public() noise.PublicKey {
+	return c.key.ID()
+}
+
+// Key implements noise.KeyedSigner, and returns the public key fetched from the Server upon dialing it.
+func (c *Client) Key() noise.IdentityKey {
+	return c.key
+}
+
+// Sign implements noise.Signer, and has data signed by the Server.
+func (c *Client) Sign(data []byte) (noise.Signature, error) {
+	buf, err := c.request(opSign, data)
+	if err != nil {
+		return noise.ZeroSignature, err
+	}
+
+	if len(buf) != noise.SizeSignature {
+		return noise.ZeroSignature, fmt.Errorf("signer responded with a signature of %d byte(s), but expected %d byte(s)",
+			len(buf), noise.SizeSignature,
+		)
+	}
+
+	return noise.UnmarshalSignature(buf), nil
+}
+
+// Close closes the connection to the Server.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) request(op byte, payload []byte) ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	// A request that failed part-way through leaves the connection with a partial frame, and so the connection is
+	// closed should a request fail.
+
+	if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+		return nil, fmt.Errorf("failed to set deadline on signer connection: %w", err)
+	}
+
+	if err := writeFrame(c.writer, append([]byte{op}, payload...)); err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("failed to send request to signer: %w", err)
+	}
+
+	res, err := readFrame(c.reader)
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("failed to read response from signer: %w", err)
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("signer responded with an empty response: %w", io.ErrUnexpectedEOF)
+	}
+
+	if res[0] != statusOK {
+		return nil, fmt.Errorf("signer responded with an error: %w", errors.New(string(res[1:])))
+	}
+
+	return res[1:], nil
+}
+
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	var header [4]byte
+
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("got frame of %d byte(s), but max is %d byte(s)", size, maxFrameSize)
+	}
+
+	buf := make([]byte, size)
+
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+func writeFrame(writer *bufio.Writer, buf []byte) error {
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(buf)))
+
+	if _, err := writer.Write(header[:]); err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(buf); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
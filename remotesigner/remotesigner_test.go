@@ -0,0 +1,234 @@
+package remotesigner_test
+
+import (
+	"context"
+	"errors"
+	"github.com/perlin-network/noise"
+	"github.com/perlin-network/noise/remotesigner"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingSigner struct {
+	noise.Signer
+}
+
+func (s failingSigner) Sign([]byte) (noise.Signature, error) {
+	return noise.ZeroSignature, errors.New("signer is locked")
+}
+
+// lockableSigner fails to sign data while it is locked.
+type lockableSigner struct {
+	noise.Signer
+	locked int32
+}
+
+func (s *lockableSigner) Sign(data []byte) (noise.Signature, error) {
+	if atomic.LoadInt32(&s.locked) == 1 {
+		return noise.ZeroSignature, errors.New("signer is locked")
+	}
+
+	return s.Signer.Sign(data)
+}
+
+// blockingSigner never responds to requests to sign data until it is released.
+type blockingSigner struct {
+	noise.Signer
+	release chan struct{}
+}
+
+func (s blockingSigner) Sign(data []byte) (noise.Signature, error) {
+	<-s.release
+	return s.Signer.Sign(data)
+}
+
+func TestNodeWithRemoteSigner(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	dir, err := ioutil.TempDir("", "remotesigner")
+	assert.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	publicKey, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, "signer.sock"))
+	assert.NoError(t, err)
+
+	server := remotesigner.NewServer(noise.NewSigner(privateKey))
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	signer, err := remotesigner.Dial("unix", listener.Addr().String())
+	assert.NoError(t, err)
+
+	assert.Equal(t, publicKey, signer.Public())
+
+	a, err := noise.NewNode(noise.WithNodeSigner(signer))
+	assert.NoError(t, err)
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// The ID of a is signed by the remote signer, and the handshake is signed by the remote signer.
+
+	assert.Equal(t, publicKey, a.ID().ID)
+	assert.NoError(t, a.ID().Verify())
+
+	client, err := b.Ping(context.Background(), a.Addr())
+	assert.NoError(t, err)
+	assert.Equal(t, publicKey, client.ID().ID)
+
+	a.Close()
+	b.Close()
+
+	assert.NoError(t, signer.Close())
+	assert.NoError(t, listener.Close())
+	assert.Error(t, <-served)
+}
+
+func TestNodeWithFailingSigner(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	a, err := noise.NewNode(noise.WithNodeSigner(failingSigner{Signer: noise.NewSigner(privateKey)}))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	assert.True(t, errors.Is(a.ID().Verify(), noise.ErrRecordUnsigned))
+
+	_, err = a.Ping(context.Background(), b.Addr())
+	assert.Error(t, err)
+
+	// Sign reports failures via a zero signature, and TrySign returns them.
+
+	assert.Equal(t, noise.ZeroSignature, a.Sign([]byte("data")))
+
+	_, err = a.TrySign([]byte("data"))
+	assert.Error(t, err)
+}
+
+func TestRemoteSignerWithSecp256k1Key(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	privateKey, err := noise.GenerateSecp256k1Key(nil)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := remotesigner.NewServer(noise.NewSecp256k1Signer(privateKey))
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	signer, err := remotesigner.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+
+	assert.Equal(t, noise.IdentityKey(privateKey.Public()), signer.Key())
+	assert.Equal(t, privateKey.Public().ID(), signer.Public())
+
+	signature, err := signer.Sign([]byte("data"))
+	assert.NoError(t, err)
+	assert.True(t, privateKey.Public().Verify([]byte("data"), signature))
+
+	assert.NoError(t, signer.Close())
+	assert.NoError(t, listener.Close())
+	assert.Error(t, <-served)
+}
+
+func TestNodeKeepsSignedIDWhenSigningFails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	signer := &lockableSigner{Signer: noise.NewSigner(privateKey)}
+
+	node, err := noise.NewNode(noise.WithNodeSigner(signer), noise.WithNodeRecordTTL(4*time.Second))
+	assert.NoError(t, err)
+
+	assert.NoError(t, node.Listen())
+
+	defer node.Close()
+
+	signed := node.ID()
+	assert.NoError(t, signed.Verify())
+
+	atomic.StoreInt32(&signer.locked, 1)
+
+	// The ID is kept as-is should it fail to be re-signed once it is halfway through to expiring, or should it
+	// fail to be signed with an updated address.
+
+	time.Sleep(2100 * time.Millisecond)
+
+	assert.True(t, signed.Equal(node.ID()))
+	assert.NoError(t, node.ID().Verify())
+
+	assert.Error(t, node.UpdateAddress("[::ffff:192.0.2.1]:3000"))
+	assert.True(t, signed.Equal(node.ID()))
+
+	atomic.StoreInt32(&signer.locked, 0)
+
+	resigned := node.ID()
+	assert.True(t, resigned.Supersedes(signed))
+	assert.NoError(t, resigned.Verify())
+}
+
+func TestRemoteSignerTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	_, privateKey, err := noise.GenerateKeys(nil)
+	assert.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	release := make(chan struct{})
+	server := remotesigner.NewServer(blockingSigner{Signer: noise.NewSigner(privateKey), release: release})
+
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+
+	signer, err := remotesigner.Dial("tcp", listener.Addr().String(), remotesigner.WithTimeout(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	start := time.Now()
+
+	_, err = signer.Sign([]byte("data"))
+	assert.Error(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// The connection to the server is closed once a request times out.
+
+	_, err = signer.Sign([]byte("data"))
+	assert.Error(t, err)
+
+	close(release)
+
+	assert.NoError(t, listener.Close())
+	assert.Error(t, <-served)
+}
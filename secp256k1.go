@@ -0,0 +1,195 @@
+package noise
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"io"
+)
+
+const (
+	// SizeSecp256k1PublicKey is the size in bytes of a compressed secp256k1 public key.
+	SizeSecp256k1PublicKey = 33
+
+	// SizeSecp256k1PrivateKey is the size in bytes of a secp256k1 private key.
+	SizeSecp256k1PrivateKey = 32
+)
+
+type (
+	// Secp256k1PublicKey is a secp256k1 public key in its 33-byte compressed form.
+	Secp256k1PublicKey [SizeSecp256k1PublicKey]byte
+
+	// Secp256k1PrivateKey is a secp256k1 private key, which is a 32-byte big-endian scalar.
+	Secp256k1PrivateKey [SizeSecp256k1PrivateKey]byte
+)
+
+// GenerateSecp256k1Key randomly generates a new secp256k1 private key. Nil may be passed to r in order to use
+// crypto/rand by default. It returns an error if r is invalid.
+func GenerateSecp256k1Key(r io.Reader) (Secp256k1PrivateKey, error) {
+	if r == nil {
+		r = rand.Reader
+	}
+
+	for {
+		var key Secp256k1PrivateKey
+
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return key, fmt.Errorf("failed to generate secp256k1 key: %w", err)
+		}
+
+		if _, err := UnmarshalSecp256k1PrivateKey(key[:]); err == nil {
+			return key, nil
+		}
+	}
+}
+
+// UnmarshalSecp256k1PrivateKey decodes buf into a secp256k1 private key. It returns an error should buf not be a
+// 32-byte scalar within the order of the curve.
+func UnmarshalSecp256k1PrivateKey(buf []byte) (Secp256k1PrivateKey, error) {
+	var key Secp256k1PrivateKey
+
+	if len(buf) != SizeSecp256k1PrivateKey {
+		return key, fmt.Errorf("got secp256k1 private key of %d byte(s), but expected %d byte(s): %w",
+			len(buf), SizeSecp256k1PrivateKey, io.ErrUnexpectedEOF,
+		)
+	}
+
+	var d secp256k1.ModNScalar
+
+	if overflow := d.SetByteSlice(buf); overflow || d.IsZero() {
+		return key, errors.New("secp256k1 private key is out of range")
+	}
+
+	copy(key[:], buf)
+
+	return key, nil
+}
+
+// Public returns the public key associated to this private key.
+func (k Secp256k1PrivateKey) Public() Secp256k1PublicKey {
+	var key Secp256k1PublicKey
+	copy(key[:], secp256k1.PrivKeyFromBytes(k[:]).PubKey().SerializeCompressed())
+
+	return key
+}
+
+// Sign signs the SHA-256 hash of data with ECDSA. The signature is encoded as the 32-byte big-endian r value
+// followed by the 32-byte big-endian s value, with s normalized to the lower half of the order of the curve. The
+// nonce of a signature is derived deterministically from this private key and the hash of data per RFC 6979.
+func (k Secp256k1PrivateKey) Sign(data []byte) Signature {
+	digest := sha256.Sum256(data)
+
+	privateKey := secp256k1.PrivKeyFromBytes(k[:])
+	defer privateKey.Zero()
+
+	// A compact signature is prefixed by a byte which allows for the public key to be recovered from it.
+
+	var signature Signature
+	copy(signature[:], ecdsa.SignCompact(privateKey, digest[:], true)[1:])
+
+	return signature
+}
+
+// String returns the hexadecimal representation of this private key.
+func (k Secp256k1PrivateKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// NewSecp256k1Signer returns a Signer which signs data in-process with privateKey. Nodes whose Signer is the
+// returned Signer are identified by the public key of privateKey.
+func NewSecp256k1Signer(privateKey Secp256k1PrivateKey) KeyedSigner {
+	return secp256k1Signer{privateKey: privateKey, publicKey: privateKey.Public()}
+}
+
+// UnmarshalSecp256k1PublicKey decodes buf into a secp256k1 public key in its 33-byte compressed form. It returns
+// an error should buf not be a point on the curve.
+func UnmarshalSecp256k1PublicKey(buf []byte) (Secp256k1PublicKey, error) {
+	var key Secp256k1PublicKey
+
+	if len(buf) != SizeSecp256k1PublicKey {
+		return key, fmt.Errorf("got secp256k1 public key of %d byte(s), but expected %d byte(s): %w",
+			len(buf), SizeSecp256k1PublicKey, io.ErrUnexpectedEOF,
+		)
+	}
+
+	if _, err := secp256k1.ParsePubKey(buf); err != nil {
+		return key, fmt.Errorf("secp256k1 public key is not a point on the curve: %w", err)
+	}
+
+	copy(key[:], buf)
+
+	return key, nil
+}
+
+var _ IdentityKey = Secp256k1PublicKey{}
+
+// Type implements IdentityKey, and returns KeyTypeSecp256k1.
+func (k Secp256k1PublicKey) Type() KeyType {
+	return KeyTypeSecp256k1
+}
+
+// Bytes implements IdentityKey, and returns the compressed encoding of this public key.
+func (k Secp256k1PublicKey) Bytes() []byte {
+	return append([]byte{}, k[:]...)
+}
+
+// ID implements IdentityKey, and returns the SHA-256 hash of the compressed encoding of this public key.
+func (k Secp256k1PublicKey) ID() PublicKey {
+	return sha256.Sum256(k[:])
+}
+
+// Verify implements IdentityKey, and returns true if signature is an ECDSA signature of the SHA-256 hash of data
+// produced by (Secp256k1PrivateKey).Sign. Signatures whose s value is not normalized are rejected so that
+// signatures may not be malleated.
+func (k Secp256k1PublicKey) Verify(data []byte, signature Signature) bool {
+	publicKey, err := secp256k1.ParsePubKey(k[:])
+	if err != nil {
+		return false
+	}
+
+	var r, s secp256k1.ModNScalar
+
+	if overflow := r.SetByteSlice(signature[:32]); overflow || r.IsZero() {
+		return false
+	}
+
+	if overflow := s.SetByteSlice(signature[32:]); overflow || s.IsZero() || s.IsOverHalfOrder() {
+		return false
+	}
+
+	digest := sha256.Sum256(data)
+
+	return ecdsa.NewSignature(&r, &s).Verify(digest[:], publicKey)
+}
+
+// String returns the hexadecimal representation of this public key.
+func (k Secp256k1PublicKey) String() string {
+	return hex.EncodeToString(k[:])
+}
+
+// MarshalJSON returns the hexadecimal representation of this public key in JSON. It should never throw an error.
+func (k Secp256k1PublicKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+type secp256k1Signer struct {
+	privateKey Secp256k1PrivateKey
+	publicKey  Secp256k1PublicKey
+}
+
+func (s secp256k1Signer) Public() PublicKey {
+	return s.publicKey.ID()
+}
+
+func (s secp256k1Signer) Key() IdentityKey {
+	return s.publicKey
+}
+
+func (s secp256k1Signer) Sign(data []byte) (Signature, error) {
+	return s.privateKey.Sign(data), nil
+}
@@ -0,0 +1,30 @@
+package noise
+
+// Signer signs data on behalf of the identity of a node. It allows for the private key of a node to be kept outside
+// of the node, i.e. within a separate signing process or a hardware security module. Implementations must be safe
+// to use concurrently.
+type Signer interface {
+	// Public returns the public key of the private key data is signed with.
+	Public() PublicKey
+
+	// Sign returns the signature of data signed with the private key of the identity of a node.
+	Sign(data []byte) (Signature, error)
+}
+
+type privateKeySigner struct {
+	privateKey PrivateKey
+	publicKey  PublicKey
+}
+
+// NewSigner returns a Signer which signs data in-process with privateKey.
+func NewSigner(privateKey PrivateKey) Signer {
+	return privateKeySigner{privateKey: privateKey, publicKey: privateKey.Public()}
+}
+
+func (s privateKeySigner) Public() PublicKey {
+	return s.publicKey
+}
+
+func (s privateKeySigner) Sign(data []byte) (Signature, error) {
+	return s.privateKey.Sign(data), nil
+}
@@ -0,0 +1,359 @@
+package noise
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// streamMarker marks a response as being one of a stream of responses to a single request. A stream is comprised of
+// any number of responses marked streamItem, followed by a response marked streamEnd which carries no data, or which
+// carries the error the stream ended with.
+type streamMarker byte
+
+const (
+	streamItem streamMarker = iota + 1
+	streamEnd
+)
+
+// ResponseStream is a stream of responses a peer sends back to a single request made via (*Node).RequestStream or
+// (*Node).RequestMessageStream. Responses are read by calling Next until it returns false, after which Err reports
+// why the stream ended:
+//
+//	for stream.Next() {
+//		... stream.Data() ...
+//	}
+//
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+//
+// A stream that is not read until its end must be closed via Close, which has the peer stop streaming responses. The
+// peer streams back at most 32 responses ahead of those read, after which (*HandlerContext).SendStream blocks on the
+// side of the peer until more responses are read.
+//
+// A ResponseStream may not be read concurrently.
+type ResponseStream struct {
+	client  *Client
+	ctx     context.Context
+	nonce   uint64
+	pending *pendingStream
+	start   time.Time
+
+	data   []byte
+	header Header
+	err    error
+
+	// read is the number of responses read that the peer has yet to be granted credit back for.
+	read uint32
+
+	// last is true should the response last read be the last response of the stream, which is the case should the
+	// peer have sent back a single response rather than a stream of responses.
+	last bool
+	done bool
+}
+
+// Next awaits the next response of the stream, which is then available via Data, Message, and Header. It returns
+// false once the stream has ended, once the peer responds with an error, once the context the stream was requested
+// with is cancelled/expired, or once the connection to the peer is closed. Err reports why the stream ended.
+func (s *ResponseStream) Next() bool {
+	if s.done {
+		return false
+	}
+
+	if s.last {
+		s.finish(nil)
+		return false
+	}
+
+	select {
+	case msg, ok := <-s.pending.responses:
+		switch {
+		case !ok:
+			s.finish(io.EOF)
+		case msg.header.err != nil:
+			s.finish(msg.header.err)
+		case msg.header.stream == streamEnd:
+			s.finish(nil)
+		default:
+			s.client.node.metrics.streamRecv.Inc()
+
+			s.data, s.header = msg.data, msg.header
+			s.last = msg.header.stream != streamItem
+
+			// Grant the peer credit for responses read in batches, rather than for every response read.
+
+			if !s.last {
+				if s.read++; s.read == streamBufferSize/2 {
+					s.client.sendCredit(s.nonce, s.read)
+					s.read = 0
+				}
+			}
+
+			return true
+		}
+	case <-s.ctx.Done():
+		if errors.Is(s.ctx.Err(), context.DeadlineExceeded) {
+			s.client.node.metrics.requestTimeouts.Inc()
+		} else {
+			s.client.node.metrics.requestCancels.Inc()
+		}
+
+		s.abandon()
+		s.finish(s.ctx.Err())
+	}
+
+	return false
+}
+
+// Data returns the data of the response last read via Next.
+func (s *ResponseStream) Data() []byte {
+	return s.data
+}
+
+// Header returns the headers the response last read via Next was sent with.
+func (s *ResponseStream) Header() Header {
+	return s.header
+}
+
+// Message decodes the data of the response last read via Next into a Go type registered via (*Node).RegisterMessage.
+func (s *ResponseStream) Message() (Serializable, error) {
+	msg, err := s.client.node.DecodeMessage(s.data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return msg, nil
+}
+
+// Err returns the error the stream ended with, which is a *RemoteError should the peer have ended the stream with an
+// error. It returns nil should the stream have ended successfully, should the stream have been closed via Close, or
+// should the stream not yet have ended.
+func (s *ResponseStream) Err() error {
+	return s.err
+}
+
+// Close abandons the stream should it not yet have ended, and has the peer stop streaming responses. Close may be
+// called more than once.
+func (s *ResponseStream) Close() {
+	if s.done {
+		return
+	}
+
+	s.client.node.metrics.requestCancels.Inc()
+
+	s.abandon()
+	s.finish(nil)
+}
+
+func (s *ResponseStream) abandon() {
+	if s.client.requests.abandonStream(s.nonce) {
+		s.client.sendCancel(s.nonce)
+	}
+}
+
+func (s *ResponseStream) finish(err error) {
+	s.done = true
+	s.err = err
+	s.data, s.header = nil, Header{}
+
+	if err != nil {
+		s.client.node.metrics.requestFails.Inc()
+		return
+	}
+
+	s.client.node.metrics.requests.Inc()
+	s.client.node.metrics.requestDuration.ObserveSince(s.start)
+}
+
+// RequestStream sends data as a request to the peer at addr the same way (*Node).Request does, and returns the stream
+// of responses the peer sends back via (*HandlerContext).SendStream. Should the peer send back a single response
+// instead, the stream yields that one response. See ResponseStream.
+//
+// ctx governs the stream for as long as it is read from, and should thus not be cancelled until the stream has
+// ended or has been closed. The deadline of ctx is sent alongside the request.
+func (n *Node) RequestStream(ctx context.Context, addr string, data []byte) (*ResponseStream, error) {
+	call := &Call{Addr: addr, Request: true, Stream: true, Data: data}
+
+	if err := n.invoke(ctx, call); err != nil {
+		return nil, err
+	}
+
+	return call.stream, nil
+}
+
+// RequestMessageStream encodes req which is a Go type registered via (*Node).RegisterMessage, and sends it as a
+// request to addr, and returns the stream of responses the peer at addr sends back. Responses may be decoded via
+// (*ResponseStream).Message. For more details, refer to (*Node).RequestStream.
+func (n *Node) RequestMessageStream(ctx context.Context, addr string, req Serializable) (*ResponseStream, error) {
+	data, err := n.EncodeMessage(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	call := &Call{
+		Addr:    addr,
+		Request: true,
+		Stream:  true,
+		Message: req,
+		Opcode:  binary.BigEndian.Uint16(data[:2]),
+		Data:    data,
+	}
+
+	if err := n.invoke(ctx, call); err != nil {
+		return nil, err
+	}
+
+	return call.stream, nil
+}
+
+// SendStream sends data back to the peer as one of a stream of responses to the request that some peer has sent you.
+// The stream ends once EndStream or SendError is called, or otherwise once all handlers have handled the request. It
+// returns an error if the data received is not of a request, if a single response was already sent to the request,
+// if the stream has already ended, if the peer predates streamed responses, or if the peer gave up on its request.
+//
+// SendStream blocks should the peer have yet to read 32 responses streamed back, until the peer reads them or gives
+// up on its request. Another worker takes over handling messages in the meantime, such that handlers blocked in
+// SendStream do not keep the node from handling other messages.
+//
+// SendStream may be called concurrently.
+func (ctx *HandlerContext) SendStream(data []byte) error {
+	if !ctx.IsRequest() {
+		return errors.New("server-side may only stream back responses to a request")
+	}
+
+	if ctx.client.envelope == 0 {
+		return errors.New("peer does not support receiving a stream of responses to a request")
+	}
+
+	if err := ctx.acquireCredit(); err != nil {
+		return err
+	}
+
+	ctx.response.Lock()
+	defer ctx.response.Unlock()
+
+	if ctx.response.status == responseSent {
+		return errors.New("server-side may not stream back responses to a request that was already responded to")
+	}
+
+	ctx.response.status = responseStreaming
+
+	return ctx.client.enqueue(message{nonce: ctx.msg.nonce, header: Header{stream: streamItem}, data: data})
+}
+
+// SendStreamMessage encodes and serializes a Go type into a byte slice, and sends it back to the peer as one of a
+// stream of responses. Refer to (*HandlerContext).SendStream and (*HandlerContext).SendMessage for more details.
+//
+// SendStreamMessage may be called concurrently.
+func (ctx *HandlerContext) SendStreamMessage(msg Serializable) error {
+	data, err := ctx.client.node.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	if data, err = ctx.client.remap.apply(data); err != nil {
+		return err
+	}
+
+	return ctx.SendStream(data)
+}
+
+// EndStream ends the stream of responses being sent back to the request that some peer has sent you, which is empty
+// should no responses have been streamed back yet. It returns an error if the data received is not of a request, if
+// the request was already responded to or its stream already ended, or if the peer predates streamed responses.
+//
+// EndStream may be called concurrently.
+func (ctx *HandlerContext) EndStream() error {
+	if !ctx.IsRequest() {
+		return errors.New("server-side may only stream back responses to a request")
+	}
+
+	if ctx.client.envelope == 0 {
+		return errors.New("peer does not support receiving a stream of responses to a request")
+	}
+
+	ctx.response.Lock()
+	defer ctx.response.Unlock()
+
+	if ctx.response.status == responseSent {
+		return errors.New("server-side may not end the stream of responses to a request that was already responded to")
+	}
+
+	ctx.response.status = responseSent
+
+	return ctx.client.enqueue(message{nonce: ctx.msg.nonce, header: Header{stream: streamEnd}})
+}
+
+// acquireCredit waits until the peer has granted credit to stream back another response, and takes it. It returns an
+// error should the peer give up on its request in the meantime. Peers that predate control frames are unable to grant
+// credit, and are streamed back responses without any.
+//
+// The worker of the handler awaiting credit is lent out in the meantime. Otherwise, should all workers await credit,
+// the work queue would fill up and block the connection the credit is granted over.
+func (ctx *HandlerContext) acquireCredit() error {
+	if !ctx.client.control {
+		return ctx.Context().Err()
+	}
+
+	var resume func()
+
+	defer func() {
+		if resume != nil {
+			resume()
+		}
+	}()
+
+	for {
+		ctx.response.Lock()
+
+		if ctx.response.credit > 0 {
+			ctx.response.credit--
+
+			if ctx.response.credit > 0 {
+				ctx.response.signal()
+			}
+
+			ctx.response.Unlock()
+
+			return ctx.Context().Err()
+		}
+
+		ctx.response.Unlock()
+
+		if resume == nil {
+			resume = ctx.client.node.lendWorker()
+		}
+
+		select {
+		case <-ctx.response.granted:
+		case <-ctx.Context().Done():
+			return ctx.Context().Err()
+		}
+	}
+}
+
+// grant grants credit to stream back n more responses, which never exceeds the number of responses the requester
+// buffers.
+func (r *responseState) grant(n uint32) {
+	r.Lock()
+	defer r.Unlock()
+
+	if n > streamBufferSize-uint32(r.credit) {
+		n = streamBufferSize - uint32(r.credit)
+	}
+
+	r.credit += int(n)
+	r.signal()
+}
+
+// signal wakes up a response awaiting credit to be streamed back. It must be called with the lock held.
+func (r *responseState) signal() {
+	select {
+	case r.granted <- struct{}{}:
+	default:
+	}
+}
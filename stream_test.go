@@ -0,0 +1,429 @@
+package noise_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/goleak"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRequestStream(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	// Stream back as many responses as requested, which may be more than a stream buffers, followed by an error
+	// should three responses be requested. A request for a single response is responded to with a single response.
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if !ctx.IsRequest() {
+			return nil
+		}
+
+		request := string(ctx.Data())
+
+		if request == "single" {
+			return ctx.Send([]byte("only"))
+		}
+
+		count, err := strconv.Atoi(request)
+		if err != nil {
+			return &noise.RemoteError{Code: 400, Message: "malformed page count"}
+		}
+
+		if count == 0 {
+			return ctx.EndStream()
+		}
+
+		for i := 0; i < count; i++ {
+			if err := ctx.SendStream([]byte(strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+
+		assert.Error(t, ctx.Send([]byte("single")))
+
+		if count == 3 {
+			return &noise.RemoteError{Code: 500, Message: "ran out of pages"}
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	read := func(request string) ([]string, error) {
+		stream, err := a.RequestStream(context.Background(), b.Addr(), []byte(request))
+		if err != nil {
+			return nil, err
+		}
+
+		defer stream.Close()
+
+		var responses []string
+
+		for stream.Next() {
+			responses = append(responses, string(stream.Data()))
+		}
+
+		return responses, stream.Err()
+	}
+
+	responses, err := read("100")
+	assert.NoError(t, err)
+	assert.Len(t, responses, 100)
+
+	for i, response := range responses {
+		assert.Equal(t, strconv.Itoa(i), response)
+	}
+
+	responses, err = read("0")
+	assert.NoError(t, err)
+	assert.Empty(t, responses)
+
+	responses, err = read("3")
+	assert.Equal(t, []string{"0", "1", "2"}, responses)
+
+	var remote *noise.RemoteError
+	assert.True(t, errors.As(err, &remote))
+	assert.EqualValues(t, 500, remote.Code)
+
+	responses, err = read("single")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"only"}, responses)
+
+	// A request that does not await a stream only gets the first response of the stream.
+
+	res, err := a.Request(context.Background(), b.Addr(), []byte("5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "0", string(res))
+
+	responses, err = read("2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0", "1"}, responses)
+
+	assert.EqualValues(t, 106, a.Stats().Counters["noise_stream_responses_received_total"])
+}
+
+func TestRequestMessageStream(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	for _, node := range []*noise.Node{a, b} {
+		node.RegisterMessage(echo{}, unmarshalEcho)
+	}
+
+	b.HandleMessage(echo{}, func(ctx noise.HandlerContext, msg echo) error {
+		for i := 0; i < 3; i++ {
+			if err := ctx.SendStreamMessage(echo{data: []byte(fmt.Sprintf("%s %d", msg.data, i))}); err != nil {
+				return err
+			}
+		}
+
+		if err := ctx.EndStream(); err != nil {
+			return err
+		}
+
+		assert.Error(t, ctx.SendStream(nil))
+		assert.Error(t, ctx.EndStream())
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	stream, err := a.RequestMessageStream(context.Background(), b.Addr(), echo{data: []byte("page")})
+	assert.NoError(t, err)
+
+	defer stream.Close()
+
+	var responses []noise.Serializable
+
+	for stream.Next() {
+		msg, err := stream.Message()
+		assert.NoError(t, err)
+
+		responses = append(responses, msg)
+	}
+
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []noise.Serializable{
+		echo{data: []byte("page 0")},
+		echo{data: []byte("page 1")},
+		echo{data: []byte("page 2")},
+	}, responses)
+}
+
+func TestResponseStreamCancellation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	stopped := make(chan error, 2)
+
+	// Stream responses until the requester gives up.
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if !ctx.IsRequest() {
+			return nil
+		}
+
+		if string(ctx.Data()) == "ping" {
+			return ctx.Send([]byte("pong"))
+		}
+
+		for {
+			if err := ctx.SendStream([]byte("page")); err != nil {
+				stopped <- err
+				return err
+			}
+		}
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// Close the stream after having read some responses.
+
+	stream, err := a.RequestStream(context.Background(), b.Addr(), []byte("pages"))
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.True(t, stream.Next())
+		assert.Equal(t, "page", string(stream.Data()))
+	}
+
+	stream.Close()
+
+	assert.False(t, stream.Next())
+	assert.NoError(t, stream.Err())
+
+	assert.True(t, errors.Is(<-stopped, context.Canceled))
+
+	// Cancel the context the stream was requested with.
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err = a.RequestStream(ctx, b.Addr(), []byte("pages"))
+	assert.NoError(t, err)
+
+	assert.True(t, stream.Next())
+
+	cancel()
+
+	for stream.Next() {
+	}
+
+	assert.True(t, errors.Is(stream.Err(), context.Canceled))
+	assert.True(t, errors.Is(<-stopped, context.Canceled))
+
+	// Handlers that gave up as the requester gave up do not close the connection.
+
+	res, err := a.Request(context.Background(), b.Addr(), []byte("ping"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(res))
+	assert.Len(t, a.Outbound(), 1)
+}
+
+func TestResponseStreamFlowControl(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode(noise.WithNodeHeartbeatInterval(20*time.Millisecond), noise.WithNodeHeartbeatMaxMissed(2))
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(
+		noise.WithNodeHeartbeatInterval(20*time.Millisecond),
+		noise.WithNodeHeartbeatMaxMissed(2),
+		noise.WithNodeNumWorkers(1),
+	)
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	var sent atomic.Uint32
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if !ctx.IsRequest() {
+			return nil
+		}
+
+		if string(ctx.Data()) == "ping" {
+			return ctx.Send([]byte("pong"))
+		}
+
+		for i := 0; i < 100; i++ {
+			if err := ctx.SendStream([]byte(strconv.Itoa(i))); err != nil {
+				return err
+			}
+
+			sent.Inc()
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	stream, err := a.RequestStream(context.Background(), b.Addr(), []byte("pages"))
+	assert.NoError(t, err)
+
+	defer stream.Close()
+
+	// The peer stops streaming responses while the stream is not read from, and the connection is kept alive in the
+	// meantime by heartbeats.
+
+	time.Sleep(300 * time.Millisecond)
+
+	assert.EqualValues(t, 32, sent.Load())
+
+	res, err := a.Request(context.Background(), b.Addr(), []byte("ping"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(res))
+
+	if assert.Len(t, a.Outbound(), 1) {
+		assert.NoError(t, a.Outbound()[0].Error())
+	}
+
+	// The peer resumes streaming responses once they are read.
+
+	for i := 0; i < 100; i++ {
+		if !assert.True(t, stream.Next()) {
+			break
+		}
+
+		assert.Equal(t, strconv.Itoa(i), string(stream.Data()))
+	}
+
+	assert.False(t, stream.Next())
+	assert.NoError(t, stream.Err())
+	assert.EqualValues(t, 100, sent.Load())
+}
+
+func TestStreamingHandlersDoNotStarveWorkers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	const numWorkers = 2
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode(noise.WithNodeNumWorkers(numWorkers))
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	var sent atomic.Uint32
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		if !ctx.IsRequest() {
+			return nil
+		}
+
+		if string(ctx.Data()) == "ping" {
+			return ctx.Send([]byte("pong"))
+		}
+
+		for i := 0; i < 64; i++ {
+			if err := ctx.SendStream([]byte(strconv.Itoa(i))); err != nil {
+				return err
+			}
+
+			sent.Inc()
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	// Have as many streams as b has workers be read slowly, such that all handlers of b await credit.
+
+	streams := make([]*noise.ResponseStream, 0, numWorkers)
+
+	for i := 0; i < numWorkers; i++ {
+		stream, err := a.RequestStream(context.Background(), b.Addr(), []byte("pages"))
+		assert.NoError(t, err)
+
+		defer stream.Close()
+
+		streams = append(streams, stream)
+	}
+
+	waitFor(t, func() bool { return sent.Load() == numWorkers*32 })
+
+	// Requests made in the meantime, enough to fill up the work queue of b, are still handled.
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errs := make(chan error, numWorkers+1)
+
+	for i := 0; i < numWorkers+1; i++ {
+		go func() {
+			res, err := a.Request(ctx, b.Addr(), []byte("ping"))
+			if err == nil && string(res) != "pong" {
+				err = fmt.Errorf("got response %q", res)
+			}
+
+			errs <- err
+		}()
+	}
+
+	for i := 0; i < numWorkers+1; i++ {
+		assert.NoError(t, <-errs)
+	}
+
+	for _, stream := range streams {
+		for i := 0; i < 64; i++ {
+			if !assert.True(t, stream.Next()) {
+				break
+			}
+
+			assert.Equal(t, strconv.Itoa(i), string(stream.Data()))
+		}
+
+		assert.False(t, stream.Next())
+		assert.NoError(t, stream.Err())
+	}
+
+	assert.EqualValues(t, numWorkers*64, sent.Load())
+}
@@ -0,0 +1,163 @@
+package noise
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// SizeTraceContext is the number of bytes a trace context occupies within the header block of a message.
+	SizeTraceContext = 16 + 8 + 1
+
+	// TraceFlagSampled marks a trace as being sampled by its caller.
+	TraceFlagSampled byte = 0x01
+)
+
+// TraceContext identifies the span of a distributed trace that caused a message to be sent. It follows the W3C Trace
+// Context data model, and is carried as a header of a message should it be valid. See Header.
+type TraceContext struct {
+	// TraceID identifies the trace as a whole.
+	TraceID [16]byte
+
+	// SpanID identifies the span within the trace that sent the message.
+	SpanID [8]byte
+
+	// Flags are the W3C trace flags of the trace, i.e. TraceFlagSampled.
+	Flags byte
+}
+
+// IsValid returns true if this trace context has both a non-zero trace ID and a non-zero span ID.
+func (t TraceContext) IsValid() bool {
+	return t.TraceID != [16]byte{} && t.SpanID != [8]byte{}
+}
+
+// IsSampled returns true if TraceFlagSampled is set.
+func (t TraceContext) IsSampled() bool {
+	return t.Flags&TraceFlagSampled != 0
+}
+
+// String returns this trace context formatted as a W3C traceparent header.
+func (t TraceContext) String() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(t.TraceID[:]), hex.EncodeToString(t.SpanID[:]), t.Flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header into a trace context. It returns an error should header be
+// malformed, or should it denote an invalid trace context.
+func ParseTraceParent(header string) (TraceContext, error) {
+	var trace TraceContext
+
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return trace, fmt.Errorf("malformed traceparent %q", header)
+	}
+
+	if parts[0] == "00" && len(parts) != 4 {
+		return trace, fmt.Errorf("malformed traceparent %q", header)
+	}
+
+	fields := []struct {
+		dst []byte
+		src string
+	}{
+		{trace.TraceID[:], parts[1]},
+		{trace.SpanID[:], parts[2]},
+	}
+
+	for _, field := range fields {
+		if len(field.src) != 2*len(field.dst) {
+			return trace, fmt.Errorf("malformed traceparent %q", header)
+		}
+
+		if _, err := hex.Decode(field.dst, []byte(field.src)); err != nil {
+			return trace, fmt.Errorf("malformed traceparent %q: %w", header, err)
+		}
+	}
+
+	var flags [1]byte
+
+	if len(parts[3]) != 2 {
+		return trace, fmt.Errorf("malformed traceparent %q", header)
+	}
+
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return trace, fmt.Errorf("malformed traceparent %q: %w", header, err)
+	}
+
+	trace.Flags = flags[0]
+
+	if !trace.IsValid() {
+		return TraceContext{}, fmt.Errorf("traceparent %q has a zero trace or span id", header)
+	}
+
+	return trace, nil
+}
+
+type traceContextKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying trace. Requests and messages sent via (*Node).Request,
+// (*Node).Send, and their variants with a context carrying a valid trace context carry the trace context to the
+// peer, where it is available via (*HandlerContext).Trace.
+func ContextWithTrace(ctx context.Context, trace TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, trace)
+}
+
+// TraceFromContext returns the trace context carried by ctx. It returns false should ctx not carry a valid trace
+// context.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	trace, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return trace, ok && trace.IsValid()
+}
+
+// TraceCarrier adapts a trace context to the carrier interface of OpenTelemetry propagators
+// (go.opentelemetry.io/otel/propagation.TextMapCarrier), which *TraceCarrier satisfies, by exposing it as a W3C
+// traceparent header. A span context held by an OpenTelemetry context may be carried to peers by injecting it into a
+// carrier:
+//
+//	var carrier noise.TraceCarrier
+//	propagator.Inject(ctx, &carrier)
+//
+//	res, err := node.Request(noise.ContextWithTrace(ctx, carrier.Trace), addr, data)
+//
+// The span context of a request may in turn be extracted within a handler:
+//
+//	ctx := propagator.Extract(context.Background(), &noise.TraceCarrier{Trace: hctx.Trace()})
+//
+// Only the W3C trace context propagator (propagation.TraceContext) is able to make use of a TraceCarrier.
+type TraceCarrier struct {
+	Trace TraceContext
+}
+
+const traceParentHeader = "traceparent"
+
+// Get returns the traceparent header of the carried trace context. It returns an empty string for any other key,
+// or should the carried trace context be invalid.
+func (c *TraceCarrier) Get(key string) string {
+	if !strings.EqualFold(key, traceParentHeader) || !c.Trace.IsValid() {
+		return ""
+	}
+
+	return c.Trace.String()
+}
+
+// Set sets the carried trace context to value should key be traceparent. Any other key, or any malformed
+// traceparent, is ignored.
+func (c *TraceCarrier) Set(key, value string) {
+	if !strings.EqualFold(key, traceParentHeader) {
+		return
+	}
+
+	if trace, err := ParseTraceParent(value); err == nil {
+		c.Trace = trace
+	}
+}
+
+// Keys returns the keys this carrier holds a value for.
+func (c *TraceCarrier) Keys() []string {
+	if !c.Trace.IsValid() {
+		return nil
+	}
+
+	return []string{traceParentHeader}
+}
@@ -0,0 +1,103 @@
+package noise_test
+
+import (
+	"context"
+	"github.com/perlin-network/noise"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"testing"
+)
+
+func TestTraceCarrier(t *testing.T) {
+	t.Parallel()
+
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var carrier noise.TraceCarrier
+	carrier.Set("Traceparent", header)
+
+	assert.True(t, carrier.Trace.IsValid())
+	assert.True(t, carrier.Trace.IsSampled())
+	assert.Equal(t, header, carrier.Get("traceparent"))
+	assert.Equal(t, []string{"traceparent"}, carrier.Keys())
+	assert.Empty(t, carrier.Get("tracestate"))
+
+	for _, malformed := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err := noise.ParseTraceParent(malformed)
+		assert.Error(t, err, malformed)
+	}
+
+	var empty noise.TraceCarrier
+	assert.Empty(t, empty.Keys())
+	assert.Empty(t, empty.Get("traceparent"))
+}
+
+func TestTraceContextPropagated(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	a, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer a.Close()
+
+	b, err := noise.NewNode()
+	assert.NoError(t, err)
+
+	defer b.Close()
+
+	traces := make(chan noise.TraceContext, 3)
+
+	b.Handle(func(ctx noise.HandlerContext) error {
+		traces <- ctx.Trace()
+
+		if ctx.IsRequest() {
+			return ctx.Send(ctx.Data())
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, a.Listen())
+	assert.NoError(t, b.Listen())
+
+	trace, err := noise.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NoError(t, err)
+
+	ctx := noise.ContextWithTrace(context.Background(), trace)
+
+	res, err := a.Request(ctx, b.Addr(), []byte("traced"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("traced"), res)
+	assert.Equal(t, trace, <-traces)
+
+	// Messages sent without a trace context carry no trace context, and no additional bytes.
+
+	client := a.Outbound()[0]
+
+	sent := func() uint64 { return client.Stats().BytesSent }
+
+	before := sent()
+
+	assert.NoError(t, a.Send(context.Background(), b.Addr(), []byte("hello")))
+	assert.False(t, (<-traces).IsValid())
+
+	waitFor(t, func() bool { return sent() > before })
+	untraced := sent() - before
+
+	before = sent()
+
+	assert.NoError(t, a.Send(ctx, b.Addr(), []byte("hello")))
+	assert.Equal(t, trace, <-traces)
+
+	waitFor(t, func() bool { return sent() > before })
+	// A header block (3 bytes) holding a single trace context entry (3 bytes).
+
+	assert.EqualValues(t, untraced+3+3+noise.SizeTraceContext, sent()-before)
+}